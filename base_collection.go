@@ -2,7 +2,7 @@ package collection
 
 import (
 	"encoding/json"
-	"fmt"
+	"io"
 
 	"github.com/shopspring/decimal"
 )
@@ -14,19 +14,28 @@ type BaseCollection struct {
 }
 
 const (
+	// Deprecated: use ErrNotImplemented with errors.Is.
 	ErrNotImplement = "not implement %s"
 )
 
-func (c *BaseCollection) errorHandle(e string, args ...string) {
+// errorHandle keeps err as the error of the collection unless there is already one, so the first failure
+// of a chain is the one which is reported.
+func (c *BaseCollection) errorHandle(err error) {
 	if c.err == nil {
-		c.err = fmt.Errorf(e, args)
+		c.err = err
 	}
 }
 
+// Error sets the error of the collection.
 func (c *BaseCollection) Error(e error) {
 	c.err = e
 }
 
+// Err returns the first error which happened in the chain of calls that built the collection.
+func (c BaseCollection) Err() error {
+	return c.err
+}
+
 func (c BaseCollection) Value() interface{} {
 	return c.value
 }
@@ -38,13 +47,13 @@ func (c BaseCollection) Length() int {
 
 // Select select the keys of collection and delete others.
 func (c BaseCollection) Select(keys ...string) Collection {
-	c.errorHandle(ErrNotImplement, "Select")
+	c.errorHandle(notImplemented("Select"))
 	return c
 }
 
 // Column select the values of collection by the given key.
 func (c BaseCollection) Column(key string) Collection {
-	c.errorHandle(ErrNotImplement, "Column")
+	c.errorHandle(notImplemented("Column"))
 	return c
 }
 
@@ -56,41 +65,42 @@ func (c BaseCollection) ToStruct(dist interface{}) {
 
 func (c BaseCollection) ToStructE(dist interface{}) error {
 	dist = nil
-	c.errorHandle(ErrNotImplement, "ToStructE")
+	c.errorHandle(notImplemented("ToStructE"))
 	return c.err
 }
 
 // All returns the underlying array represented by the collection.
 func (c BaseCollection) All() []interface{} {
-	c.errorHandle(ErrNotImplement, "All")
+	c.errorHandle(notImplemented("All"))
 	return nil
 }
 
 func (c BaseCollection) AllE() ([]interface{}, error) {
-	c.errorHandle(ErrNotImplement, "AllE")
+	c.errorHandle(notImplemented("AllE"))
 	return nil, c.err
 }
 
 // Avg returns the average value of a given key.
 func (c BaseCollection) Avg(key ...string) decimal.Decimal {
-	return c.Sum(key...).Div(decimal.New(int64(c.length), 0))
+	c.errorHandle(notImplemented("Avg"))
+	return decimal.Decimal{}
 }
 
 // Sum returns the sum of all items in the collection.
 func (c BaseCollection) Sum(key ...string) decimal.Decimal {
-	c.errorHandle(ErrNotImplement, "Sum")
+	c.errorHandle(notImplemented("Sum"))
 	return decimal.Decimal{}
 }
 
 // Min returns the minimum value of a given key.
 func (c BaseCollection) Min(key ...string) decimal.Decimal {
-	c.errorHandle(ErrNotImplement, "Min")
+	c.errorHandle(notImplemented("Min"))
 	return decimal.Decimal{}
 }
 
 // Max returns the maximum value of a given key.
 func (c BaseCollection) Max(key ...string) decimal.Decimal {
-	c.errorHandle(ErrNotImplement, "Max")
+	c.errorHandle(notImplemented("Max"))
 	return decimal.Decimal{}
 }
 
@@ -100,19 +110,43 @@ func (c BaseCollection) Join(delimiter string) string {
 }
 
 func (c BaseCollection) JoinE(delimiter string) (string, error) {
-	c.errorHandle(ErrNotImplement, "Join")
+	c.errorHandle(notImplemented("Join"))
 	return "", c.err
 }
 
+// JSONPath returns the values which the RFC 9535 JSONPath expression selects, as a collection of their type.
+func (c BaseCollection) JSONPath(expr string) Collection {
+	c.errorHandle(notImplemented("JSONPath"))
+	return c
+}
+
+// JSONPathValues returns the values which the RFC 9535 JSONPath expression selects, in order.
+func (c BaseCollection) JSONPathValues(expr string) ([]interface{}, error) {
+	c.errorHandle(notImplemented("JSONPathValues"))
+	return nil, c.err
+}
+
+// Clone returns a deep copy of the collection.
+func (c BaseCollection) Clone() Collection {
+	c.errorHandle(notImplemented("Clone"))
+	return c
+}
+
 // Combine combines the values of the collection, as keys, with the values of another array or collection.
 func (c BaseCollection) Combine(value []interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Combine")
+	c.errorHandle(notImplemented("Combine"))
 	return c
 }
 
 // Pluck retrieves all of the values for a given key.
 func (c BaseCollection) Pluck(key string) Collection {
-	c.errorHandle(ErrNotImplement, "Pluck")
+	c.errorHandle(notImplemented("Pluck"))
+	return c
+}
+
+// Pivot returns one row per value at index, with a column per value at columns.
+func (c BaseCollection) Pivot(string, string, Aggregator, ...interface{}) Collection {
+	c.errorHandle(notImplemented("Pivot"))
 	return c
 }
 
@@ -122,7 +156,7 @@ func (c BaseCollection) ToIntArray() []int {
 }
 
 func (c BaseCollection) ToIntArrayE() ([]int, error) {
-	c.errorHandle(ErrNotImplement, "ToIntArrayE")
+	c.errorHandle(notImplemented("ToIntArrayE"))
 	return nil, c.err
 }
 
@@ -132,7 +166,7 @@ func (c BaseCollection) ToInt64Array() []int64 {
 }
 
 func (c BaseCollection) ToInt64ArrayE() ([]int64, error) {
-	c.errorHandle(ErrNotImplement, "ToInt64ArrayE")
+	c.errorHandle(notImplemented("ToInt64ArrayE"))
 	return nil, c.err
 }
 
@@ -142,61 +176,163 @@ func (c BaseCollection) Mode(key ...string) []interface{} {
 }
 
 func (c BaseCollection) ModeE(key ...string) ([]interface{}, error) {
-	c.errorHandle(ErrNotImplement, "ModeE")
+	c.errorHandle(notImplemented("ModeE"))
 	return nil, c.err
 }
 
+// Percentile returns the p-th percentile of the values, p is between 0 and 100. The value between two items is
+// interpolated linearly.
+func (c BaseCollection) Percentile(p float64, key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) PercentileE(p float64, key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("PercentileE"))
+	return decimal.Decimal{}, c.err
+}
+
+// Quantile returns the q-quantile of the values, q is between 0 and 1. The interpolation decides the value
+// between two items.
+func (c BaseCollection) Quantile(q float64, interpolation Interpolation, key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) QuantileE(q float64, interpolation Interpolation, key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("QuantileE"))
+	return decimal.Decimal{}, c.err
+}
+
+// Variance returns the population variance of the values.
+func (c BaseCollection) Variance(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) VarianceE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("VarianceE"))
+	return decimal.Decimal{}, c.err
+}
+
+// SampleVariance returns the sample variance of the values, which divides by n-1.
+func (c BaseCollection) SampleVariance(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) SampleVarianceE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("SampleVarianceE"))
+	return decimal.Decimal{}, c.err
+}
+
+// StdDev returns the population standard deviation of the values.
+func (c BaseCollection) StdDev(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) StdDevE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("StdDevE"))
+	return decimal.Decimal{}, c.err
+}
+
+// SampleStdDev returns the sample standard deviation of the values.
+func (c BaseCollection) SampleStdDev(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) SampleStdDevE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("SampleStdDevE"))
+	return decimal.Decimal{}, c.err
+}
+
+// IQR returns the interquartile range of the values, the difference of the 75th and the 25th percentile.
+func (c BaseCollection) IQR(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) IQRE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("IQRE"))
+	return decimal.Decimal{}, c.err
+}
+
+// Skewness returns the population skewness of the values.
+func (c BaseCollection) Skewness(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) SkewnessE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("SkewnessE"))
+	return decimal.Decimal{}, c.err
+}
+
+// Kurtosis returns the population excess kurtosis of the values, which is 0 for a normal distribution.
+func (c BaseCollection) Kurtosis(key ...string) decimal.Decimal {
+	return decimal.Decimal{}
+}
+
+func (c BaseCollection) KurtosisE(key ...string) (decimal.Decimal, error) {
+	c.errorHandle(notImplemented("KurtosisE"))
+	return decimal.Decimal{}, c.err
+}
+
+// Describe returns the count, mean, standard deviation, minimum, quartiles and maximum of the values.
+func (c BaseCollection) Describe(key ...string) Description {
+	return Description{}
+}
+
+func (c BaseCollection) DescribeE(key ...string) (Description, error) {
+	c.errorHandle(notImplemented("DescribeE"))
+	return Description{}, c.err
+}
+
 // Only returns the items in the collection with the specified keys.
 func (c BaseCollection) Only(keys []string) Collection {
-	c.errorHandle(ErrNotImplement, "Only")
+	c.errorHandle(notImplemented("Only"))
 	return c
 }
 
 // Prepend adds an item to the beginning of the collection.
 func (c BaseCollection) Prepend(values ...interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Prepend")
+	c.errorHandle(notImplemented("Prepend"))
 	return c
 }
 
 // Pull removes and returns an item from the collection by its key.
 func (c BaseCollection) Pull(key interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Pull")
+	c.errorHandle(notImplemented("Pull"))
 	return c
 }
 
 // Put sets the given key and value in the collection:.
 func (c BaseCollection) Put(key string, value interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Put")
+	c.errorHandle(notImplemented("Put"))
 	return c
 }
 
-// SortBy sorts the collection by the given key.
-func (c BaseCollection) SortBy(key string) Collection {
-	c.errorHandle(ErrNotImplement, "SortBy")
+// SortBy sorts the collection by the given keys. A key is a string, a SortKey or a comparison function.
+func (c BaseCollection) SortBy(keys ...interface{}) Collection {
+	c.errorHandle(notImplemented("SortBy"))
 	return c
 }
 
 // Take returns a new collection with the specified number of items.
 func (c BaseCollection) Take(num int) Collection {
-	c.errorHandle(ErrNotImplement, "Take")
+	c.errorHandle(notImplemented("Take"))
 	return c
 }
 
 // Chunk breaks the collection into multiple, smaller collections of a given size.
 func (c BaseCollection) Chunk(num int) MultiDimensionalArrayCollection {
-	c.errorHandle(ErrNotImplement, "Chunk")
-	return MultiDimensionalArrayCollection{}
+	c.errorHandle(notImplemented("Chunk"))
+	return MultiDimensionalArrayCollection{BaseCollection: BaseCollection{err: c.err}}
 }
 
 // Collapse collapses a collection of arrays into a single, flat collection.
 func (c BaseCollection) Collapse() Collection {
-	c.errorHandle(ErrNotImplement, "Collapse")
+	c.errorHandle(notImplemented("Collapse"))
 	return c
 }
 
 // Concat appends the given array or collection values onto the end of the collection.
 func (c BaseCollection) Concat(value interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Concat")
+	c.errorHandle(notImplemented("Concat"))
 	return c
 }
 
@@ -206,7 +342,7 @@ func (c BaseCollection) Contains(value ...interface{}) bool {
 }
 
 func (c BaseCollection) ContainsE(value ...interface{}) (bool, error) {
-	c.errorHandle(ErrNotImplement, "ContainsE")
+	c.errorHandle(notImplemented("ContainsE"))
 	return false, c.err
 }
 
@@ -216,14 +352,14 @@ func (c BaseCollection) CountBy(callback ...interface{}) map[interface{}]int {
 }
 
 func (c BaseCollection) CountByE(callback ...interface{}) (map[interface{}]int, error) {
-	c.errorHandle(ErrNotImplement, "CountByE")
+	c.errorHandle(notImplemented("CountByE"))
 	return nil, c.err
 }
 
 // CrossJoin cross joins the collection's values among the given arrays or collections, returning a Cartesian product with all possible permutations.
 func (c BaseCollection) CrossJoin(array ...[]interface{}) MultiDimensionalArrayCollection {
-	c.errorHandle(ErrNotImplement, "CrossJoin")
-	return MultiDimensionalArrayCollection{}
+	c.errorHandle(notImplemented("CrossJoin"))
+	return MultiDimensionalArrayCollection{BaseCollection: BaseCollection{err: c.err}}
 }
 
 // Dd dumps the collection's items and ends execution of the script.
@@ -231,43 +367,73 @@ func (c BaseCollection) Dd() {
 }
 
 func (c BaseCollection) DdE() error {
-	c.errorHandle(ErrNotImplement, "DdE")
+	c.errorHandle(notImplemented("DdE"))
 	return c.err
 }
 
 // Diff compares the collection against another collection or a plain PHP array based on its values.
 // This method will return the values in the original collection that are not present in the given collection.
 func (c BaseCollection) Diff(interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Diff")
+	c.errorHandle(notImplemented("Diff"))
 	return c
 }
 
 // DiffAssoc compares the collection against another collection or a plain PHP  array based on its keys and values.
 // This method will return the key / value pairs in the original collection that are not present in the given collection.
 func (c BaseCollection) DiffAssoc(map[string]interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "DiffAssoc")
+	c.errorHandle(notImplemented("DiffAssoc"))
 	return c
 }
 
 // DiffKeys compares the collection against another collection or a plain PHP array based on its keys.
 // This method will return the key / value pairs in the original collection that are not present in the given collection.
 func (c BaseCollection) DiffKeys(map[string]interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "DiffKeys")
+	c.errorHandle(notImplemented("DiffKeys"))
 	return c
 }
 
+// DiffPatch returns the RFC 6902 JSON Patch which turns the collection into the given collection or map,
+// including the changes of nested maps and arrays.
+func (c BaseCollection) DiffPatch(interface{}) Patch {
+	c.errorHandle(notImplemented("DiffPatch"))
+	return nil
+}
+
+func (c BaseCollection) DiffPatchE(interface{}) (Patch, error) {
+	c.errorHandle(notImplemented("DiffPatchE"))
+	return nil, c.err
+}
+
+// ApplyPatch returns the collection with the operations of the RFC 6902 JSON Patch applied.
+func (c BaseCollection) ApplyPatch(Patch) Collection {
+	c.errorHandle(notImplemented("ApplyPatch"))
+	return c
+}
+
+// MergePatch returns the collection with the RFC 7396 JSON Merge Patch applied.
+func (c BaseCollection) MergePatch(interface{}) Collection {
+	c.errorHandle(notImplemented("MergePatch"))
+	return c
+}
+
+// DiffBy compares the rows of the collection with the rows of another collection by the given key columns.
+func (c BaseCollection) DiffBy(interface{}, ...string) RowDiff {
+	c.errorHandle(notImplemented("DiffBy"))
+	return rowDiffError(c.err)
+}
+
 // Dump dumps the collection's items.
 func (c BaseCollection) Dump() {
 }
 
 func (c BaseCollection) DumpE() error {
-	c.errorHandle(ErrNotImplement, "DumpE")
+	c.errorHandle(notImplemented("DumpE"))
 	return c.err
 }
 
 // Each iterates over the items in the collection and passes each item to a callback.
 func (c BaseCollection) Each(func(item, value interface{}) (interface{}, bool)) Collection {
-	c.errorHandle(ErrNotImplement, "Each")
+	c.errorHandle(notImplemented("Each"))
 	return c
 }
 
@@ -277,19 +443,37 @@ func (c BaseCollection) Every(CB) bool {
 }
 
 func (c BaseCollection) EveryE(CB) (bool, error) {
-	c.errorHandle(ErrNotImplement, "EveryE")
+	c.errorHandle(notImplemented("EveryE"))
 	return false, c.err
 }
 
 // Except returns all items in the collection except for those with the specified keys.
 func (c BaseCollection) Except([]string) Collection {
-	c.errorHandle(ErrNotImplement, "Except")
+	c.errorHandle(notImplemented("Except"))
+	return c
+}
+
+// ParallelEach is the same as Each, but runs the callbacks on at most workers goroutines.
+func (c BaseCollection) ParallelEach(int, func(item, value interface{}) (interface{}, bool)) Collection {
+	c.errorHandle(notImplemented("ParallelEach"))
+	return c
+}
+
+// ParallelFilter is the same as Filter, but runs the callbacks on at most workers goroutines.
+func (c BaseCollection) ParallelFilter(int, CB) Collection {
+	c.errorHandle(notImplemented("ParallelFilter"))
+	return c
+}
+
+// ParallelReject is the same as Reject, but runs the callbacks on at most workers goroutines.
+func (c BaseCollection) ParallelReject(int, CB) Collection {
+	c.errorHandle(notImplemented("ParallelReject"))
 	return c
 }
 
 // Filter filters the collection using the given callback, keeping only those items that pass a given truth test.
 func (c BaseCollection) Filter(CB) Collection {
-	c.errorHandle(ErrNotImplement, "Filter")
+	c.errorHandle(notImplemented("Filter"))
 	return c
 }
 
@@ -299,7 +483,7 @@ func (c BaseCollection) First(...CB) interface{} {
 }
 
 func (c BaseCollection) FirstE(...CB) (interface{}, error) {
-	c.errorHandle(ErrNotImplement, "FirstE")
+	c.errorHandle(notImplemented("FirstE"))
 	return nil, c.err
 }
 
@@ -309,31 +493,31 @@ func (c BaseCollection) FirstWhere(key string, values ...interface{}) map[string
 }
 
 func (c BaseCollection) FirstWhereE(key string, values ...interface{}) (map[string]interface{}, error) {
-	c.errorHandle(ErrNotImplement, "FirstWhereE")
+	c.errorHandle(notImplemented("FirstWhereE"))
 	return nil, c.err
 }
 
 // FlatMap iterates through the collection and passes each value to the given callback.
 func (c BaseCollection) FlatMap(func(value interface{}) interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "FlatMap")
+	c.errorHandle(notImplemented("FlatMap"))
 	return c
 }
 
 // Flip swaps the collection's keys with their corresponding values.
 func (c BaseCollection) Flip() Collection {
-	c.errorHandle(ErrNotImplement, "Flip")
+	c.errorHandle(notImplemented("Flip"))
 	return c
 }
 
 // Forget removes an item from the collection by its key.
 func (c BaseCollection) Forget(string) Collection {
-	c.errorHandle(ErrNotImplement, "Forget")
+	c.errorHandle(notImplemented("Forget"))
 	return c
 }
 
 // ForPage returns a new collection containing the items that would be present on a given page number.
 func (c BaseCollection) ForPage(int, int) Collection {
-	c.errorHandle(ErrNotImplement, "ForPage")
+	c.errorHandle(notImplemented("ForPage"))
 	return c
 }
 
@@ -343,14 +527,14 @@ func (c BaseCollection) Get(string, ...interface{}) interface{} {
 }
 
 func (c BaseCollection) GetE(string, ...interface{}) (interface{}, error) {
-	c.errorHandle(ErrNotImplement, "GetE")
+	c.errorHandle(notImplemented("GetE"))
 	return nil, c.err
 }
 
-// GroupBy groups the collection's items by a given key.
-func (c BaseCollection) GroupBy(string) Collection {
-	c.errorHandle(ErrNotImplement, "GroupBy")
-	return c
+// GroupBy groups the collection's items by the given keys.
+func (c BaseCollection) GroupBy(...string) GroupedCollection {
+	c.errorHandle(notImplemented("GroupBy"))
+	return GroupedCollection{MapCollection: MapCollection{BaseCollection: BaseCollection{err: c.err}}}
 }
 
 // Has determines if a given key exists in the collection.
@@ -359,7 +543,7 @@ func (c BaseCollection) Has(...string) bool {
 }
 
 func (c BaseCollection) HasE(...string) (bool, error) {
-	c.errorHandle(ErrNotImplement, "HasE")
+	c.errorHandle(notImplemented("HasE"))
 	return false, c.err
 }
 
@@ -369,19 +553,67 @@ func (c BaseCollection) Implode(string, string) string {
 }
 
 func (c BaseCollection) ImplodeE(string, string) (string, error) {
-	c.errorHandle(ErrNotImplement, "ImplodeE")
+	c.errorHandle(notImplemented("ImplodeE"))
 	return "", c.err
 }
 
+// InnerJoin joins the collection with other, keeping the pairs of rows which have the same key.
+func (c BaseCollection) InnerJoin(interface{}, ...interface{}) Collection {
+	c.errorHandle(notImplemented("InnerJoin"))
+	return c
+}
+
+// LeftJoin is the same as InnerJoin, but also keeps the rows of the collection without a match.
+func (c BaseCollection) LeftJoin(interface{}, ...interface{}) Collection {
+	c.errorHandle(notImplemented("LeftJoin"))
+	return c
+}
+
+// RightJoin is the same as InnerJoin, but also keeps the rows of other without a match.
+func (c BaseCollection) RightJoin(interface{}, ...interface{}) Collection {
+	c.errorHandle(notImplemented("RightJoin"))
+	return c
+}
+
+// FullOuterJoin is the same as InnerJoin, but also keeps the rows of both sides without a match.
+func (c BaseCollection) FullOuterJoin(interface{}, ...interface{}) Collection {
+	c.errorHandle(notImplemented("FullOuterJoin"))
+	return c
+}
+
+// SemiJoin returns the rows of the collection which have a match in other.
+func (c BaseCollection) SemiJoin(interface{}, ...interface{}) Collection {
+	c.errorHandle(notImplemented("SemiJoin"))
+	return c
+}
+
+// AntiJoin returns the rows of the collection which have no match in other.
+func (c BaseCollection) AntiJoin(interface{}, ...interface{}) Collection {
+	c.errorHandle(notImplemented("AntiJoin"))
+	return c
+}
+
+// Index returns the collection with a hash index on every given key.
+func (c BaseCollection) Index(...string) Collection {
+	c.errorHandle(notImplemented("Index"))
+	return c
+}
+
+// SortedIndex is the same as Index, but range conditions on numbers and times are looked up too.
+func (c BaseCollection) SortedIndex(...string) Collection {
+	c.errorHandle(notImplemented("SortedIndex"))
+	return c
+}
+
 // Intersect removes any values from the original collection that are not present in the given array or collection.
 func (c BaseCollection) Intersect([]string) Collection {
-	c.errorHandle(ErrNotImplement, "Intersect")
+	c.errorHandle(notImplemented("Intersect"))
 	return c
 }
 
 // IntersectByKeys removes any keys from the original collection that are not present in the given array or collection.
 func (c BaseCollection) IntersectByKeys(map[string]interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "IntersectByKeys")
+	c.errorHandle(notImplemented("IntersectByKeys"))
 	return c
 }
 
@@ -391,7 +623,7 @@ func (c BaseCollection) IsEmpty() bool {
 }
 
 func (c BaseCollection) IsEmptyE() (bool, error) {
-	c.errorHandle(ErrNotImplement, "IsEmptyE")
+	c.errorHandle(notImplemented("IsEmptyE"))
 	return false, c.err
 }
 
@@ -401,48 +633,54 @@ func (c BaseCollection) IsNotEmpty() bool {
 }
 
 func (c BaseCollection) IsNotEmptyE() (bool, error) {
-	c.errorHandle(ErrNotImplement, "IsNotEmptyE")
+	c.errorHandle(notImplemented("IsNotEmptyE"))
 	return false, c.err
 }
 
 // KeyBy keys the collection by the given key. If multiple items have the same key, only the last one will
 // appear in the new collection.
 func (c BaseCollection) KeyBy(interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "KeyBy")
+	c.errorHandle(notImplemented("KeyBy"))
 	return c
 }
 
 // Keys returns all of the collection's keys.
 func (c BaseCollection) Keys() Collection {
-	c.errorHandle(ErrNotImplement, "Keys")
+	c.errorHandle(notImplemented("Keys"))
 	return c
 }
 
+// Lazy returns a LazyCollection which runs the chained operations on demand.
+func (c BaseCollection) Lazy() LazyCollection {
+	c.errorHandle(notImplemented("Lazy"))
+	return LazyCollection{err: c.err}
+}
+
 // Last returns the last element in the collection that passes a given truth test.
 func (c BaseCollection) Last(...CB) interface{} {
 	return nil
 }
 
 func (c BaseCollection) LastE(...CB) (interface{}, error) {
-	c.errorHandle(ErrNotImplement, "LastE")
+	c.errorHandle(notImplemented("LastE"))
 	return nil, c.err
 }
 
 // MapToGroups groups the collection's items by the given callback.
 func (c BaseCollection) MapToGroups(MapCB) Collection {
-	c.errorHandle(ErrNotImplement, "MapToGroups")
+	c.errorHandle(notImplemented("MapToGroups"))
 	return c
 }
 
 // MapWithKeys iterates through the collection and passes each value to the given callback.
 func (c BaseCollection) MapWithKeys(MapCB) Collection {
-	c.errorHandle(ErrNotImplement, "MapWithKeys")
+	c.errorHandle(notImplemented("MapWithKeys"))
 	return c
 }
 
 // Median returns the median value of a given key.
 func (c BaseCollection) Median(key ...string) decimal.Decimal {
-	c.errorHandle(ErrNotImplement, "Median")
+	c.errorHandle(notImplemented("Median"))
 	return decimal.Decimal{}
 }
 
@@ -450,24 +688,43 @@ func (c BaseCollection) Median(key ...string) decimal.Decimal {
 // matches a string key in the original collection, the given items's value will overwrite the value in the
 // original collection.
 func (c BaseCollection) Merge(interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Merge")
+	c.errorHandle(notImplemented("Merge"))
+	return c
+}
+
+// DeepMerge merges the given map or collection into the collection, or into every row of a MapArrayCollection.
+func (c BaseCollection) DeepMerge(interface{}, ...MergeStrategy) Collection {
+	c.errorHandle(notImplemented("DeepMerge"))
+	return c
+}
+
+// UpsertBy merges the given rows into the rows of the collection which have the same values at the key
+// columns, and appends the rows without a match.
+func (c BaseCollection) UpsertBy([]string, interface{}, ...MergeStrategy) Collection {
+	c.errorHandle(notImplemented("UpsertBy"))
+	return c
+}
+
+// Melt is the reverse of Pivot, it turns every row into one row per value column.
+func (c BaseCollection) Melt([]string, ...string) Collection {
+	c.errorHandle(notImplemented("Melt"))
 	return c
 }
 
 func (c BaseCollection) Nth(...int) Collection {
-	c.errorHandle(ErrNotImplement, "Nth")
+	c.errorHandle(notImplemented("Nth"))
 	return c
 }
 
 // Pad will fill the array with the given value until the array reaches the specified size.
 func (c BaseCollection) Pad(int, interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Pad")
+	c.errorHandle(notImplemented("Pad"))
 	return c
 }
 
 // Partition separate elements that pass a given truth test from those that do not.
 func (c BaseCollection) Partition(PartCB) (Collection, Collection) {
-	c.errorHandle(ErrNotImplement, "Partition")
+	c.errorHandle(notImplemented("Partition"))
 	return c, c
 }
 
@@ -477,19 +734,19 @@ func (c BaseCollection) Pop() interface{} {
 }
 
 func (c BaseCollection) PopE() (interface{}, error) {
-	c.errorHandle(ErrNotImplement, "PopE")
+	c.errorHandle(notImplemented("PopE"))
 	return nil, c.err
 }
 
 // Push appends an item to the end of the collection.
 func (c BaseCollection) Push(interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Push")
+	c.errorHandle(notImplemented("Push"))
 	return c
 }
 
 // Random returns a random item from the collection.
 func (c BaseCollection) Random(...int) Collection {
-	c.errorHandle(ErrNotImplement, "Random")
+	c.errorHandle(notImplemented("Random"))
 	return c
 }
 
@@ -499,19 +756,19 @@ func (c BaseCollection) Reduce(ReduceCB) interface{} {
 }
 
 func (c BaseCollection) ReduceE(ReduceCB) (interface{}, error) {
-	c.errorHandle(ErrNotImplement, "ReduceE")
+	c.errorHandle(notImplemented("ReduceE"))
 	return nil, c.err
 }
 
 // Reject filters the collection using the given callback.
 func (c BaseCollection) Reject(CB) Collection {
-	c.errorHandle(ErrNotImplement, "Reject")
+	c.errorHandle(notImplemented("Reject"))
 	return c
 }
 
 // Reverse reverses the order of the collection's items, preserving the original keys.
 func (c BaseCollection) Reverse() Collection {
-	c.errorHandle(ErrNotImplement, "Reverse")
+	c.errorHandle(notImplemented("Reverse"))
 	return c
 }
 
@@ -522,70 +779,82 @@ func (c BaseCollection) Search(interface{}) int {
 }
 
 func (c BaseCollection) SearchE(interface{}) (int, error) {
-	c.errorHandle(ErrNotImplement, "SearchE")
+	c.errorHandle(notImplemented("SearchE"))
 	return -1, c.err
 }
 
+// Set sets the value at a given key or path.
+func (c BaseCollection) Set(string, interface{}) Collection {
+	c.errorHandle(notImplemented("Set"))
+	return c
+}
+
 // Shift removes and returns the first item from the collection.
 func (c BaseCollection) Shift() Collection {
-	c.errorHandle(ErrNotImplement, "Shift")
+	c.errorHandle(notImplemented("Shift"))
 	return c
 }
 
 // Shuffle randomly shuffles the items in the collection.
 func (c BaseCollection) Shuffle() Collection {
-	c.errorHandle(ErrNotImplement, "Shuffle")
+	c.errorHandle(notImplemented("Shuffle"))
 	return c
 }
 
 // Slice returns a slice of the collection starting at the given index.
 func (c BaseCollection) Slice(...int) Collection {
-	c.errorHandle(ErrNotImplement, "Slice")
+	c.errorHandle(notImplemented("Slice"))
 	return c
 }
 
 // Sort sorts the collection.
 func (c BaseCollection) Sort() Collection {
-	c.errorHandle(ErrNotImplement, "Sort")
+	c.errorHandle(notImplemented("Sort"))
 	return c
 }
 
 // SortByDesc has the same signature as the sortBy method, but will sort the collection in the opposite order.
-func (c BaseCollection) SortByDesc() Collection {
-	c.errorHandle(ErrNotImplement, "SortByDesc")
+func (c BaseCollection) SortByDesc(keys ...interface{}) Collection {
+	c.errorHandle(notImplemented("SortByDesc"))
 	return c
 }
 
 // Splice removes and returns a slice of items starting at the specified index.
 func (c BaseCollection) Split(int) Collection {
-	c.errorHandle(ErrNotImplement, "Split")
+	c.errorHandle(notImplemented("Split"))
 	return c
 }
 
 // Split breaks a collection into the given number of groups.
 func (c BaseCollection) Splice(index ...int) Collection {
-	c.errorHandle(ErrNotImplement, "Splice")
+	c.errorHandle(notImplemented("Splice"))
 	return c
 }
 
 // Unique returns all of the unique items in the collection.
 func (c BaseCollection) Unique() Collection {
-	c.errorHandle(ErrNotImplement, "Unique")
+	c.errorHandle(notImplemented("Unique"))
 	return c
 }
 
 // WhereIn filters the collection by a given key / value contained within the given array.
 func (c BaseCollection) WhereIn(string, []interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "WhereIn")
+	c.errorHandle(notImplemented("WhereIn"))
 	return c
 }
 
 // WhereNotIn filters the collection by a given key / value not contained within the given array.
 func (c BaseCollection) WhereNotIn(string, []interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "WhereNotIn")
+	c.errorHandle(notImplemented("WhereNotIn"))
 	return c
 }
 
+// Window returns a WindowCollection which adds columns computed over the partitions of the rows.
+func (c BaseCollection) Window([]string, ...interface{}) WindowCollection {
+	c.errorHandle(notImplemented("Window"))
+	return WindowCollection{err: c.err}
+}
+
 // ToJson converts the collection into a json string.
 func (c BaseCollection) ToJson() string {
 	s, err := json.Marshal(c.value)
@@ -596,20 +865,36 @@ func (c BaseCollection) ToJson() string {
 }
 
 func (c BaseCollection) ToJsonE() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
 	s, err := json.Marshal(c.value)
 	if err != nil {
-		return "", err
+		c.errorHandle(err)
+		return "", c.err
 	}
 	return string(s), nil
 }
 
+// ToCSV writes the collection as csv to w.
+func (c BaseCollection) ToCSV(io.Writer, ...string) error {
+	c.errorHandle(notImplemented("ToCSV"))
+	return c.err
+}
+
+// WriteNDJSON writes the items of the collection to w as newline delimited json.
+func (c BaseCollection) WriteNDJSON(io.Writer) error {
+	c.errorHandle(notImplemented("WriteNDJSON"))
+	return c.err
+}
+
 // ToNumberArray converts the collection into a plain golang slice which contains decimal.Decimal.
 func (c BaseCollection) ToNumberArray() []decimal.Decimal {
 	return nil
 }
 
 func (c BaseCollection) ToNumberArrayE() ([]decimal.Decimal, error) {
-	c.errorHandle(ErrNotImplement, "ToNumberArrayE")
+	c.errorHandle(notImplemented("ToNumberArrayE"))
 	return nil, c.err
 }
 
@@ -619,7 +904,7 @@ func (c BaseCollection) ToMultiDimensionalArray() [][]interface{} {
 }
 
 func (c BaseCollection) ToMultiDimensionalArrayE() ([][]interface{}, error) {
-	c.errorHandle(ErrNotImplement, "ToMultiDimensionalArrayE")
+	c.errorHandle(notImplemented("ToMultiDimensionalArrayE"))
 	return nil, c.err
 }
 
@@ -629,7 +914,7 @@ func (c BaseCollection) ToStringArray() []string {
 }
 
 func (c BaseCollection) ToStringArrayE() ([]string, error) {
-	c.errorHandle(ErrNotImplement, "ToStringArrayE")
+	c.errorHandle(notImplemented("ToStringArrayE"))
 	return nil, c.err
 }
 
@@ -639,7 +924,7 @@ func (c BaseCollection) ToMap() map[string]interface{} {
 }
 
 func (c BaseCollection) ToMapE() (map[string]interface{}, error) {
-	c.errorHandle(ErrNotImplement, "ToMapE")
+	c.errorHandle(notImplemented("ToMapE"))
 	return nil, c.err
 }
 
@@ -649,13 +934,19 @@ func (c BaseCollection) ToMapArray() []map[string]interface{} {
 }
 
 func (c BaseCollection) ToMapArrayE() ([]map[string]interface{}, error) {
-	c.errorHandle(ErrNotImplement, "ToMapArrayE")
+	c.errorHandle(notImplemented("ToMapArrayE"))
 	return nil, c.err
 }
 
 // Where filters the collection by a given key / value pair.
 func (c BaseCollection) Where(key string, values ...interface{}) Collection {
-	c.errorHandle(ErrNotImplement, "Where")
+	c.errorHandle(notImplemented("Where"))
+	return c
+}
+
+// WhereGroup filters the collection by the conditions built by fn.
+func (c BaseCollection) WhereGroup(func(q *WhereQuery)) Collection {
+	c.errorHandle(notImplemented("WhereGroup"))
 	return c
 }
 
@@ -0,0 +1,211 @@
+package collection
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/hulklab/collection/hamt"
+	"github.com/hulklab/collection/vector_trie"
+	"github.com/shopspring/decimal"
+)
+
+// The rows of a collection are shared with the collections derived from it and are never changed in place.
+// A method which changes a row copies it first, only the maps and slices along the changed path, so the
+// untouched rows and values stay shared. Clone gives a deep copy for the callers who change the rows of
+// ToMapArray or All themselves.
+
+// Clone returns a deep copy of the collection, no map or slice of its rows is shared with it.
+func (c MapArrayCollection) Clone() Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	items := c.items()
+	var d = make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		d[i] = copyMap(item)
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// Clone returns a deep copy of the collection, no map or slice of its values is shared with it.
+func (c MapCollection) Clone() Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var d = hamt.New().Transient()
+	c.value.Range(func(k string, v interface{}) bool {
+		d.Assoc(k, deepCopy(v))
+		return true
+	})
+	return newMapCollection(d.Persistent())
+}
+
+// copyMap returns a deep copy of m.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	return deepCopy(m).(map[string]interface{})
+}
+
+// deepCopy returns a copy of v which shares no map, slice or pointer with it. The common types of decoded
+// json and of Collect are copied without reflection. Values which are immutable, such as strings, numbers,
+// decimals, times and the persistent maps and lists, are returned as they are. The unexported fields of a
+// struct are copied shallowly, and a pointer, map or slice which is reached twice is copied once.
+func deepCopy(v interface{}) interface{} {
+	return deepCopySeen(v, make(map[copyKey]interface{}))
+}
+
+// copyKey identifies a pointer, map or slice which has been copied. The type is a part of the key because
+// a struct and its first field have the same address, and the length because slices of one array may
+// differ in it.
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// copied looks up the copy of the pointer, map or slice v in seen.
+func copied(v reflect.Value, seen map[copyKey]interface{}) (copyKey, interface{}, bool) {
+	k := copyKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		k.len = v.Len()
+	}
+	d, ok := seen[k]
+	return k, d, ok
+}
+
+func deepCopySeen(v interface{}, seen map[copyKey]interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32,
+		float64, decimal.Decimal, time.Time, *hamt.Map, *vector_trie.List:
+		return v
+	case map[string]interface{}:
+		if t == nil {
+			return t
+		}
+		k, c, ok := copied(reflect.ValueOf(t), seen)
+		if ok {
+			return c
+		}
+		var d = make(map[string]interface{}, len(t))
+		seen[k] = d
+		for key, value := range t {
+			d[key] = deepCopySeen(value, seen)
+		}
+		return d
+	case []interface{}:
+		if t == nil {
+			return t
+		}
+		k, c, ok := copied(reflect.ValueOf(t), seen)
+		if ok {
+			return c
+		}
+		var d = make([]interface{}, len(t))
+		seen[k] = d
+		for i, value := range t {
+			d[i] = deepCopySeen(value, seen)
+		}
+		return d
+	case []map[string]interface{}:
+		if t == nil {
+			return t
+		}
+		k, c, ok := copied(reflect.ValueOf(t), seen)
+		if ok {
+			return c
+		}
+		var d = make([]map[string]interface{}, len(t))
+		seen[k] = d
+		for i, value := range t {
+			if value != nil {
+				d[i] = deepCopySeen(value, seen).(map[string]interface{})
+			}
+		}
+		return d
+	case []string:
+		if t == nil {
+			return t
+		}
+		var d = make([]string, len(t))
+		copy(d, t)
+		return d
+	}
+	return deepCopyValue(reflect.ValueOf(v), seen).Interface()
+}
+
+// deepCopyValue copies the value with reflection. The pointers, maps and slices already copied are looked
+// up in seen, which keeps cycles from recursing forever.
+func deepCopyValue(v reflect.Value, seen map[copyKey]interface{}) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		k, c, ok := copied(v, seen)
+		if ok {
+			return reflect.ValueOf(c)
+		}
+		d := reflect.New(v.Type().Elem())
+		seen[k] = d.Interface()
+		d.Elem().Set(deepCopyValue(v.Elem(), seen))
+		return d
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		d := reflect.New(v.Type()).Elem()
+		d.Set(reflect.ValueOf(deepCopySeen(v.Elem().Interface(), seen)))
+		return d
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		k, c, ok := copied(v, seen)
+		if ok {
+			return reflect.ValueOf(c)
+		}
+		d := reflect.MakeMapWithSize(v.Type(), v.Len())
+		seen[k] = d.Interface()
+		iter := v.MapRange()
+		for iter.Next() {
+			d.SetMapIndex(iter.Key(), deepCopyValue(iter.Value(), seen))
+		}
+		return d
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		k, c, ok := copied(v, seen)
+		if ok {
+			return reflect.ValueOf(c)
+		}
+		d := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		seen[k] = d.Interface()
+		for i := 0; i < v.Len(); i++ {
+			d.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return d
+	case reflect.Array:
+		d := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			d.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return d
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) || v.Type() == reflect.TypeOf(decimal.Decimal{}) {
+			return v
+		}
+		d := reflect.New(v.Type()).Elem()
+		d.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if d.Field(i).CanSet() {
+				d.Field(i).Set(deepCopyValue(v.Field(i), seen))
+			}
+		}
+		return d
+	default:
+		return v
+	}
+}
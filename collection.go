@@ -1,63 +1,57 @@
 package collection
 
 import (
-	"bytes"
-	"encoding/gob"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/hulklab/collection/hamt"
 	"github.com/shopspring/decimal"
 )
 
 // Collect transforms src into Collection. The src could be json string, []string,
 // []map[string]interface{}, map[string]interface{}, []int, []int16, []int32, []int64,
-// []float32, []float64, []interface{}.
+// []float32, []float64, []interface{}. A slice of structs or of pointers to structs is collected by
+// CollectStructs.
 func Collect(src interface{}) Collection {
 	switch src.(type) {
 	case string:
 		jsonStr := strings.TrimSpace(src.(string))
-		if jsonStr[0] == '[' {
+		if strings.HasPrefix(jsonStr, "[") {
 			var p []interface{}
 			if err := json.Unmarshal([]byte(jsonStr), &p); err != nil {
-				return BaseCollection{err: err}
+				return BaseCollection{err: fmt.Errorf("%w: %v", ErrWrongValue, err)}
 			}
 			return Collect(p)
 		}
-		if jsonStr[0] == '{' {
+		if strings.HasPrefix(jsonStr, "{") {
 			var p map[string]interface{}
 			if err := json.Unmarshal([]byte(jsonStr), &p); err != nil {
-				return BaseCollection{err: err}
+				return BaseCollection{err: fmt.Errorf("%w: %v", ErrWrongValue, err)}
 			}
-			var c MapCollection
-			c.value = p
-			c.length = len(p)
-			return c
+			return newMapCollection(hamt.FromMap(p))
 		}
-		return BaseCollection{err: errors.New("invalid type")}
+		return BaseCollection{err: fmt.Errorf("%w: not a json array or object", ErrWrongValue)}
 	case []string:
 		var c StringArrayCollection
-		c.value = src.([]string)
+		c.value = stringList(src.([]string))
 		c.length = len(src.([]string))
 		return c
 	case []map[string]interface{}:
 		var c MapArrayCollection
-		c.value = src.([]map[string]interface{})
+		c.value = mapList(src.([]map[string]interface{}))
 		c.length = len(src.([]map[string]interface{}))
 		return c
 	case map[string]interface{}:
-		var c MapCollection
-		c.value = src.(map[string]interface{})
-		c.length = len(src.(map[string]interface{}))
-		return c
+		return newMapCollection(hamt.FromMap(src.(map[string]interface{})))
 	case []int:
 		var c NumberArrayCollection
 		var d = make([]decimal.Decimal, len(src.([]int)))
 		for k, v := range src.([]int) {
 			d[k] = decimal.New(int64(v), 0)
 		}
-		c.value = d
+		c.value = decimalList(d)
 		c.length = len(src.([]int))
 		return c
 	case []int8:
@@ -66,7 +60,7 @@ func Collect(src interface{}) Collection {
 		for k, v := range src.([]int8) {
 			d[k] = decimal.New(int64(v), 0)
 		}
-		c.value = d
+		c.value = decimalList(d)
 		c.length = len(src.([]int8))
 		return c
 	case []int16:
@@ -75,7 +69,7 @@ func Collect(src interface{}) Collection {
 		for k, v := range src.([]int16) {
 			d[k] = decimal.New(int64(v), 0)
 		}
-		c.value = d
+		c.value = decimalList(d)
 		c.length = len(src.([]int16))
 		return c
 	case []int32:
@@ -84,7 +78,7 @@ func Collect(src interface{}) Collection {
 		for k, v := range src.([]int32) {
 			d[k] = decimal.New(int64(v), 0)
 		}
-		c.value = d
+		c.value = decimalList(d)
 		c.length = len(src.([]int32))
 		return c
 	case []int64:
@@ -93,7 +87,7 @@ func Collect(src interface{}) Collection {
 		for k, v := range src.([]int64) {
 			d[k] = decimal.New(v, 0)
 		}
-		c.value = d
+		c.value = decimalList(d)
 		c.length = len(src.([]int64))
 		return c
 	case []float32:
@@ -102,7 +96,7 @@ func Collect(src interface{}) Collection {
 		for k, v := range src.([]float32) {
 			f[k] = decimal.NewFromFloat32(v)
 		}
-		c.value = f
+		c.value = decimalList(f)
 		c.length = len(src.([]float32))
 		return c
 	case []float64:
@@ -111,124 +105,175 @@ func Collect(src interface{}) Collection {
 		for k, v := range src.([]float64) {
 			f[k] = decimal.NewFromFloat(v)
 		}
-		c.value = f
+		c.value = decimalList(f)
 		c.length = len(src.([]float64))
 		return c
 	case []interface{}:
 		if len(src.([]interface{})) == 0 {
-			return BaseCollection{err: errors.New("wrong value")}
+			return BaseCollection{err: ErrWrongValue}
 		}
 		switch src.([]interface{})[0].(type) {
 		case map[string]interface{}:
 			var c MapArrayCollection
 			var f = make([]map[string]interface{}, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				f[k] = v.(map[string]interface{})
+				t, ok := v.(map[string]interface{})
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("map item", v))}
+				}
+				f[k] = t
 			}
-			c.value = f
+			c.value = mapList(f)
 			c.length = len(src.([]interface{}))
 			return c
 		case decimal.Decimal:
 			var c NumberArrayCollection
 			var f = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				f[k] = v.(decimal.Decimal)
+				t, ok := v.(decimal.Decimal)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("number item", v))}
+				}
+				f[k] = t
 			}
-			c.value = f
+			c.value = decimalList(f)
 			c.length = len(src.([]interface{}))
 			return c
 		case string:
 			var c StringArrayCollection
 			var f = make([]string, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				f[k] = v.(string)
+				t, ok := v.(string)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("string item", v))}
+				}
+				f[k] = t
 			}
-			c.value = f
+			c.value = stringList(f)
 			c.length = len(src.([]interface{}))
 			return c
 		case uint8:
 			var c StringArrayCollection
 			var f = make([]string, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				f[k] = string(v.([]uint8))
+				t, ok := v.([]uint8)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("[]uint8 item", v))}
+				}
+				f[k] = string(t)
 			}
-			c.value = f
+			c.value = stringList(f)
 			c.length = len(src.([]interface{}))
 			return c
 		case int:
 			var c NumberArrayCollection
 			var d = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				d[k] = decimal.New(int64(v.(int)), 0)
+				t, ok := v.(int)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("int item", v))}
+				}
+				d[k] = decimal.New(int64(t), 0)
 			}
-			c.value = d
+			c.value = decimalList(d)
 			c.length = len(src.([]interface{}))
 			return c
 		case int8:
 			var c NumberArrayCollection
 			var d = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				d[k] = decimal.New(int64(v.(int8)), 0)
+				t, ok := v.(int8)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("int8 item", v))}
+				}
+				d[k] = decimal.New(int64(t), 0)
 			}
-			c.value = d
+			c.value = decimalList(d)
 			c.length = len(src.([]interface{}))
 			return c
 		case int16:
 			var c NumberArrayCollection
 			var d = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				d[k] = decimal.New(int64(v.(int16)), 0)
+				t, ok := v.(int16)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("int16 item", v))}
+				}
+				d[k] = decimal.New(int64(t), 0)
 			}
-			c.value = d
+			c.value = decimalList(d)
 			c.length = len(src.([]interface{}))
 			return c
 		case int32:
 			var c NumberArrayCollection
 			var d = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				d[k] = decimal.New(int64(v.(int32)), 0)
+				t, ok := v.(int32)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("int32 item", v))}
+				}
+				d[k] = decimal.New(int64(t), 0)
 			}
-			c.value = d
+			c.value = decimalList(d)
 			c.length = len(src.([]interface{}))
 			return c
 		case int64:
 			var c NumberArrayCollection
 			var d = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				d[k] = decimal.New(v.(int64), 0)
+				t, ok := v.(int64)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("int64 item", v))}
+				}
+				d[k] = decimal.New(t, 0)
 			}
-			c.value = d
+			c.value = decimalList(d)
 			c.length = len(src.([]interface{}))
 			return c
 		case float32:
 			var c NumberArrayCollection
 			var f = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				f[k] = decimal.NewFromFloat32(v.(float32))
+				t, ok := v.(float32)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("float32 item", v))}
+				}
+				f[k] = decimal.NewFromFloat32(t)
 			}
-			c.value = f
+			c.value = decimalList(f)
 			c.length = len(src.([]interface{}))
 			return c
 		case float64:
 			var c NumberArrayCollection
 			var f = make([]decimal.Decimal, len(src.([]interface{})))
 			for k, v := range src.([]interface{}) {
-				f[k] = decimal.NewFromFloat(v.(float64))
+				t, ok := v.(float64)
+				if !ok {
+					return BaseCollection{err: fmt.Errorf("item %d: %w", k, wrongType("float64 item", v))}
+				}
+				f[k] = decimal.NewFromFloat(t)
 			}
-			c.value = f
+			c.value = decimalList(f)
 			c.length = len(src.([]interface{}))
 			return c
 		default:
-			return BaseCollection{err: errors.New("wrong type")}
+			return BaseCollection{err: wrongType("string, number or map item", src.([]interface{})[0])}
 		}
 	default:
-		return BaseCollection{err: errors.New("wrong type")}
+		if isStructSlice(src) {
+			return CollectStructs(src)
+		}
+		return BaseCollection{err: wrongType("slice or map", src)}
 	}
 }
 
 type Collection interface {
 	Value() interface{}
 
+	// Err returns the first error which happened in the chain of calls that built the collection. Once a call
+	// fails, the error is carried through every later call of the chain.
+	Err() error
+
 	// All returns the underlying array represented by the collection.
 	All() []interface{}
 
@@ -266,6 +311,16 @@ type Collection interface {
 
 	JoinE(delimiter string) (string, error)
 
+	// JSONPath returns the values which the RFC 9535 JSONPath expression selects, as a collection of their type.
+	JSONPath(expr string) Collection
+
+	// JSONPathValues returns the values which the RFC 9535 JSONPath expression selects, in order.
+	JSONPathValues(expr string) ([]interface{}, error)
+
+	// Clone returns a deep copy of the collection. The rows of a collection are shared with the collections
+	// derived from it, so a row must be cloned before it is changed in place.
+	Clone() Collection
+
 	// Combine combines the values of the collection, as keys, with the values of another array or collection.
 	Combine(value []interface{}) Collection
 
@@ -275,11 +330,67 @@ type Collection interface {
 	// Pluck retrieves all of the values for a given key.
 	Pluck(key string) Collection
 
+	// Pivot returns one row per value at index, with a column per value at columns which holds the result of
+	// the aggregator over the rows with both values. The cells without any row are set to fill.
+	Pivot(index, columns string, value Aggregator, fill ...interface{}) Collection
+
 	// Mode returns the mode value of a given key.
 	Mode(key ...string) []interface{}
 
 	ModeE(key ...string) ([]interface{}, error)
 
+	// Percentile returns the p-th percentile of the values, p is between 0 and 100. The value between two items
+	// is interpolated linearly.
+	Percentile(p float64, key ...string) decimal.Decimal
+
+	PercentileE(p float64, key ...string) (decimal.Decimal, error)
+
+	// Quantile returns the q-quantile of the values, q is between 0 and 1. The interpolation decides the value
+	// between two items.
+	Quantile(q float64, interpolation Interpolation, key ...string) decimal.Decimal
+
+	QuantileE(q float64, interpolation Interpolation, key ...string) (decimal.Decimal, error)
+
+	// Variance returns the population variance of the values.
+	Variance(key ...string) decimal.Decimal
+
+	VarianceE(key ...string) (decimal.Decimal, error)
+
+	// SampleVariance returns the sample variance of the values, which divides by n-1.
+	SampleVariance(key ...string) decimal.Decimal
+
+	SampleVarianceE(key ...string) (decimal.Decimal, error)
+
+	// StdDev returns the population standard deviation of the values.
+	StdDev(key ...string) decimal.Decimal
+
+	StdDevE(key ...string) (decimal.Decimal, error)
+
+	// SampleStdDev returns the sample standard deviation of the values.
+	SampleStdDev(key ...string) decimal.Decimal
+
+	SampleStdDevE(key ...string) (decimal.Decimal, error)
+
+	// IQR returns the interquartile range of the values, the difference of the 75th and the 25th percentile.
+	IQR(key ...string) decimal.Decimal
+
+	IQRE(key ...string) (decimal.Decimal, error)
+
+	// Skewness returns the population skewness of the values.
+	Skewness(key ...string) decimal.Decimal
+
+	SkewnessE(key ...string) (decimal.Decimal, error)
+
+	// Kurtosis returns the population excess kurtosis of the values, which is 0 for a normal distribution.
+	Kurtosis(key ...string) decimal.Decimal
+
+	KurtosisE(key ...string) (decimal.Decimal, error)
+
+	// Describe returns the count, mean, standard deviation, minimum, quartiles and maximum of the values.
+	Describe(key ...string) Description
+
+	DescribeE(key ...string) (Description, error)
+
 	// Only returns the items in the collection with the specified keys.
 	Only(keys []string) Collection
 
@@ -292,8 +403,8 @@ type Collection interface {
 	// Put sets the given key and value in the collection:.
 	Put(key string, value interface{}) Collection
 
-	// SortBy sorts the collection by the given key.
-	SortBy(key string) Collection
+	// SortBy sorts the collection by the given keys. A key is a string, a SortKey or a comparison function.
+	SortBy(keys ...interface{}) Collection
 
 	// Take returns a new collection with the specified number of items.
 	Take(num int) Collection
@@ -337,6 +448,23 @@ type Collection interface {
 	// This method will return the key / value pairs in the original collection that are not present in the given collection.
 	DiffKeys(map[string]interface{}) Collection
 
+	// DiffPatch returns the RFC 6902 JSON Patch which turns the collection into the given collection or map,
+	// including the changes of nested maps and arrays.
+	DiffPatch(interface{}) Patch
+
+	DiffPatchE(interface{}) (Patch, error)
+
+	// ApplyPatch returns the collection with the operations of the RFC 6902 JSON Patch applied.
+	ApplyPatch(Patch) Collection
+
+	// MergePatch returns the collection with the RFC 7396 JSON Merge Patch applied.
+	MergePatch(interface{}) Collection
+
+	// DiffBy compares the rows of the collection with the rows of another collection by the given key columns,
+	// and returns the rows which were added, removed and changed, with the before and after values of the
+	// changed columns.
+	DiffBy(other interface{}, keys ...string) RowDiff
+
 	// Dump dumps the collection's items.
 	Dump()
 
@@ -383,8 +511,9 @@ type Collection interface {
 
 	GetE(string, ...interface{}) (interface{}, error)
 
-	// GroupBy groups the collection's items by a given key.
-	GroupBy(string) Collection
+	// GroupBy groups the collection's items by the given keys. The result is a MapCollection of the groups,
+	// and its Aggregate method turns the groups into a MapArrayCollection with one row per group.
+	GroupBy(keys ...string) GroupedCollection
 
 	// Has determines if a given key exists in the collection.
 	Has(...string) bool
@@ -396,6 +525,34 @@ type Collection interface {
 
 	ImplodeE(string, string) (string, error)
 
+	// InnerJoin joins the collection with other, keeping the pairs of rows which have the same key. Other is a
+	// MapArrayCollection or a []map[string]interface{}. The rows are joined on one or more shared column names,
+	// on a JoinKey for both sides, or on two JoinKeys for the left and the right side. The columns of the right
+	// side which collide with the left side are prefixed with JoinPrefix.
+	InnerJoin(other interface{}, on ...interface{}) Collection
+
+	// LeftJoin is the same as InnerJoin, but also keeps the rows of the collection without a match.
+	LeftJoin(other interface{}, on ...interface{}) Collection
+
+	// RightJoin is the same as InnerJoin, but also keeps the rows of other without a match.
+	RightJoin(other interface{}, on ...interface{}) Collection
+
+	// FullOuterJoin is the same as InnerJoin, but also keeps the rows of both sides without a match.
+	FullOuterJoin(other interface{}, on ...interface{}) Collection
+
+	// SemiJoin returns the rows of the collection which have a match in other, the rows are not merged.
+	SemiJoin(other interface{}, on ...interface{}) Collection
+
+	// AntiJoin returns the rows of the collection which have no match in other.
+	AntiJoin(other interface{}, on ...interface{}) Collection
+
+	// Index returns the collection with a hash index on every given key, which the later calls of Where,
+	// WhereIn and FirstWhere on it use instead of scanning every row.
+	Index(keys ...string) Collection
+
+	// SortedIndex is the same as Index, but range conditions on numbers and times are looked up too.
+	SortedIndex(keys ...string) Collection
+
 	// Intersect removes any values from the original collection that are not present in the given array or collection.
 	Intersect([]string) Collection
 
@@ -419,6 +576,10 @@ type Collection interface {
 	// Keys returns all of the collection's keys.
 	Keys() Collection
 
+	// Lazy returns a LazyCollection which runs the chained operations on demand. Take and First stop
+	// pulling items as soon as they have enough of them.
+	Lazy() LazyCollection
+
 	// Last returns the last element in the collection that passes a given truth test.
 	Last(...CB) interface{}
 
@@ -438,9 +599,34 @@ type Collection interface {
 	// original collection.
 	Merge(interface{}) Collection
 
+	// DeepMerge merges the given map or collection into the collection, or into every row of a MapArrayCollection.
+	// The maps which both sides have at a key are merged recursively, and the other values with the strategy,
+	// MergeReplace when none is given.
+	DeepMerge(other interface{}, strategy ...MergeStrategy) Collection
+
+	// UpsertBy merges the given rows into the rows of the collection which have the same values at the key
+	// columns, and appends the rows without a match.
+	UpsertBy(keys []string, rows interface{}, strategy ...MergeStrategy) Collection
+
+	// Melt is the reverse of Pivot, it turns every row into one row per value column, with the name of the
+	// column in MeltVariable and its value in MeltValue.
+	Melt(idColumns []string, valueColumns ...string) Collection
+
 	// Pad will fill the array with the given value until the array reaches the specified size.
 	Pad(int, interface{}) Collection
 
+	// ParallelEach is the same as Each, but runs the callbacks on at most workers goroutines and keeps the
+	// order of the items. A panic inside a callback is returned as the error of the collection.
+	ParallelEach(workers int, cb func(item, value interface{}) (interface{}, bool)) Collection
+
+	// ParallelFilter is the same as Filter, but runs the callbacks on at most workers goroutines and keeps
+	// the order of the items. A panic inside a callback is returned as the error of the collection.
+	ParallelFilter(workers int, cb CB) Collection
+
+	// ParallelReject is the same as Reject, but runs the callbacks on at most workers goroutines and keeps
+	// the order of the items. A panic inside a callback is returned as the error of the collection.
+	ParallelReject(workers int, cb CB) Collection
+
 	// Partition separate elements that pass a given truth test from those that do not.
 	Partition(PartCB) (Collection, Collection)
 
@@ -472,6 +658,10 @@ type Collection interface {
 
 	SearchE(interface{}) (int, error)
 
+	// Set returns a new collection with the value at a given key or path such as "user.address.city" set to
+	// value.
+	Set(path string, value interface{}) Collection
+
 	// Shift removes and returns the first item from the collection.
 	Shift() Collection
 
@@ -485,7 +675,7 @@ type Collection interface {
 	Sort() Collection
 
 	// SortByDesc has the same signature as the sortBy method, but will sort the collection in the opposite order.
-	SortByDesc() Collection
+	SortByDesc(keys ...interface{}) Collection
 
 	// Splice removes and returns a slice of items starting at the specified index.
 	Splice(index ...int) Collection
@@ -502,11 +692,22 @@ type Collection interface {
 	// WhereNotIn filters the collection by a given key / value not contained within the given array.
 	WhereNotIn(string, []interface{}) Collection
 
+	// Window returns a WindowCollection which adds columns computed over the partitions of the rows, like the
+	// window functions of SQL.
+	Window(partitionBy []string, orderBy ...interface{}) WindowCollection
+
 	// ToJson converts the collection into a json string.
 	ToJson() string
 
 	ToJsonE() (string, error)
 
+	// ToCSV writes the collection as csv to w. The given columns are written as the first record, by default
+	// a MapArrayCollection writes all of the keys of its items in sorted order.
+	ToCSV(w io.Writer, columns ...string) error
+
+	// WriteNDJSON writes the items of the collection to w as newline delimited json, one item per line.
+	WriteNDJSON(w io.Writer) error
+
 	// ToNumberArray converts the collection into a plain golang slice which contains decimal.Decimal.
 	ToNumberArray() []decimal.Decimal
 
@@ -542,8 +743,11 @@ type Collection interface {
 
 	ToMapArrayE() ([]map[string]interface{}, error)
 
-	// Where filters the collection by a given key / value pair.
+	// Where filters the collection by a given key / value pair, or by a key, an operator and its operands.
 	Where(key string, values ...interface{}) Collection
+
+	// WhereGroup filters the collection by the conditions built by fn, which may be joined with OR and nested.
+	WhereGroup(fn func(q *WhereQuery)) Collection
 }
 
 func newDecimalFromInterface(a interface{}) decimal.Decimal {
@@ -574,6 +778,8 @@ func newDecimalFromInterface(a interface{}) decimal.Decimal {
 		d = decimal.NewFromFloat32(a.(float32))
 	case float64:
 		d = decimal.NewFromFloat(a.(float64))
+	case decimal.Decimal:
+		d = a.(decimal.Decimal)
 	default:
 	}
 
@@ -615,6 +821,74 @@ func isTrue(a interface{}) bool {
 	}
 }
 
+// compareValues compares two values for sorting. It returns -1, 0 or +1. Nil is the smallest value, numbers
+// compare as numbers, strings compare lexically, and values of other types compare by their formatting.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if isNumber(a) && isNumber(b) {
+		return nd(a).Cmp(nd(b))
+	}
+	if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			return strings.Compare(sa, sb)
+		}
+	}
+	if ba, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			switch {
+			case ba == bb:
+				return 0
+			case bb:
+				return -1
+			default:
+				return 1
+			}
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// valueHash returns the string a key is hashed as. Numbers of different golang types with the same value,
+// such as int 1 and the float64 1 of a json string, get the same hash.
+func valueHash(key interface{}) (string, bool) {
+	if key == nil {
+		return "", false
+	}
+	keys, ok := key.([]interface{})
+	if !ok {
+		keys = []interface{}{key}
+	}
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(0)
+		}
+		if isNumber(k) {
+			b.WriteString("n:" + nd(k).String())
+		} else {
+			b.WriteString(fmt.Sprintf("%T:%v", k, k))
+		}
+	}
+	return b.String(), true
+}
+
+// isNumber determines if a is a golang number or a decimal.Decimal.
+func isNumber(a interface{}) bool {
+	switch a.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, decimal.Decimal:
+		return true
+	default:
+		return false
+	}
+}
+
 func nd(a interface{}) decimal.Decimal {
 	return newDecimalFromInterface(a)
 }
@@ -625,22 +899,6 @@ type MapCB func(map[string]interface{}) (string, interface{})
 type PartCB func(int) bool
 type ReduceCB func(interface{}, interface{}) interface{}
 
-func copyMap(m map[string]interface{}) map[string]interface{} {
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	dec := gob.NewDecoder(&buf)
-	err := enc.Encode(m)
-	if err != nil {
-		return nil
-	}
-	var cm map[string]interface{}
-	err = dec.Decode(&cm)
-	if err != nil {
-		return nil
-	}
-	return cm
-}
-
 func dd(c Collection) {
 	fmt.Println(c)
 }
@@ -697,33 +955,3 @@ func newDecimalArray(src interface{}) []decimal.Decimal {
 		return nil
 	}
 }
-
-func qsort(arr []decimal.Decimal, order bool) []decimal.Decimal {
-	if len(arr) < 2 {
-		return arr
-	} else {
-		pivot := arr[0]
-		var less []decimal.Decimal
-		var greater []decimal.Decimal
-		for _, value := range arr[1:] {
-			if value.LessThanOrEqual(pivot) {
-				less = append(less, value)
-			} else {
-				greater = append(greater, value)
-			}
-		}
-
-		var result []decimal.Decimal
-		if order {
-			result = append(result, qsort(less, order)...)
-			result = append(result, pivot)
-			result = append(result, qsort(greater, order)...)
-		} else {
-			result = append(result, qsort(greater, order)...)
-			result = append(result, pivot)
-			result = append(result, qsort(less, order)...)
-		}
-
-		return result
-	}
-}
@@ -1,10 +1,18 @@
 package collection
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/magiconair/properties/assert"
 	"github.com/shopspring/decimal"
@@ -522,6 +530,15 @@ func TestMapArrayCollection_GroupBy(t *testing.T) {
 		{"name": "Mary", "sex": 1},
 		{"name": "Jane", "sex": 1},
 	})
+
+	// The groups of 1 and "1" differ, but share the bucket of their formatted key.
+	b := []map[string]interface{}{{"k": 1, "v": 1}, {"k": "1", "v": 2}, {"k": true, "v": 3}, {"k": "true", "v": 4}}
+	g := Collect(b).GroupBy("k")
+	assert.Equal(t, g.ToMap(), map[string]interface{}{
+		"1":    []map[string]interface{}{{"k": 1, "v": 1}, {"k": "1", "v": 2}},
+		"true": []map[string]interface{}{{"k": true, "v": 3}, {"k": "true", "v": 4}},
+	})
+	assert.Equal(t, g.Aggregate(Count()).Count(), 4)
 }
 
 func ExampleBaseCollection_GroupBy() {
@@ -1259,3 +1276,1344 @@ func BenchmarkMapArrayCollection_Diff(t *testing.B) {
 		Collect(a).Diff(b).ToIntArray()
 	}
 }
+
+func TestNumberArrayCollection_PushChain(t *testing.T) {
+	c := Collect([]int{1})
+	d := c
+	for i := 2; i <= 100; i++ {
+		d = d.Push(i)
+	}
+
+	assert.Equal(t, c.ToIntArray(), []int{1})
+	assert.Equal(t, d.Length(), 100)
+	assert.Equal(t, d.Shift().Splice(0, 3).ToIntArray(), []int{2, 3, 4})
+	assert.Equal(t, d.Slice(97).Push(0).ToIntArray(), []int{98, 99, 100, 0})
+	assert.Equal(t, d.Pop(), nd(100))
+}
+
+func BenchmarkStringArrayCollection_Push(t *testing.B) {
+	c := Collect([]string{"h"})
+	for i := 0; i < t.N; i++ {
+		c = c.Push("e")
+	}
+}
+
+func TestTypedCollection_Map(t *testing.T) {
+	c := CollectTyped([]int{2, 3, 4, 5, 6, 7})
+
+	assert.Equal(t, Map(c, func(value int) int {
+		return value + 2
+	}).All(), []int{4, 5, 6, 7, 8, 9})
+	assert.Equal(t, Map(c, strconv.Itoa).All(), []string{"2", "3", "4", "5", "6", "7"})
+}
+
+func ExampleMap() {
+	c := CollectTyped([]int{2, 3, 4, 5, 6, 7})
+
+	fmt.Println(Map(c, func(value int) int {
+		return value + 2
+	}).All())
+
+	// Output: [4 5 6 7 8 9]
+}
+
+func TestTypedCollection_Filter(t *testing.T) {
+	c := CollectTyped([]int{2, 3, 4, 5, 6, 7})
+
+	assert.Equal(t, c.Filter(func(value int) bool {
+		return value > 4
+	}).All(), []int{5, 6, 7})
+	assert.Equal(t, c.Reject(func(value int) bool {
+		return value > 4
+	}).All(), []int{2, 3, 4})
+}
+
+func TestTypedCollection_Reduce(t *testing.T) {
+	c := CollectTyped([]string{"h", "e", "l", "l", "o"})
+
+	assert.Equal(t, Reduce(c, "", func(carry string, value string) string {
+		return carry + value
+	}), "hello")
+}
+
+func TestTypedCollection_GroupBy(t *testing.T) {
+	type People struct {
+		Name string
+		Sex  int
+	}
+	c := CollectTyped([]People{{"mike", 0}, {"Mary", 1}, {"Jane", 1}})
+
+	g := GroupBy(c, func(p People) int {
+		return p.Sex
+	})
+	assert.Equal(t, g[1].All(), []People{{"Mary", 1}, {"Jane", 1}})
+	assert.Equal(t, SortBy(c, func(p People) string {
+		return p.Name
+	}).All(), []People{{"Jane", 1}, {"Mary", 1}, {"mike", 0}})
+}
+
+func TestTypedCollection_ChunkUnique(t *testing.T) {
+	c := CollectTyped([]int{4, 5, 5, 2, 2, 3, 6, 6, 7})
+
+	assert.Equal(t, Unique(c).All(), []int{4, 5, 2, 3, 6, 7})
+	assert.Equal(t, len(c.Chunk(4)), 3)
+	assert.Equal(t, c.Chunk(4)[2].All(), []int{7})
+	assert.Equal(t, c.Take(-2).All(), []int{6, 7})
+}
+
+func TestTypedCollection_Bridge(t *testing.T) {
+	c, err := FromCollection[int](Collect([]int{2, 3, 4}))
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, c.All(), []int{2, 3, 4})
+	assert.Equal(t, c.ToCollection().Sum().IntPart(), int64(9))
+
+	_, err = FromCollection[string](Collect([]int{2, 3, 4}))
+	assert.Equal(t, err, nil)
+	_, err = FromCollection[bool](Collect([]int{2, 3, 4}))
+	assert.Equal(t, err != nil, true)
+
+	// The numbers which are not whole or out of the range of the type are not truncated or wrapped.
+	_, err = FromCollection[int](Collect([]float64{1.9, -2.5}))
+	assert.Equal(t, errors.Is(err, ErrWrongType), true)
+	_, err = FromCollection[uint8](Collect([]int{-1, 300}))
+	assert.Equal(t, errors.Is(err, ErrWrongType), true)
+	_, err = FromCollection[int8](Collect([]int{128}))
+	assert.Equal(t, errors.Is(err, ErrWrongType), true)
+	u, err := FromCollection[uint64](Collect([]interface{}{decimal.RequireFromString("18446744073709551615"), decimal.New(2, 1)}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, u.All(), []uint64{18446744073709551615, 20})
+	f, err := FromCollection[float64](Collect([]float64{1.5}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, f.All(), []float64{1.5})
+}
+
+func TestMapArrayCollection_Lazy(t *testing.T) {
+	a := []map[string]interface{}{
+		{"name": "mike", "sex": 0, "age": 18},
+		{"name": "Mary", "sex": 1, "age": 20},
+		{"name": "Jane", "sex": 1, "age": 22},
+		{"name": "Lily", "sex": 1, "age": 24},
+	}
+
+	pulled := 0
+	l := Collect(a).Lazy().Filter(func(item, value interface{}) bool {
+		pulled++
+		return true
+	}).Where("sex", 1).Select("name").Take(2)
+
+	assert.Equal(t, l.ToMapArray(), []map[string]interface{}{{"name": "Mary"}, {"name": "Jane"}})
+	assert.Equal(t, pulled, 3)
+	assert.Equal(t, len(a[1]), 3)
+
+	pulled = 0
+	assert.Equal(t, l.First(), map[string]interface{}{"name": "Mary"})
+	assert.Equal(t, pulled, 2)
+
+	assert.Equal(t, l.Count(), 2)
+	assert.Equal(t, Collect(a).Lazy().Where("age", ">", 19).Collect().Length(), 3)
+	assert.Equal(t, Collect(a).Lazy().WhereNotIn("name", []interface{}{"mike", "Mary"}).Count(), 2)
+
+	_, err := Collect([]int{1, 2}).Lazy().Where("sex", 1).AllE()
+	assert.Equal(t, err != nil, true)
+}
+
+func TestNumberArrayCollection_Lazy(t *testing.T) {
+	c := Collect([]int{1, 2, 3, 4, 5, 6}).Lazy().Reject(func(item, value interface{}) bool {
+		return value.(decimal.Decimal).IntPart()%2 == 0
+	})
+
+	assert.Equal(t, c.Collect().ToIntArray(), []int{1, 3, 5})
+	assert.Equal(t, c.Take(0).Count(), 0)
+	assert.Equal(t, c.Map(func(value interface{}) interface{} {
+		return value.(decimal.Decimal).String()
+	}).ToStringArray(), []string{"1", "3", "5"})
+}
+
+func ExampleBaseCollection_Lazy() {
+	a := []map[string]interface{}{
+		{"name": "mike", "sex": 0},
+		{"name": "Mary", "sex": 1},
+		{"name": "Jane", "sex": 1},
+	}
+
+	fmt.Println(Collect(a).Lazy().Where("sex", 1).Take(1).ToMapArray())
+
+	// Output: [map[name:Mary sex:1]]
+}
+
+func TestNumberArrayCollection_Parallel(t *testing.T) {
+	a := make([]int, 1000)
+	for i := range a {
+		a[i] = i
+	}
+	c := Collect(a)
+
+	assert.Equal(t, c.ParallelEach(4, func(item, value interface{}) (interface{}, bool) {
+		return value.(decimal.Decimal).IntPart() * 2, false
+	}).ToIntArray()[999], 1998)
+
+	even := c.ParallelFilter(4, func(item, value interface{}) bool {
+		return value.(decimal.Decimal).IntPart()%2 == 0
+	}).ToIntArray()
+	assert.Equal(t, len(even), 500)
+	assert.Equal(t, even[:3], []int{0, 2, 4})
+
+	assert.Equal(t, Collect([]int{1, 2, 3, 4}).ParallelReject(0, func(item, value interface{}) bool {
+		return value.(decimal.Decimal).IntPart() > 2
+	}).ToIntArray(), []int{1, 2})
+
+	assert.Equal(t, Collect([]int{1, 2, 3, 4}).ParallelEach(2, func(item, value interface{}) (interface{}, bool) {
+		return value.(decimal.Decimal).IntPart() + 10, item.(int) == 1
+	}).ToIntArray(), []int{11, 12, 3, 4})
+
+	_, err := c.ParallelFilter(4, func(item, value interface{}) bool {
+		if item.(int) == 500 {
+			panic("boom")
+		}
+		return true
+	}).ToIntArrayE()
+	assert.Equal(t, err.Error(), "panic in parallel callback: boom")
+}
+
+func TestMapArrayCollection_Parallel(t *testing.T) {
+	a := []map[string]interface{}{
+		{"name": "mike", "sex": 0},
+		{"name": "Mary", "sex": 1},
+		{"name": "Jane", "sex": 1},
+	}
+
+	assert.Equal(t, Collect(a).ParallelFilter(2, func(item, value interface{}) bool {
+		return value.(map[string]interface{})["sex"] == 1
+	}).ToMapArray(), []map[string]interface{}{{"name": "Mary", "sex": 1}, {"name": "Jane", "sex": 1}})
+	assert.Equal(t, Collect(a).Filter(func(item, value interface{}) bool {
+		return value.(map[string]interface{})["sex"] == 0
+	}).ToMapArray(), []map[string]interface{}{{"name": "mike", "sex": 0}})
+	assert.Equal(t, Collect(a).ParallelEach(2, func(item, value interface{}) (interface{}, bool) {
+		return map[string]interface{}{"name": value.(map[string]interface{})["name"]}, false
+	}).Pluck("name").ToStringArray(), []string{"mike", "Mary", "Jane"})
+
+	err := Collect(a).ParallelEach(2, func(item, value interface{}) (interface{}, bool) {
+		return value.(map[string]interface{})["age"].(int), false
+	}).Err()
+	assert.Equal(t, errors.Is(err, ErrPanic), true)
+}
+
+func TestCollection_Err(t *testing.T) {
+	c := Collect([]int{1, 2, 3}).Where("name", "mike")
+	assert.Equal(t, errors.Is(c.Err(), ErrNotImplemented), true)
+
+	c = c.Select("name").Pluck("name").Take(1)
+	assert.Equal(t, c.Err().Error(), "not implemented: Where")
+	assert.Equal(t, c.Avg().IsZero(), true)
+
+	_, err := c.ToMapArrayE()
+	assert.Equal(t, errors.Is(err, ErrNotImplemented), true)
+	_, err = c.ToJsonE()
+	assert.Equal(t, errors.Is(err, ErrNotImplemented), true)
+
+	_, err = Collect([]int{1, 2, 3}).ContainsE(1)
+	assert.Equal(t, err, nil)
+	_, err = Collect("nil").ToIntArrayE()
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+	_, err = Collect(true).ToIntArrayE()
+	assert.Equal(t, errors.Is(err, ErrWrongType), true)
+	assert.Equal(t, errors.Is(Collect("[1,").Err(), ErrWrongValue), true)
+	assert.Equal(t, errors.Is(Collect(`{"a":}`).Err(), ErrWrongValue), true)
+	assert.Equal(t, Collect([]interface{}{map[string]interface{}{"a": 1}, "x"}).Err().Error(),
+		"item 1: wrong type: expected map item, got string")
+	assert.Equal(t, errors.Is(Collect([]interface{}{"x", 1}).Err(), ErrWrongType), true)
+	assert.Equal(t, errors.Is(Collect([]interface{}{1, "x"}).Err(), ErrWrongType), true)
+
+	assert.Equal(t, Collect([]string{"a"}).Push(1).Push("b").Err().Error(), "wrong type: expected string, got int")
+	assert.Equal(t, errors.Is(Collect([]int{1}).Take(2).Err(), ErrInvalidArgument), true)
+
+	m := Collect(map[string]interface{}{"name": "mike"})
+	ok, err := m.HasE("name")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, err, nil)
+	ok, _ = m.ContainsE("mike")
+	assert.Equal(t, ok, true)
+}
+
+func ExampleBaseCollection_Err() {
+	c := Collect([]int{1, 2, 3}).Where("name", "mike").Take(1)
+
+	fmt.Println(errors.Is(c.Err(), ErrNotImplemented))
+
+	// Output: true
+}
+
+func TestMapCollection_Path(t *testing.T) {
+	c := Collect(`{"user": {"name": "mike", "address": {"city": "NY"}},
+		"items": [{"sku": "a1", "price": 10}, {"sku": "b2", "price": 20}], "a.b": 1}`)
+
+	assert.Equal(t, c.Get("user.address.city"), "NY")
+	assert.Equal(t, c.Get("items.1.sku"), "b2")
+	assert.Equal(t, c.Get("items.*.price"), []interface{}{float64(10), float64(20)})
+	assert.Equal(t, c.Get("a.b"), float64(1))
+	assert.Equal(t, c.Get("user.phone", "none"), "none")
+
+	d := c.Set("user.address.zip", "10001").Set("items.*.price", 0)
+	assert.Equal(t, d.Get("user.address.zip"), "10001")
+	assert.Equal(t, d.Get("items.*.price"), []interface{}{0, 0})
+	assert.Equal(t, c.Get("user.address.zip"), nil)
+	assert.Equal(t, c.Get("items.0.price"), float64(10))
+	assert.Equal(t, c.Set("user.name.first", "m").Err() != nil, true)
+
+	e := c.Forget("items.0").Forget("user.address.city")
+	assert.Equal(t, e.Get("items.*.sku"), []interface{}{"b2"})
+	assert.Equal(t, e.Get("user.address"), map[string]interface{}{})
+	assert.Equal(t, c.Get("items.*.sku"), []interface{}{"a1", "b2"})
+}
+
+func TestMapArrayCollection_Path(t *testing.T) {
+	a := []map[string]interface{}{
+		{"name": "mike", "address": map[string]interface{}{"city": "NY"}, "items": []interface{}{
+			map[string]interface{}{"price": 30}}},
+		{"name": "Mary", "address": map[string]interface{}{"city": "LA"}, "items": []interface{}{
+			map[string]interface{}{"price": 5}, map[string]interface{}{"price": 8}}},
+		{"name": "Jane", "address": map[string]interface{}{"city": "NY"}, "items": []interface{}{}},
+	}
+	c := Collect(a)
+
+	assert.Equal(t, c.Where("address.city", "NY").Pluck("name").ToStringArray(), []string{"mike", "Jane"})
+	assert.Equal(t, c.Where("items.*.price", ">", 6).Pluck("name").ToStringArray(), []string{"mike", "Mary"})
+	assert.Equal(t, c.Pluck("items.*.price").ToIntArray(), []int{30, 5, 8})
+	assert.Equal(t, c.SortBy("address.city").Pluck("name").ToStringArray(), []string{"Mary", "mike", "Jane"})
+	assert.Equal(t, len(c.GroupBy("address.city").ToMap()["NY"].([]map[string]interface{})), 2)
+	assert.Equal(t, c.Get("1.address.city"), "LA")
+
+	d := c.Set("*.address.city", "SF")
+	assert.Equal(t, d.Pluck("address.city").ToStringArray(), []string{"SF", "SF", "SF"})
+	assert.Equal(t, c.Pluck("address.city").ToStringArray(), []string{"NY", "LA", "NY"})
+	assert.Equal(t, c.Forget("1").Pluck("name").ToStringArray(), []string{"mike", "Jane"})
+	assert.Equal(t, c.Forget("*.address").Get("0"), map[string]interface{}{"name": "mike",
+		"items": []interface{}{map[string]interface{}{"price": 30}}})
+}
+
+func ExampleBaseCollection_Get_path() {
+	c := Collect(`{"user": {"address": {"city": "NY"}}, "items": [{"price": 10}, {"price": 20}]}`)
+
+	fmt.Println(c.Get("user.address.city"))
+	fmt.Println(c.Get("items.*.price"))
+
+	// Output:
+	// NY
+	// [10 20]
+}
+
+func TestMapArrayCollection_Join(t *testing.T) {
+	users := Collect([]map[string]interface{}{
+		{"id": 1, "name": "mike"},
+		{"id": 2, "name": "Mary"},
+		{"id": 3, "name": "Jane"},
+	})
+	orders := []map[string]interface{}{
+		{"id": 1, "user_id": float64(1), "name": "book"},
+		{"id": 2, "user_id": float64(1), "name": "pen"},
+		{"id": 3, "user_id": float64(2), "name": "cup"},
+		{"id": 4, "user_id": float64(9), "name": "ink"},
+	}
+	byUser := func(row map[string]interface{}) interface{} {
+		return row["id"]
+	}
+	byOrder := func(row map[string]interface{}) interface{} {
+		return row["user_id"]
+	}
+
+	assert.Equal(t, users.InnerJoin(orders, byUser, byOrder).ToMapArray(), []map[string]interface{}{
+		{"id": 1, "name": "mike", "right_id": 1, "user_id": float64(1), "right_name": "book"},
+		{"id": 1, "name": "mike", "right_id": 2, "user_id": float64(1), "right_name": "pen"},
+		{"id": 2, "name": "Mary", "right_id": 3, "user_id": float64(2), "right_name": "cup"},
+	})
+	assert.Equal(t, users.LeftJoin(orders, byUser, byOrder).Length(), 4)
+	assert.Equal(t, users.LeftJoin(orders, byUser, byOrder).ToMapArray()[3], map[string]interface{}{"id": 3, "name": "Jane"})
+	assert.Equal(t, users.RightJoin(orders, byUser, byOrder).Pluck("right_name").ToStringArray(),
+		[]string{"book", "pen", "cup", "ink"})
+	assert.Equal(t, users.FullOuterJoin(orders, byUser, byOrder).Length(), 5)
+	assert.Equal(t, users.SemiJoin(orders, byUser, byOrder).Pluck("name").ToStringArray(), []string{"mike", "Mary"})
+	assert.Equal(t, users.AntiJoin(orders, byUser, byOrder).Pluck("name").ToStringArray(), []string{"Jane"})
+
+	assert.Equal(t, users.InnerJoin(Collect(orders), "id").ToMapArray(), []map[string]interface{}{
+		{"id": 1, "name": "mike", "user_id": float64(1), "right_name": "book"},
+		{"id": 2, "name": "Mary", "user_id": float64(1), "right_name": "pen"},
+		{"id": 3, "name": "Jane", "user_id": float64(2), "right_name": "cup"},
+	})
+	assert.Equal(t, users.InnerJoin(orders, "id", "name").Length(), 0)
+	assert.Equal(t, errors.Is(users.InnerJoin(orders).Err(), ErrInvalidArgument), true)
+	assert.Equal(t, errors.Is(users.InnerJoin(1, "id").Err(), ErrWrongType), true)
+}
+
+func TestMapArrayCollection_Aggregate(t *testing.T) {
+	a := []map[string]interface{}{
+		{"city": "NY", "sex": 1, "name": "mike", "age": 18},
+		{"city": "LA", "sex": 1, "name": "Mary", "age": 20},
+		{"city": "NY", "sex": 1, "name": "Jane", "age": 22},
+		{"city": "NY", "sex": 0, "name": "Lily", "age": 31},
+		{"city": "LA", "sex": 1, "name": "Mary", "age": 27},
+	}
+
+	g := Collect(a).GroupBy("city")
+	assert.Equal(t, len(g.ToMap()["NY"].([]map[string]interface{})), 3)
+
+	rows := g.Aggregate(Count(), Sum("age"), Avg("age").As("avg"), Min("age"), Max("name"),
+		CountDistinct("name"), First("name"), Last("name"), Median("age")).ToMapArray()
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[0]["city"], "NY")
+	assert.Equal(t, rows[0]["count"], 3)
+	assert.Equal(t, rows[0]["sum_age"].(decimal.Decimal).IntPart(), int64(71))
+	assert.Equal(t, rows[1]["avg"].(decimal.Decimal).String(), "23.5")
+	assert.Equal(t, rows[0]["min_age"], 18)
+	assert.Equal(t, rows[0]["max_name"], "mike")
+	assert.Equal(t, rows[1]["count_distinct_name"], 1)
+	assert.Equal(t, rows[0]["first_name"], "mike")
+	assert.Equal(t, rows[0]["last_name"], "Lily")
+	assert.Equal(t, rows[0]["median_age"].(decimal.Decimal).IntPart(), int64(22))
+
+	rows = Collect(a).GroupBy("city", "sex").Aggregate(Count(), NewAggregator("names", func(rows []map[string]interface{}) interface{} {
+		return Collect(rows).Pluck("name").ToStringArray()
+	})).ToMapArray()
+	assert.Equal(t, rows, []map[string]interface{}{
+		{"city": "NY", "sex": 1, "count": 2, "names": []string{"mike", "Jane"}},
+		{"city": "LA", "sex": 1, "count": 2, "names": []string{"Mary", "Mary"}},
+		{"city": "NY", "sex": 0, "count": 1, "names": []string{"Lily"}},
+	})
+	assert.Equal(t, Collect([]int{1}).GroupBy("a").Aggregate(Count()).Err() != nil, true)
+}
+
+func ExampleGroupedCollection_Aggregate() {
+	a := []map[string]interface{}{
+		{"city": "NY", "age": 18},
+		{"city": "LA", "age": 20},
+		{"city": "NY", "age": 22},
+	}
+
+	fmt.Println(Collect(a).GroupBy("city").Aggregate(Count(), Sum("age").As("total")).ToMapArray())
+
+	// Output: [map[city:NY count:2 total:40] map[city:LA count:1 total:20]]
+}
+
+func TestMapArrayCollection_CSV(t *testing.T) {
+	c := CollectCSV(strings.NewReader("name;age;vip\nmike;18;true\n\"Mary; Jr\";20;false\nJane;;true\n"),
+		CSVOptions{Comma: ';', InferTypes: true, Types: map[string]CSVType{"vip": CSVString}})
+
+	rows, err := c.ToMapArrayE()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rows[1]["name"], "Mary; Jr")
+	assert.Equal(t, rows[0]["age"], decimal.New(18, 0))
+	assert.Equal(t, rows[2]["age"], nil)
+	assert.Equal(t, rows[0]["vip"], "true")
+	assert.Equal(t, c.Sum("age").IntPart(), int64(38))
+
+	var b strings.Builder
+	assert.Equal(t, c.ToCSV(&b), nil)
+	assert.Equal(t, b.String(), "age,name,vip\n18,mike,true\n20,Mary; Jr,false\n,Jane,true\n")
+
+	b.Reset()
+	assert.Equal(t, c.ToCSV(&b, "vip", "name"), nil)
+	assert.Equal(t, b.String(), "vip,name\ntrue,mike\nfalse,Mary; Jr\ntrue,Jane\n")
+
+	d := CollectCSV(strings.NewReader("1,true\n2,false\n"), CSVOptions{NoHeader: true, InferTypes: true})
+	assert.Equal(t, d.ToMultiDimensionalArray(), [][]interface{}{{decimal.New(1, 0), true}, {decimal.New(2, 0), false}})
+	b.Reset()
+	assert.Equal(t, d.ToCSV(&b, "id", "ok"), nil)
+	assert.Equal(t, b.String(), "id,ok\n1,true\n2,false\n")
+
+	assert.Equal(t, errors.Is(CollectCSV(strings.NewReader("a,b\n1\n")).Err(), ErrWrongValue), true)
+	assert.Equal(t, errors.Is(CollectCSV(strings.NewReader("a\n\"x\n")).Err(), ErrWrongValue), true)
+	assert.Equal(t, errors.Is(CollectCSV(strings.NewReader("a\nx\n"), CSVOptions{Types: map[string]CSVType{"a": CSVBool}}).Err(),
+		ErrWrongValue), true)
+}
+
+type testAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testModel struct {
+	ID      int    `collection:"id" json:"user_id"`
+	Created string `mapstructure:"created_at"`
+}
+
+type testUser struct {
+	testModel
+	Name     string          `json:"name"`
+	Nickname string          `json:"nickname,omitempty"`
+	Password string          `json:"-"`
+	Home     testAddress     `json:"home"`
+	Work     *testAddress    `json:"work,flatten"`
+	Tags     []string        `json:"tags"`
+	Price    decimal.Decimal `json:"price"`
+	secret   string
+}
+
+func TestCollectStructs(t *testing.T) {
+	users := []testUser{
+		{testModel: testModel{ID: 1, Created: "2020"}, Name: "mike", Password: "x", Home: testAddress{City: "Paris"},
+			Work: &testAddress{City: "Lyon", Zip: "69000"}, Tags: []string{"a"}, Price: decimal.NewFromFloat(1.5), secret: "y"},
+		{testModel: testModel{ID: 2}, Name: "mary", Nickname: "m", Home: testAddress{City: "Rome", Zip: "00100"}},
+	}
+
+	c := Collect(users)
+	assert.Equal(t, c.Err(), nil)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{
+		{"id": 1, "created_at": "2020", "name": "mike", "home": map[string]interface{}{"city": "Paris"},
+			"work.city": "Lyon", "work.zip": "69000", "tags": []string{"a"}, "price": decimal.NewFromFloat(1.5)},
+		{"id": 2, "created_at": "", "name": "mary", "nickname": "m",
+			"home": map[string]interface{}{"city": "Rome", "zip": "00100"}, "tags": nil, "price": decimal.Decimal{}},
+	})
+	assert.Equal(t, c.Sum("price").String(), "1.5")
+	assert.Equal(t, c.Where("home.city", "Rome").Pluck("name").ToStringArray(), []string{"mary"})
+
+	var pointers = []*testUser{&users[1]}
+	assert.Equal(t, CollectStructs(pointers).Pluck("id").ToIntArray(), []int{2})
+
+	var back []testUser
+	assert.Equal(t, CollectStructs([]testAddress{{City: "Oslo"}}).ToStructE(&back), nil)
+	assert.Equal(t, back[0].Home, testAddress{})
+
+	assert.Equal(t, errors.Is(CollectStructs([]*testUser{nil}).Err(), ErrWrongValue), true)
+	assert.Equal(t, errors.Is(CollectStructs([]int{1}).Err(), ErrWrongType), true)
+}
+
+func TestMapArrayCollection_SortBy(t *testing.T) {
+	c := Collect([]map[string]interface{}{
+		{"name": "mike", "team": "b", "score": 3},
+		{"name": "mary", "team": "a", "score": nil},
+		{"name": "jane", "team": "b", "score": 5},
+		{"name": "jack", "team": "a", "score": 3},
+		{"name": "rose", "team": "b", "score": 3},
+	})
+
+	assert.Equal(t, c.SortBy("team", Desc("score")).Pluck("name").ToStringArray(),
+		[]string{"jack", "mary", "jane", "mike", "rose"})
+	assert.Equal(t, c.SortBy(SortKey{Key: "score", Nulls: NullsLast}).Pluck("name").ToStringArray(),
+		[]string{"mike", "jack", "rose", "jane", "mary"})
+	assert.Equal(t, c.SortByDesc("team", SortKey{Key: "score", Desc: true, Nulls: NullsFirst}).Pluck("name").ToStringArray(),
+		[]string{"mike", "rose", "jane", "mary", "jack"})
+	assert.Equal(t, c.SortBy(SortKey{Key: "name", Compare: func(a, b interface{}) int {
+		return len(a.(string)) - len(b.(string))
+	}}, "name").Pluck("name").ToStringArray(), []string{"jack", "jane", "mary", "mike", "rose"})
+
+	assert.Equal(t, errors.Is(c.SortBy().Err(), ErrInvalidArgument), true)
+	assert.Equal(t, errors.Is(c.SortBy(1).Err(), ErrWrongType), true)
+}
+
+func TestStringArrayCollection_SortBy(t *testing.T) {
+	c := Collect([]string{"pear", "Fig", "apple", "kiwi"})
+
+	assert.Equal(t, c.Sort().ToStringArray(), []string{"Fig", "apple", "kiwi", "pear"})
+	assert.Equal(t, c.SortByDesc().ToStringArray(), []string{"pear", "kiwi", "apple", "Fig"})
+	assert.Equal(t, c.SortBy(func(a, b interface{}) int {
+		return len(a.(string)) - len(b.(string))
+	}).ToStringArray(), []string{"Fig", "pear", "kiwi", "apple"})
+	assert.Equal(t, errors.Is(c.SortBy("name").Err(), ErrInvalidArgument), true)
+
+	n := Collect([]int{3, 1, 2})
+	assert.Equal(t, n.SortBy().ToIntArray(), []int{1, 2, 3})
+	assert.Equal(t, n.SortBy(Desc("")).ToIntArray(), []int{3, 2, 1})
+}
+
+func TestNumberArrayCollection_Statistics(t *testing.T) {
+	c := Collect([]int{9, 2, 4, 4, 5, 4, 7, 5})
+
+	assert.Equal(t, c.Percentile(25).String(), "4")
+	assert.Equal(t, c.Percentile(75).String(), "5.5")
+	assert.Equal(t, c.Quantile(0.1, QuantileLinear).String(), "3.4")
+	assert.Equal(t, c.Quantile(0.1, QuantileLower).String(), "2")
+	assert.Equal(t, c.Quantile(0.1, QuantileHigher).String(), "4")
+	assert.Equal(t, c.Quantile(0.1, QuantileNearest).String(), "4")
+	assert.Equal(t, c.Quantile(0.1, QuantileMidpoint).String(), "3")
+	assert.Equal(t, c.IQR().String(), "1.5")
+	assert.Equal(t, c.Variance().String(), "4")
+	assert.Equal(t, c.StdDev().String(), "2")
+	assert.Equal(t, c.SampleVariance().StringFixed(4), "4.5714")
+	assert.Equal(t, c.SampleStdDev().StringFixed(4), "2.1381")
+	assert.Equal(t, c.Skewness().String(), "0.65625")
+	assert.Equal(t, c.Kurtosis().String(), "-0.21875")
+	assert.Equal(t, Collect([]int{2}).StdDev().String(), "0")
+	assert.Equal(t, Collect([]float64{2}).Kurtosis().String(), "0")
+
+	d := c.Describe()
+	assert.Equal(t, d.Count, 8)
+	assert.Equal(t, d.Mean.String(), "5")
+	assert.Equal(t, d.Min.String()+" "+d.P25.String()+" "+d.Median.String()+" "+d.P75.String()+" "+d.Max.String(), "2 4 4.5 5.5 9")
+
+	_, err := c.PercentileE(101)
+	assert.Equal(t, errors.Is(err, ErrInvalidArgument), true)
+	_, err = c.PercentileE(math.NaN())
+	assert.Equal(t, errors.Is(err, ErrInvalidArgument), true)
+	_, err = c.QuantileE(math.Inf(1), QuantileLinear)
+	assert.Equal(t, errors.Is(err, ErrInvalidArgument), true)
+	_, err = Collect([]int{1}).SampleVarianceE()
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+
+	m := Collect([]map[string]interface{}{{"score": 1}, {"score": 3}, {"score": nil}, {"name": "mike"}})
+	assert.Equal(t, m.Percentile(50, "score").String(), "2")
+	assert.Equal(t, m.Variance("score").String(), "1")
+	assert.Equal(t, m.Describe("score").Count, 2)
+	_, err = m.QuantileE(math.Inf(-1), QuantileLower, "score")
+	assert.Equal(t, errors.Is(err, ErrInvalidArgument), true)
+	_, err = m.StdDevE()
+	assert.Equal(t, errors.Is(err, ErrInvalidArgument), true)
+}
+
+func TestMapArrayCollection_Window(t *testing.T) {
+	c := Collect([]map[string]interface{}{
+		{"name": "mike", "team": "a", "score": 30},
+		{"name": "mary", "team": "b", "score": 20},
+		{"name": "jane", "team": "a", "score": 50},
+		{"name": "jack", "team": "a", "score": 30},
+		{"name": "rose", "team": "b", "score": nil},
+		{"name": "lily", "team": "a", "score": 10},
+	})
+
+	rows := c.Window([]string{"team"}, Desc("score")).
+		RowNumber("row").
+		Rank("rank").
+		DenseRank("dense").
+		Ntile("tile", 3).
+		Lag("prev", "name", 1, "-").
+		Lead("next", "score", 1).
+		RunningSum("total", "score").
+		MovingAvg("avg", "score", 2).
+		Collect().ToMapArray()
+
+	var got = make([]string, len(rows))
+	for i, row := range rows {
+		got[i] = fmt.Sprintf("%v %v %v %v %v %v %v %v %v", row["name"], row["row"], row["rank"], row["dense"],
+			row["tile"], row["prev"], row["next"], row["total"], row["avg"])
+	}
+	assert.Equal(t, got, []string{
+		"mike 2 2 2 1 jane 30 80 40",
+		"mary 1 1 1 1 - <nil> 20 20",
+		"jane 1 1 1 1 - 30 50 50",
+		"jack 3 2 2 2 mike 10 110 30",
+		"rose 2 2 2 2 mary <nil> 20 20",
+		"lily 4 4 3 3 jack <nil> 120 20",
+	})
+	_, ok := c.ToMapArray()[0]["row"]
+	assert.Equal(t, ok, false)
+
+	avg := c.Window(nil, "name").RunningAvg("avg", "score").Collect().Pluck("avg").All()
+	assert.Equal(t, fmt.Sprint(avg), "[28 27.5 40 30 28 30]")
+
+	assert.Equal(t, errors.Is(c.Window(nil).Ntile("tile", 0).Collect().Err(), ErrInvalidArgument), true)
+	assert.Equal(t, errors.Is(c.Window(nil, 1).Collect().Err(), ErrWrongType), true)
+}
+
+func TestMapArrayCollection_Pivot(t *testing.T) {
+	c := Collect([]map[string]interface{}{
+		{"month": "jan", "product": "tea", "revenue": 10},
+		{"month": "jan", "product": "tea", "revenue": 5},
+		{"month": "jan", "product": "coffee", "revenue": 20},
+		{"month": "feb", "product": "coffee", "revenue": 30},
+	})
+
+	pivot := c.Pivot("month", "product", Sum("revenue"), 0)
+	assert.Equal(t, pivot.ToMapArray(), []map[string]interface{}{
+		{"month": "jan", "tea": decimal.New(15, 0), "coffee": decimal.New(20, 0)},
+		{"month": "feb", "tea": 0, "coffee": decimal.New(30, 0)},
+	})
+	assert.Equal(t, c.Pivot("month", "product", Count()).ToMapArray()[1], map[string]interface{}{
+		"month": "feb", "tea": nil, "coffee": 1,
+	})
+
+	melted := pivot.Melt([]string{"month"}, "tea", "coffee")
+	assert.Equal(t, melted.ToMapArray(), []map[string]interface{}{
+		{"month": "jan", MeltVariable: "tea", MeltValue: decimal.New(15, 0)},
+		{"month": "jan", MeltVariable: "coffee", MeltValue: decimal.New(20, 0)},
+		{"month": "feb", MeltVariable: "tea", MeltValue: 0},
+		{"month": "feb", MeltVariable: "coffee", MeltValue: decimal.New(30, 0)},
+	})
+	assert.Equal(t, pivot.Melt([]string{"month"}).Pluck(MeltVariable).ToStringArray(),
+		[]string{"coffee", "tea", "coffee", "tea"})
+	assert.Equal(t, errors.Is(c.Pivot("month", "product", Aggregator{}).Err(), ErrInvalidArgument), true)
+
+	clash := Collect([]map[string]interface{}{
+		{"month": "jan", "product": "month", "revenue": 1},
+		{"month": "jan", "product": 1, "revenue": 2},
+		{"month": "jan", "product": "1", "revenue": 3},
+	})
+	assert.Equal(t, errors.Is(clash.Pivot("month", "product", Sum("revenue")).Err(), ErrInvalidArgument), true)
+	assert.Equal(t, errors.Is(clash.Where("product", "!=", "month").Pivot("month", "product", Sum("revenue")).Err(), ErrInvalidArgument), true)
+	assert.Equal(t, clash.WhereNotIn("product", []interface{}{"month", "1"}).Pivot("month", "product", Sum("revenue")).ToMapArray(),
+		[]map[string]interface{}{{"month": "jan", "1": decimal.New(2, 0)}})
+}
+
+func TestMapArrayCollection_WhereOperators(t *testing.T) {
+	c := Collect([]map[string]interface{}{
+		{"name": "Mike", "age": 18, "born": "2002-05-01", "tags": []string{"vip"}, "email": nil},
+		{"name": "mary", "age": 25, "born": "1995-10-12", "tags": []string{}, "email": "mary@example.com"},
+		{"name": "Jane", "age": 30.5, "born": "1990-01-31", "tags": []string{"new", "vip"}},
+	})
+	names := func(c Collection) []string {
+		assert.Equal(t, c.Err(), nil)
+		return c.Pluck("name").ToStringArray()
+	}
+
+	assert.Equal(t, names(c.Where("age", "!=", 25)), []string{"Mike", "Jane"})
+	assert.Equal(t, names(c.Where("age", "<>", 18.0)), []string{"mary", "Jane"})
+	assert.Equal(t, names(c.Where("name", "like", "M%")), []string{"Mike"})
+	assert.Equal(t, names(c.Where("name", "ILIKE", "m_%")), []string{"Mike", "mary"})
+	assert.Equal(t, names(c.Where("name", "not like", "%a%")), []string{"Mike"})
+	assert.Equal(t, names(c.Where("age", "between", 18, 25)), []string{"Mike", "mary"})
+	assert.Equal(t, names(c.Where("age", "not between", []int{18, 25})), []string{"Jane"})
+	assert.Equal(t, names(c.Where("name", "regexp", "^[A-Z]")), []string{"Mike", "Jane"})
+	assert.Equal(t, names(c.Where("email", "is", nil)), []string{"Mike", "Jane"})
+	assert.Equal(t, names(c.Where("email", "IS NOT", nil)), []string{"mary"})
+	nulls := Collect([]map[string]interface{}{{"s": "null"}, {"s": nil}})
+	assert.Equal(t, nulls.Where("s", "null").ToMapArray(), []map[string]interface{}{{"s": "null"}})
+	assert.Equal(t, nulls.Where("s", "is", nil).ToMapArray(), []map[string]interface{}{{"s": nil}})
+	assert.Equal(t, errors.Is(c.Where("email", "is", "null").Err(), ErrInvalidArgument), true)
+	assert.Equal(t, names(c.Where("tags", "contains", "vip")), []string{"Mike", "Jane"})
+	assert.Equal(t, names(c.Where("email", "contains", "@")), []string{"mary"})
+	assert.Equal(t, names(c.Where("name", "starts with", "Ja")), []string{"Jane"})
+	assert.Equal(t, names(c.Where("name", "in", []string{"Jane", "mary"})), []string{"mary", "Jane"})
+	assert.Equal(t, names(c.Where("born", ">", "1995-01-01")), []string{"Mike", "mary"})
+	assert.Equal(t, names(c.Where("born", "<", time.Date(1995, 1, 1, 0, 0, 0, 0, time.UTC))), []string{"Jane"})
+	assert.Equal(t, c.FirstWhere("age", ">=", 20)["name"], "mary")
+
+	assert.Equal(t, errors.Is(c.Where("age", "~", 1).Err(), ErrInvalidArgument), true)
+	_, err := c.FirstWhereE("name", "regexp", "(")
+	assert.Equal(t, errors.Is(err, ErrInvalidArgument), true)
+}
+
+func TestMapArrayCollection_WhereGroup(t *testing.T) {
+	c := Collect([]map[string]interface{}{
+		{"name": "mike", "age": 18, "vip": true},
+		{"name": "mary", "age": 25, "vip": false},
+		{"name": "jane", "age": 30, "vip": true},
+		{"name": "jack", "age": 16, "vip": false},
+	})
+
+	assert.Equal(t, c.WhereGroup(func(q *WhereQuery) {
+		q.Where("age", "<", 18).OrWhere("vip", true).Where("age", ">", 20)
+	}).Pluck("name").ToStringArray(), []string{"jane", "jack"})
+
+	assert.Equal(t, c.WhereGroup(func(q *WhereQuery) {
+		q.Where("name", "like", "m%").WhereGroup(func(q *WhereQuery) {
+			q.Where("vip", true).OrWhere("age", ">", 20)
+		})
+	}).Pluck("name").ToStringArray(), []string{"mike", "mary"})
+
+	assert.Equal(t, c.Lazy().WhereGroup(func(q *WhereQuery) {
+		q.Where("age", ">=", 25).OrWhereGroup(func(q *WhereQuery) {
+			q.Where("name", "jack")
+		})
+	}).Count(), 3)
+
+	assert.Equal(t, errors.Is(c.WhereGroup(func(q *WhereQuery) {
+		q.Where("age", "<", 18).OrWhere("age", "almost", 20)
+	}).Err(), ErrInvalidArgument), true)
+}
+
+func TestMapArrayCollection_Index(t *testing.T) {
+	born := func(year int) time.Time {
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "mike", "score": 7.5, "born": born(2000)},
+		{"id": 2, "name": "mary", "score": 3, "born": born(1990)},
+		{"id": 3, "name": "jane", "score": nil, "born": born(2010)},
+		{"id": 4, "name": "mike", "score": int64(9), "born": born(1990)},
+		{"id": 5, "name": "rose", "score": decimal.New(3, 0)},
+	}
+	scan := Collect(rows)
+	indexed := scan.SortedIndex("name", "score", "born")
+	assert.Equal(t, indexed.Err(), nil)
+
+	var conditions = [][]interface{}{
+		{"name", "mike"},
+		{"name", "=", "nobody"},
+		{"score", 3},
+		{"score", "in", []interface{}{3.0, 9}},
+		{"score", ">", 3},
+		{"score", ">=", "3"},
+		{"score", "<", 9},
+		{"score", "<=", decimal.New(75, -1)},
+		{"score", "between", 3, 7.5},
+		{"score", ">", "high"},
+		{"born", "<", "2000-01-01"},
+		{"born", "between", born(1990), born(2000)},
+		{"born", "1990-01-01"},
+		{"name", ">", "m"},
+		{"name", "like", "m%"},
+		{"score", "null"},
+		{"score", "is", nil},
+		{"score", "is not", nil},
+	}
+	for _, cond := range conditions {
+		key, values := cond[0].(string), cond[1:]
+		assert.Equal(t, indexed.Where(key, values...).ToMapArray(), scan.Where(key, values...).ToMapArray(), fmt.Sprint(cond))
+		assert.Equal(t, indexed.FirstWhere(key, values...), scan.FirstWhere(key, values...), fmt.Sprint(cond))
+	}
+	in := []interface{}{"rose", "mike"}
+	assert.Equal(t, indexed.WhereIn("name", in).ToMapArray(), scan.WhereIn("name", in).ToMapArray())
+
+	// The conditions an index can answer are looked up instead of scanned.
+	m := indexed.(MapArrayCollection)
+	_, ok := m.indexLookup("score", []interface{}{"between", 3, 7.5})
+	assert.Equal(t, ok, true)
+	_, ok = m.indexLookup("name", []interface{}{">", "m"})
+	assert.Equal(t, ok, false)
+	_, ok = scan.Index("name").(MapArrayCollection).indexLookup("score", []interface{}{3})
+	assert.Equal(t, ok, false)
+
+	assert.Equal(t, errors.Is(scan.Index().Err(), ErrInvalidArgument), true)
+	assert.Equal(t, errors.Is(scan.Index("*.id").Err(), ErrInvalidArgument), true)
+}
+
+func BenchmarkMapArrayCollection_Index(b *testing.B) {
+	var rows = make([]map[string]interface{}, 10000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": strconv.Itoa(i)}
+	}
+	c := Collect(rows).Index("id")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.FirstWhere("id", i%len(rows))
+	}
+}
+
+type testPoint struct {
+	X, Y int
+	Next *testPoint
+}
+
+type testLine struct {
+	From testPoint
+	To   *testPoint
+}
+
+func TestMapArrayCollection_Clone(t *testing.T) {
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	loop := &testPoint{X: 1}
+	loop.Next = loop
+	rows := []map[string]interface{}{
+		{"name": "mike", "when": when, "price": decimal.New(15, -1), "point": loop,
+			"tags": []string{"a"}, "meta": map[string]interface{}{"list": []interface{}{1, map[string]interface{}{"k": "v"}}},
+			"scores": map[string][]int{"math": {1, 2}}},
+	}
+	c := Collect(rows)
+
+	// Where shares the rows it keeps, and Select builds new ones instead of changing them.
+	assert.Equal(t, reflect.ValueOf(c.Where("name", "mike").ToMapArray()[0]).Pointer(), reflect.ValueOf(rows[0]).Pointer())
+	assert.Equal(t, c.Select("name").ToMapArray(), []map[string]interface{}{{"name": "mike"}})
+	assert.Equal(t, len(rows[0]), 7)
+
+	clone := c.Clone().ToMapArray()[0]
+	assert.Equal(t, reflect.DeepEqual(clone, rows[0]), true)
+	clone["meta"].(map[string]interface{})["list"].([]interface{})[1].(map[string]interface{})["k"] = "changed"
+	clone["tags"].([]string)[0] = "changed"
+	clone["scores"].(map[string][]int)["math"][0] = 0
+	p := clone["point"].(*testPoint)
+	p.X = 2
+	assert.Equal(t, p.Next == p, true)
+	assert.Equal(t, rows[0]["meta"].(map[string]interface{})["list"].([]interface{})[1], map[string]interface{}{"k": "v"})
+	assert.Equal(t, rows[0]["tags"], []string{"a"})
+	assert.Equal(t, rows[0]["scores"], map[string][]int{"math": {1, 2}})
+	assert.Equal(t, loop.X, 1)
+	assert.Equal(t, clone["when"], when)
+
+	m := Collect(map[string]interface{}{"list": []interface{}{1}})
+	m.Clone().Get("list").([]interface{})[0] = 2
+	assert.Equal(t, m.Get("list"), []interface{}{1})
+
+	// A struct and its first field have the same address, but are copied apart.
+	line := &testLine{From: testPoint{X: 1}}
+	line.To = &line.From
+	rows = []map[string]interface{}{{"line": line, "from": &line.From}}
+	clone = Collect(rows).Clone().ToMapArray()[0]
+	assert.Equal(t, clone["line"].(*testLine).From.X, 1)
+	assert.Equal(t, clone["from"].(*testPoint).X, 1)
+	assert.Equal(t, clone["from"] == interface{}(&line.From), false)
+	assert.Equal(t, clone["line"].(*testLine).To.X, 1)
+
+	// The maps and slices which contain themselves are copied once.
+	self := map[string]interface{}{"name": "self"}
+	self["self"] = self
+	list := []interface{}{1, nil}
+	list[1] = list
+	typed := map[string]map[string]interface{}{}
+	typed["a"] = map[string]interface{}{"typed": typed}
+	clone = Collect([]map[string]interface{}{{"self": self, "list": list, "typed": typed}}).Clone().ToMapArray()[0]
+	cs := clone["self"].(map[string]interface{})
+	assert.Equal(t, reflect.ValueOf(cs["self"]).Pointer(), reflect.ValueOf(cs).Pointer())
+	assert.Equal(t, reflect.ValueOf(cs).Pointer() == reflect.ValueOf(self).Pointer(), false)
+	cl := clone["list"].([]interface{})
+	assert.Equal(t, reflect.ValueOf(cl[1]).Pointer(), reflect.ValueOf(cl).Pointer())
+	ct := clone["typed"].(map[string]map[string]interface{})
+	assert.Equal(t, reflect.ValueOf(ct["a"]["typed"]).Pointer(), reflect.ValueOf(ct).Pointer())
+}
+
+// gobCopyMap is how rows were copied before deepCopy, it is kept to compare the two.
+func gobCopyMap(m map[string]interface{}) map[string]interface{} {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return nil
+	}
+	var d map[string]interface{}
+	if err := gob.NewDecoder(&buf).Decode(&d); err != nil {
+		return nil
+	}
+	return d
+}
+
+var benchmarkRow = map[string]interface{}{
+	"id": 1, "name": "mike", "price": 9.5, "tags": []interface{}{"a", "b"},
+	"address": map[string]interface{}{"city": "Paris", "zip": "75001"},
+}
+
+func BenchmarkCopyMap_Gob(b *testing.B) {
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	for i := 0; i < b.N; i++ {
+		gobCopyMap(benchmarkRow)
+	}
+}
+
+func BenchmarkCopyMap_DeepCopy(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		copyMap(benchmarkRow)
+	}
+}
+
+func BenchmarkMapArrayCollection_Where(b *testing.B) {
+	var rows = make([]map[string]interface{}, 1000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "even": i%2 == 0, "address": map[string]interface{}{"city": "Paris"}}
+	}
+	c := Collect(rows)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Where("even", true)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	users := Collect([]map[string]interface{}{
+		{"id": 1, "name": "mike", "address": map[string]interface{}{"city": "Paris"}},
+		{"id": 2, "name": "john", "address": map[string]interface{}{"city": "London"}},
+		{"id": 3, "name": "anna", "address": map[string]interface{}{"city": "Paris"}},
+	})
+	orders := Collect([]map[string]interface{}{
+		{"id": 10, "user_id": 1, "total": 30.5, "status": "paid"},
+		{"id": 11, "user_id": 1, "total": 20, "status": "paid"},
+		{"id": 12, "user_id": 2, "total": 15, "status": "open"},
+		{"id": 13, "user_id": 2, "total": 40, "status": "paid"},
+		{"id": 14, "user_id": 9, "total": 5, "status": "paid"},
+	})
+	tables := map[string]Collection{"users": users, "orders": orders}
+
+	c := Query(`SELECT u.name, COUNT(*) AS orders, SUM(o.total) AS spent
+		FROM users u JOIN orders o ON o.user_id = u.id
+		WHERE o.status = 'paid'
+		GROUP BY u.name HAVING COUNT(*) >= 1
+		ORDER BY spent DESC`, tables)
+	assert.Equal(t, c.Err(), nil)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{
+		{"name": "mike", "orders": 2, "spent": decimal.NewFromFloat(50.5)},
+		{"name": "john", "orders": 1, "spent": decimal.New(40, 0)},
+	})
+
+	c = Query(`select name, upper(address.city) city, id * 2 + 1 as n,
+			case when id > 1 then 'many' else 'one' end as kind
+		from users where name like '%n%' or id in (1) order by 1 limit 2 offset 1`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{
+		{"name": "john", "city": "LONDON", "n": decimal.New(5, 0), "kind": "many"},
+		{"name": "mike", "city": "PARIS", "n": decimal.New(3, 0), "kind": "one"},
+	})
+
+	// Outer joins keep the rows without a match, the columns of the missing side are null.
+	c = Query(`SELECT u.name, o.id AS order_id FROM users u LEFT JOIN orders o ON u.id = o.user_id
+		WHERE o.id IS NULL OR o.id >= 13 ORDER BY u.id`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{
+		{"name": "john", "order_id": 13},
+		{"name": "anna", "order_id": nil},
+	})
+	c = Query(`SELECT o.id FROM users u RIGHT JOIN orders o ON u.id = o.user_id WHERE u.id IS NULL`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{{"id": 14}})
+	c = Query(`SELECT COUNT(*) n FROM users u FULL JOIN orders o ON u.id = o.user_id`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{{"n": 6}})
+
+	c = Query(`SELECT * FROM users u JOIN orders o ON o.user_id = u.id AND o.total > 35`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{{
+		"id": 2, "name": "john", "address": map[string]interface{}{"city": "London"},
+		"o.id": 13, "user_id": 2, "total": 40, "status": "paid",
+	}})
+
+	c = Query(`SELECT address.city, COUNT(DISTINCT name) AS names, AVG(id) = 2 avg_two FROM users GROUP BY address.city
+		ORDER BY names DESC, address.city`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{
+		{"address.city": "Paris", "names": 2, "avg_two": true},
+		{"address.city": "London", "names": 1, "avg_two": true},
+	})
+
+	c = Query(`SELECT DISTINCT status FROM orders ORDER BY status`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{{"status": "open"}, {"status": "paid"}})
+	c = Query(`SELECT SUM(total) s, COUNT(*) n FROM orders WHERE total > 100`, tables)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{{"s": nil, "n": 0}})
+	c = Query(`SELECT 1 / 0 AS x, NULL = NULL AS y, 'a' || 'b' AS z, coalesce(NULL, 2) w`, nil)
+	assert.Equal(t, c.ToMapArray(), []map[string]interface{}{{"x": nil, "y": nil, "z": "ab", "w": decimal.New(2, 0)}})
+
+	for _, sql := range []string{
+		"SELECT FROM users",
+		"SELECT name FROM missing",
+		"SELECT name FROM users WHERE COUNT(*) > 1",
+		"SELECT nope(name) FROM users",
+		"SELECT id FROM users u JOIN orders o ON u.id = o.user_id",
+	} {
+		assert.Equal(t, errors.Is(Query(sql, tables).Err(), ErrInvalidArgument), true, sql)
+	}
+}
+
+func TestMapCollection_JSONPath(t *testing.T) {
+	c := Collect(`{"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+			{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+		],
+		"bicycle": {"color": "red", "price": 399}
+	}}`)
+
+	assert.Equal(t, c.JSONPath(`$.store.book[?(@.price < 10)].title`).ToStringArray(), []string{"Sayings of the Century", "Moby Dick"})
+	assert.Equal(t, c.JSONPath(`$..author`).ToStringArray(), []string{"Nigel Rees", "Evelyn Waugh", "Herman Melville", "J. R. R. Tolkien"})
+	assert.Equal(t, c.JSONPath(`$.store..price`).ToNumberArray(), []decimal.Decimal{
+		decimal.New(399, 0), decimal.NewFromFloat(8.95), decimal.NewFromFloat(12.99), decimal.NewFromFloat(8.99), decimal.NewFromFloat(22.99)})
+	assert.Equal(t, c.JSONPath(`$..book[-1:].title`).ToStringArray(), []string{"The Lord of the Rings"})
+	assert.Equal(t, c.JSONPath(`$..book[::-2]['title', "author"]`).ToStringArray(),
+		[]string{"The Lord of the Rings", "J. R. R. Tolkien", "Sword of Honour", "Evelyn Waugh"})
+	assert.Equal(t, c.JSONPath(`$..book[0, 2].category`).ToStringArray(), []string{"reference", "fiction"})
+	assert.Equal(t, c.JSONPath(`$..book[?@.isbn && !(@.price > 20)].title`).ToStringArray(), []string{"Moby Dick"})
+	assert.Equal(t, c.JSONPath(`$..book[?match(@.author, 'J.*') || search(@.title, "^S.+y")].price`).ToNumberArray(),
+		[]decimal.Decimal{decimal.NewFromFloat(8.95), decimal.NewFromFloat(22.99)})
+	assert.Equal(t, c.JSONPath(`$.store.book[?length(@.title) == 9 && count(@.*) == 5].author`).ToStringArray(), []string{"Herman Melville"})
+	patterns := Collect([]map[string]interface{}{
+		{"name": "alice", "pattern": "a.*"}, {"name": "bob", "pattern": "a.*"}, {"name": "bob", "pattern": "b.b"}, {"name": "carl", "pattern": "("},
+	})
+	assert.Equal(t, patterns.JSONPath(`$[?match(@.name, @.pattern)].pattern`).ToStringArray(), []string{"a.*", "b.b"})
+	assert.Equal(t, c.JSONPath(`$.store.book[?@.price == $.store.book[0].price].title`).ToStringArray(), []string{"Sayings of the Century"})
+
+	// A singular path returns an object as a MapCollection and collects an array.
+	bicycle := c.JSONPath(`$.store.bicycle`)
+	assert.Equal(t, bicycle.Get("color"), "red")
+	assert.Equal(t, c.JSONPath(`$["store"]['book']`).Length(), 4)
+	assert.Equal(t, c.JSONPath(`$.store.book[9]`).IsEmpty(), true)
+	assert.Equal(t, errors.Is(c.JSONPath(`$.store.*`).Err(), ErrWrongType), true)
+	values, err := c.JSONPathValues(`$.store.*`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(values), 2)
+
+	rows := Collect([]map[string]interface{}{{"name": "mike", "tags": []interface{}{"a", "b"}}, {"name": "john", "tags": []interface{}{}}})
+	assert.Equal(t, rows.JSONPath(`$[?count(@.tags[*]) > 0].name`).ToStringArray(), []string{"mike"})
+	assert.Equal(t, rows.JSONPath(`$[*].tags`).ToMultiDimensionalArray(), [][]interface{}{{"a", "b"}, {}})
+
+	for _, expr := range []string{`store`, `$.`, `$[01]`, `$[?@.a == 1 == 2]`, `$[?length(@.*) > 1]`, `$[?1]`, `$[?foo(@)]`, `$['a`, `$.a `} {
+		assert.Equal(t, errors.Is(c.JSONPath(expr).Err(), ErrInvalidArgument), true, expr)
+	}
+}
+
+func TestMapCollection_Patch(t *testing.T) {
+	var a, b map[string]interface{}
+	_ = json.Unmarshal([]byte(`{"name":"api","replicas":2,"a/b":1,"env":{"debug":true,"level":"info"},
+		"ports":[80,443,8080,9000],"servers":[{"host":"a","port":1},{"host":"b","port":2},{"host":"c","port":3}]}`), &a)
+	_ = json.Unmarshal([]byte(`{"name":"api","replicas":3,"a/b":1,"env":{"level":"debug","trace":null},
+		"ports":[8080,80,443],"servers":[{"host":"a","port":1},{"host":"b","port":4},{"host":"c","port":3},{"host":"d"}],"tags":["x"]}`), &b)
+	c := Collect(a)
+
+	p := c.DiffPatch(b)
+	assert.Equal(t, p, Patch{
+		{Op: "replace", Path: "/env/level", Value: "debug"},
+		{Op: "remove", Path: "/env/debug"},
+		{Op: "add", Path: "/env/trace", Value: nil},
+		{Op: "remove", Path: "/ports/3"},
+		{Op: "move", From: "/ports/2", Path: "/ports/0"},
+		{Op: "replace", Path: "/replicas", Value: float64(3)},
+		{Op: "replace", Path: "/servers/1/port", Value: float64(4)},
+		{Op: "add", Path: "/servers/3", Value: map[string]interface{}{"host": "d"}},
+		{Op: "add", Path: "/tags", Value: []interface{}{"x"}},
+	})
+	assert.Equal(t, c.ApplyPatch(p).ToMap(), b)
+	assert.Equal(t, c.ToMap(), a, "the collection is not changed")
+	assert.Equal(t, len(c.DiffPatch(c)), 0)
+
+	data, err := json.Marshal(p[2:5])
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(data), `[{"op":"add","path":"/env/trace","value":null},{"op":"remove","path":"/ports/3"},{"from":"/ports/2","op":"move","path":"/ports/0"}]`)
+	var q Patch
+	data, _ = json.Marshal(p)
+	assert.Equal(t, json.Unmarshal(data, &q), nil)
+	assert.Equal(t, c.ApplyPatch(q).ToMap(), b)
+	assert.Equal(t, json.Unmarshal([]byte(`[{"op":"add","path":"/x"}]`), &q) != nil, true)
+
+	// Random orders of the same items are turned into each other.
+	for _, pair := range [][2]string{{`[1,2,3,4,5]`, `[5,4,3,2,1]`}, {`[1,2,3]`, `[]`}, {`[]`, `[3,1]`}, {`[1,[2,3],4]`, `[4,[3,2],1,1]`}} {
+		var x, y interface{}
+		_ = json.Unmarshal([]byte(pair[0]), &x)
+		_ = json.Unmarshal([]byte(pair[1]), &y)
+		m := Collect(map[string]interface{}{"v": x})
+		assert.Equal(t, m.ApplyPatch(m.DiffPatch(map[string]interface{}{"v": y})).ToMap(), map[string]interface{}{"v": y}, pair[0]+" "+pair[1])
+	}
+
+	_ = json.Unmarshal([]byte(`[{"op":"test","path":"/env/level","value":"info"},{"op":"copy","from":"/servers/0","path":"/servers/-"},
+		{"op":"move","from":"/a~1b","path":"/env/ab"},{"op":"remove","path":"/ports/0"}]`), &q)
+	d := c.ApplyPatch(q)
+	assert.Equal(t, d.Err(), nil)
+	assert.Equal(t, d.ToMap()["env"], map[string]interface{}{"debug": true, "level": "info", "ab": float64(1)})
+	assert.Equal(t, len(d.ToMap()["servers"].([]interface{})), 4)
+	assert.Equal(t, d.ToMap()["ports"], []interface{}{float64(443), float64(8080), float64(9000)})
+
+	for _, o := range []PatchOperation{
+		{Op: "test", Path: "/name", Value: "web"},
+		{Op: "remove", Path: "/missing"},
+		{Op: "replace", Path: "/ports/4", Value: 1},
+		{Op: "add", Path: "/missing/x", Value: 1},
+	} {
+		assert.Equal(t, errors.Is(c.ApplyPatch(Patch{{Op: "add", Path: "/x", Value: 1}, o}).Err(), ErrWrongValue), true, o.Op+" "+o.Path)
+	}
+	for _, o := range []PatchOperation{
+		{Op: "add", Path: "x", Value: 1},
+		{Op: "add", Path: "/ports/01", Value: 1},
+		{Op: "remove", Path: "/ports/-0"},
+		{Op: "remove", Path: "/ports/+1"},
+		{Op: "remove", Path: "/ports/"},
+		{Op: "move", From: "/env", Path: "/env/x"},
+		{Op: "swap", Path: "/name"},
+	} {
+		assert.Equal(t, errors.Is(c.ApplyPatch(Patch{o}).Err(), ErrInvalidArgument), true, o.Op+" "+o.Path)
+	}
+
+	m := c.MergePatch(map[string]interface{}{"env": map[string]interface{}{"debug": nil, "level": "warn"}, "ports": []interface{}{1}, "name": nil})
+	assert.Equal(t, m.ToMap()["env"], map[string]interface{}{"level": "warn"})
+	assert.Equal(t, m.ToMap()["ports"], []interface{}{1})
+	assert.Equal(t, m.Has("name"), false)
+	assert.Equal(t, c.Has("name"), true)
+	assert.Equal(t, errors.Is(c.MergePatch([]interface{}{1}).Err(), ErrWrongType), true)
+	assert.Equal(t, errors.Is(Collect([]int{1}).ApplyPatch(nil).Err(), ErrNotImplemented), true)
+}
+
+func TestMapArrayCollection_DiffBy(t *testing.T) {
+	before := Collect([]map[string]interface{}{
+		{"id": 1, "region": "eu", "name": "a", "price": 10},
+		{"id": 2, "region": "eu", "name": "b", "price": 20},
+		{"id": 2, "region": "us", "name": "b", "price": 20, "tags": []interface{}{"x"}},
+		{"id": 3, "region": "eu", "name": "c", "price": 30},
+	})
+	after := []map[string]interface{}{
+		{"id": float64(1), "region": "eu", "name": "a", "price": float64(10)},
+		{"id": 4, "region": "eu", "name": "d"},
+		{"id": 2, "region": "us", "name": "b", "price": 25, "tags": []interface{}{"x", "y"}},
+		{"id": 2, "region": "eu", "name": "B", "price": 20, "stock": 5},
+	}
+
+	d := before.DiffBy(after, "id", "region")
+	assert.Equal(t, d.Err(), nil)
+	assert.Equal(t, d.Added.ToMapArray(), []map[string]interface{}{{"id": 4, "region": "eu", "name": "d"}})
+	assert.Equal(t, d.Removed.ToMapArray(), []map[string]interface{}{{"id": 3, "region": "eu", "name": "c", "price": 30}})
+	assert.Equal(t, d.Changed.ToMapArray(), []map[string]interface{}{
+		{"id": 2, "region": "eu", "changes": map[string]interface{}{
+			"name":  map[string]interface{}{"before": "b", "after": "B"},
+			"stock": map[string]interface{}{"before": nil, "after": 5},
+		}},
+		{"id": 2, "region": "us", "changes": map[string]interface{}{
+			"price": map[string]interface{}{"before": 20, "after": 25},
+			"tags":  map[string]interface{}{"before": []interface{}{"x"}, "after": []interface{}{"x", "y"}},
+		}},
+	})
+	assert.Equal(t, d.Changed.Where("changes.name.after", "B").Count(), 1)
+
+	d = before.DiffBy(before)
+	assert.Equal(t, errors.Is(d.Err(), ErrInvalidArgument), true)
+	d = before.DiffBy(after, "id")
+	assert.Equal(t, errors.Is(d.Changed.Err(), ErrWrongValue), true)
+	d = before.DiffBy(after, "sku")
+	assert.Equal(t, errors.Is(d.Err(), ErrWrongValue), true)
+	assert.Equal(t, errors.Is(before.DiffBy(1, "id").Err(), ErrWrongType), true)
+	assert.Equal(t, errors.Is(Collect([]int{1}).DiffBy(after, "id").Err(), ErrNotImplemented), true)
+}
+
+func TestMapCollection_DeepMerge(t *testing.T) {
+	c := Collect(map[string]interface{}{
+		"name": "api",
+		"env":  map[string]interface{}{"level": "info", "debug": false},
+		"tags": []interface{}{"a", "b"},
+	})
+	other := map[string]interface{}{
+		"env":  map[string]interface{}{"level": "debug", "trace": true},
+		"tags": []interface{}{"b", "c"},
+	}
+
+	assert.Equal(t, c.DeepMerge(other).ToMap(), map[string]interface{}{
+		"name": "api",
+		"env":  map[string]interface{}{"level": "debug", "debug": false, "trace": true},
+		"tags": []interface{}{"b", "c"},
+	})
+	assert.Equal(t, c.DeepMerge(other, MergeAppend).ToMap()["tags"], []interface{}{"a", "b", "b", "c"})
+	assert.Equal(t, c.DeepMerge(Collect(other), MergeUnion).ToMap()["tags"], []interface{}{"a", "b", "c"})
+	assert.Equal(t, c.DeepMerge(other, MergeKeepLeft).ToMap()["env"], map[string]interface{}{"level": "info", "debug": false, "trace": true})
+	assert.Equal(t, errors.Is(c.DeepMerge(other, MergeError).Err(), ErrWrongValue), true)
+	assert.Equal(t, c.DeepMerge(map[string]interface{}{"env": map[string]interface{}{"level": "info"}}, MergeError).Err(), nil)
+	assert.Equal(t, c.ToMap()["env"], map[string]interface{}{"level": "info", "debug": false})
+	assert.Equal(t, errors.Is(c.DeepMerge([]int{1}).Err(), ErrWrongType), true)
+	assert.Equal(t, errors.Is(c.Merge(1).Err(), ErrWrongType), true)
+	assert.Equal(t, c.Merge(Collect(map[string]interface{}{"name": "web"})).ToMap()["name"], "web")
+
+	rows := Collect([]map[string]interface{}{
+		{"id": 1, "meta": map[string]interface{}{"a": 1}},
+		{"id": 2},
+	})
+	assert.Equal(t, rows.DeepMerge(map[string]interface{}{"meta": map[string]interface{}{"b": 2}}).ToMapArray(), []map[string]interface{}{
+		{"id": 1, "meta": map[string]interface{}{"a": 1, "b": 2}},
+		{"id": 2, "meta": map[string]interface{}{"b": 2}},
+	})
+	assert.Equal(t, rows.Merge([]map[string]interface{}{{"id": 3}}).Count(), 3)
+	assert.Equal(t, rows.Merge(rows).Count(), 4)
+}
+
+func TestMapArrayCollection_UpsertBy(t *testing.T) {
+	c := Collect([]map[string]interface{}{
+		{"id": 1, "region": "eu", "stock": 5, "tags": []interface{}{"a"}},
+		{"id": 2, "region": "eu", "stock": 3},
+	})
+	rows := []map[string]interface{}{
+		{"id": float64(1), "region": "eu", "stock": 7, "tags": []interface{}{"b"}},
+		{"id": 1, "region": "us", "stock": 1},
+		{"id": 1, "region": "us", "price": 9},
+		{"stock": 0},
+	}
+
+	assert.Equal(t, c.UpsertBy([]string{"id", "region"}, rows).ToMapArray(), []map[string]interface{}{
+		{"id": float64(1), "region": "eu", "stock": 7, "tags": []interface{}{"b"}},
+		{"id": 2, "region": "eu", "stock": 3},
+		{"id": 1, "region": "us", "stock": 1, "price": 9},
+		{"stock": 0},
+	})
+	assert.Equal(t, c.UpsertBy([]string{"id", "region"}, Collect(rows[:1]), MergeAppend).ToMapArray()[0]["tags"], []interface{}{"a", "b"})
+	assert.Equal(t, errors.Is(c.UpsertBy([]string{"id", "region"}, rows, MergeError).Err(), ErrWrongValue), true)
+	assert.Equal(t, c.Count(), 2)
+	assert.Equal(t, errors.Is(c.UpsertBy(nil, rows).Err(), ErrInvalidArgument), true)
+	assert.Equal(t, errors.Is(c.UpsertBy([]string{"id"}, 1).Err(), ErrWrongType), true)
+	assert.Equal(t, errors.Is(Collect([]int{1}).UpsertBy([]string{"id"}, rows).Err(), ErrNotImplemented), true)
+}
+
+// countingReader counts the bytes which have been read from it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	const data = `{"id":1,"level":"info","ms":12}
+{"id":2,"level":"error","ms":30}
+
+{"id":3,"level":"info","ms":null}
+{"id":4,"level":"error","ms":8}
+`
+	s := StreamNDJSON(strings.NewReader(data))
+	assert.Equal(t, s.Where("level", "error").Sum("ms").String(), "38")
+	assert.Equal(t, StreamNDJSON(strings.NewReader(data)).Avg("ms").String(), "16.6666666666666667")
+	assert.Equal(t, StreamNDJSON(strings.NewReader(data)).Max("ms").String(), "30")
+	assert.Equal(t, StreamNDJSON(strings.NewReader(data)).Min("id").String(), "1")
+	assert.Equal(t, StreamNDJSON(strings.NewReader(data)).Filter(func(_, v interface{}) bool {
+		return v.(map[string]interface{})["ms"] == nil
+	}).Count(), 1)
+
+	var ids []interface{}
+	err := StreamNDJSON(strings.NewReader(data)).Each(func(i, v interface{}) bool {
+		ids = append(ids, v.(map[string]interface{})["id"])
+		return i.(int) < 1
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids, []interface{}{decimal.New(1, 0), decimal.New(2, 0)})
+
+	// The numbers are not rounded through float64.
+	var out bytes.Buffer
+	assert.Equal(t, StreamNDJSON(strings.NewReader(`{"id":12345678901234567890,"price":0.10}`+"\n")).WriteNDJSON(&out), nil)
+	assert.Equal(t, out.String(), `{"id":12345678901234567890,"price":0.1}`+"\n")
+
+	// A stream is read once, a second run does not return the rest of it.
+	s = StreamNDJSON(strings.NewReader(data))
+	assert.Equal(t, s.Take(1).Count(), 1)
+	_, err = s.CountE()
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+
+	// The lines after the ones which are needed are not read.
+	r := &countingReader{r: io.MultiReader(strings.NewReader(data), strings.NewReader(strings.Repeat(`{"id":5}`+"\n", 100000)))}
+	assert.Equal(t, len(StreamNDJSON(r).Take(2).ToMapArray()), 2)
+	assert.Equal(t, r.n < 100000, true)
+
+	c := StreamNDJSON(strings.NewReader(data)).Select("id").Collect()
+	assert.Equal(t, c.Count(), 4)
+	var buf bytes.Buffer
+	assert.Equal(t, c.WriteNDJSON(&buf), nil)
+	assert.Equal(t, buf.String(), "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n")
+	buf.Reset()
+	assert.Equal(t, StreamNDJSON(strings.NewReader(data)).Where("level", "info").Select("id", "level").WriteNDJSON(&buf), nil)
+	assert.Equal(t, buf.String(), "{\"id\":1,\"level\":\"info\"}\n{\"id\":3,\"level\":\"info\"}\n")
+
+	err = StreamNDJSON(strings.NewReader("{\"id\":1}\n{\"id\":\n{\"id\":3}\n")).Each(func(_, _ interface{}) bool { return true })
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+	assert.Equal(t, strings.Contains(err.Error(), "line 2"), true)
+	err = StreamNDJSON(strings.NewReader("{\"id\":1}\n{\"id\":2} {\"id\":3}\n")).Each(func(_, _ interface{}) bool { return true })
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+	assert.Equal(t, strings.Contains(err.Error(), "line 2"), true)
+	_, err = StreamNDJSON(strings.NewReader("{\"id\":1}\n\n[1]\n")).CountE()
+	assert.Equal(t, errors.Is(err, ErrWrongType), true)
+	assert.Equal(t, strings.Contains(err.Error(), "line 3"), true)
+	_, err = StreamNDJSON(strings.NewReader("")).AvgE("ms")
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+	assert.Equal(t, Collect([]map[string]interface{}{{"f": func() {}}}).WriteNDJSON(&buf) != nil, true)
+	assert.Equal(t, errors.Is(Collect([]int{1}).WriteNDJSON(&buf), ErrNotImplemented), true)
+}
+
+func TestCollectReader(t *testing.T) {
+	c := CollectReader(strings.NewReader(`[{"id":9007199254740993,"price":0.1,"tags":[1.10]},{"id":2,"price":0.2}]`))
+	assert.Equal(t, c.Err(), nil)
+	rows := c.ToMapArray()
+	assert.Equal(t, rows[0]["id"].(decimal.Decimal).String(), "9007199254740993")
+	assert.Equal(t, rows[0]["tags"].([]interface{})[0].(decimal.Decimal).String(), "1.1")
+	assert.Equal(t, c.Sum("price").String(), "0.3")
+	assert.Equal(t, c.Where("id", 2).Count(), 1)
+
+	assert.Equal(t, CollectReader(strings.NewReader(`["a","b"]`)).ToStringArray(), []string{"a", "b"})
+	assert.Equal(t, CollectReader(strings.NewReader(` [1, 2.5] `)).Sum().String(), "3.5")
+	assert.Equal(t, CollectReader(strings.NewReader(`[]`)).Count(), 0)
+	m := CollectReader(strings.NewReader(`{"name":"api","replicas":3,"env":{"ratio":0.25}}`))
+	assert.Equal(t, m.Get("env.ratio").(decimal.Decimal).String(), "0.25")
+	assert.Equal(t, m.Get("name"), "api")
+
+	for _, src := range []string{``, `  `, `1`, `"a"`, `[1,`, `[1,]`, `{"a":}`, `[1] [2]`} {
+		assert.Equal(t, errors.Is(CollectReader(strings.NewReader(src)).Err(), ErrWrongValue), true, src)
+	}
+	assert.Equal(t, CollectReader(strings.NewReader(`[1,"a"]`)).Err().Error(),
+		"wrong type: the values have mixed types, use StreamJSON and AllE")
+	items, err := StreamJSON(strings.NewReader(`[1,"a"]`)).AllE()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, items, []interface{}{decimal.New(1, 0), "a"})
+	assert.Equal(t, errors.Is(Collect("").Err(), ErrWrongValue), true)
+	assert.Equal(t, errors.Is(Collect("  ").Err(), ErrWrongValue), true)
+
+	// The items after the ones which are needed are not decoded.
+	r := &countingReader{r: io.MultiReader(strings.NewReader(`[{"id":1},{"id":2}`), strings.NewReader(strings.Repeat(`,{"id":3}`, 100000)+`]`))}
+	s := StreamJSON(r)
+	assert.Equal(t, s.First().(map[string]interface{})["id"].(decimal.Decimal).String(), "1")
+	assert.Equal(t, r.n < 100000, true)
+	_, err = s.CountE()
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+	assert.Equal(t, StreamJSON(strings.NewReader(`[{"v":1},{"v":2},{"v":3}]`)).Where("v", ">", 1).Sum("v").String(), "5")
+	assert.Equal(t, StreamJSON(strings.NewReader(`["a","b"]`)).Collect().ToStringArray(), []string{"a", "b"})
+	_, err = StreamJSON(strings.NewReader(`{"a":1}`)).CountE()
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+	_, err = StreamJSON(strings.NewReader(`[1,2,}`)).CountE()
+	assert.Equal(t, errors.Is(err, ErrWrongValue), true)
+}
@@ -0,0 +1,265 @@
+package collection
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// CSVType is the type the fields of a csv column are converted into.
+type CSVType int
+
+const (
+	// CSVString keeps the fields as string.
+	CSVString CSVType = iota
+	// CSVDecimal converts the fields into decimal.Decimal.
+	CSVDecimal
+	// CSVBool converts the fields "true" and "false" into bool.
+	CSVBool
+)
+
+// CSVOptions configures CollectCSV.
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' by default.
+	Comma rune
+
+	// Comment starts a comment line when it is the first character of the line, there are no comments by
+	// default.
+	Comment rune
+
+	// LazyQuotes allows a quote to appear in an unquoted field and a non-doubled quote in a quoted field.
+	LazyQuotes bool
+
+	// TrimLeadingSpace ignores the leading white space of the fields.
+	TrimLeadingSpace bool
+
+	// NoHeader reads every record as data into a MultiDimensionalArrayCollection. Otherwise the first record
+	// holds the column names and the result is a MapArrayCollection.
+	NoHeader bool
+
+	// InferTypes converts the fields of a column into decimal.Decimal when all of them are numbers, or into
+	// bool when all of them are "true" or "false". The empty fields of such a column become nil.
+	InferTypes bool
+
+	// Types sets the type of the given columns, which wins over InferTypes. Without a header the columns are
+	// named by their index, such as "0".
+	Types map[string]CSVType
+}
+
+// CollectCSV reads csv from r into a MapArrayCollection, or into a MultiDimensionalArrayCollection when
+// opts.NoHeader is set. All of the fields are strings unless the types are set or inferred.
+func CollectCSV(r io.Reader, opts ...CSVOptions) Collection {
+	var opt CSVOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	reader := csv.NewReader(r)
+	if opt.Comma != 0 {
+		reader.Comma = opt.Comma
+	}
+	reader.Comment = opt.Comment
+	reader.LazyQuotes = opt.LazyQuotes
+	reader.TrimLeadingSpace = opt.TrimLeadingSpace
+
+	records, err := reader.ReadAll()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return BaseCollection{err: fmt.Errorf("%w: %v", ErrWrongValue, err)}
+	}
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+
+	var header []string
+	if !opt.NoHeader && len(records) > 0 {
+		header, records = records[0], records[1:]
+	}
+	if header == nil && len(records) > 0 {
+		header = make([]string, len(records[0]))
+		for i := range header {
+			header[i] = strconv.Itoa(i)
+		}
+	}
+
+	var convert = make([]func(string) (interface{}, error), len(header))
+	for i, name := range header {
+		t, ok := opt.Types[name]
+		if !ok && opt.InferTypes {
+			t = inferCSVType(records, i)
+		}
+		convert[i] = csvConverter(t)
+	}
+
+	var rows = make([][]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = make([]interface{}, len(record))
+		for j, field := range record {
+			if rows[i][j], err = convert[j](field); err != nil {
+				return BaseCollection{err: fmt.Errorf("%w: record %d column %s: %v", ErrWrongValue, i+1, header[j], err)}
+			}
+		}
+	}
+
+	if opt.NoHeader {
+		return MultiDimensionalArrayCollection{value: rows, BaseCollection: BaseCollection{length: len(rows)}}
+	}
+	var d = make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		d[i] = make(map[string]interface{}, len(header))
+		for j, v := range row {
+			d[i][header[j]] = v
+		}
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// inferCSVType returns the type all of the non-empty fields of the column have.
+func inferCSVType(records [][]string, column int) CSVType {
+	var isDecimal, isBool, empty = true, true, true
+	for _, record := range records {
+		field := record[column]
+		if field == "" {
+			continue
+		}
+		empty = false
+		if isDecimal {
+			if _, err := decimal.NewFromString(field); err != nil {
+				isDecimal = false
+			}
+		}
+		if isBool {
+			isBool = field == "true" || field == "false"
+		}
+		if !isDecimal && !isBool {
+			return CSVString
+		}
+	}
+	switch {
+	case empty:
+		return CSVString
+	case isDecimal:
+		return CSVDecimal
+	case isBool:
+		return CSVBool
+	default:
+		return CSVString
+	}
+}
+
+func csvConverter(t CSVType) func(string) (interface{}, error) {
+	switch t {
+	case CSVDecimal:
+		return func(field string) (interface{}, error) {
+			if field == "" {
+				return nil, nil
+			}
+			return decimal.NewFromString(field)
+		}
+	case CSVBool:
+		return func(field string) (interface{}, error) {
+			if field == "" {
+				return nil, nil
+			}
+			return strconv.ParseBool(field)
+		}
+	default:
+		return func(field string) (interface{}, error) {
+			return field, nil
+		}
+	}
+}
+
+// ToCSV writes the collection as csv to w. The first record holds the given columns, which are all of the
+// keys of the items in sorted order by default. A column may be a path.
+func (c MapArrayCollection) ToCSV(w io.Writer, columns ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+	items := c.items()
+	if len(columns) == 0 {
+		var keys = make(map[string]struct{})
+		for _, item := range items {
+			for k := range item {
+				keys[k] = struct{}{}
+			}
+		}
+		columns = make([]string, 0, len(keys))
+		for k := range keys {
+			columns = append(columns, k)
+		}
+		sort.Strings(columns)
+	}
+
+	var (
+		writer = csv.NewWriter(w)
+		record = make([]string, len(columns))
+	)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, item := range items {
+		for i, col := range columns {
+			record[i] = csvField(lookupPath(item, col))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ToCSV writes the collection as csv to w, the given columns are written as the first record.
+func (c MultiDimensionalArrayCollection) ToCSV(w io.Writer, columns ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+	writer := csv.NewWriter(w)
+	if len(columns) > 0 {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range c.value {
+		var record = make([]string, len(row))
+		for i, v := range row {
+			record[i] = csvField(v)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvField formats a value as a csv field. Nil is the empty field, and maps and slices are written as json.
+func csvField(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case decimal.Decimal:
+		return t.String()
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]interface{}, []interface{}, []map[string]interface{}:
+		s, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%v", t)
+		}
+		return string(s)
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
@@ -0,0 +1,142 @@
+package collection
+
+import "fmt"
+
+// DiffChanges is the column of a row of RowDiff.Changed which holds the changed columns.
+const DiffChanges = "changes"
+
+// RowDiff is the result of DiffBy. Its collections are MapArrayCollections, or carry the error of DiffBy.
+type RowDiff struct {
+	// Added are the rows of other whose key the collection does not have, in the order of other.
+	Added Collection
+	// Removed are the rows of the collection whose key other does not have, in the order of the collection.
+	Removed Collection
+	// Changed has a row for every key whose rows differ, in the order of the collection. The row holds the
+	// key columns, and under DiffChanges a map from every changed column to a map with its "before" value in
+	// the collection and its "after" value in other.
+	Changed Collection
+}
+
+// Err returns the error of DiffBy.
+func (d RowDiff) Err() error {
+	return d.Added.Err()
+}
+
+func rowDiffError(err error) RowDiff {
+	return RowDiff{Added: BaseCollection{err: err}, Removed: BaseCollection{err: err}, Changed: BaseCollection{err: err}}
+}
+
+// DiffBy compares the rows of the collection with the rows of other, a MapArrayCollection or a
+// []map[string]interface{}, matching them by the values of the key columns. The key must be unique on both
+// sides. The other columns are compared by value, so numbers of different types are equal, and a column
+// which a row does not have is compared as nil.
+func (c MapArrayCollection) DiffBy(other interface{}, keys ...string) RowDiff {
+	if c.err != nil {
+		return rowDiffError(c.err)
+	}
+	var right []map[string]interface{}
+	switch o := other.(type) {
+	case MapArrayCollection:
+		if o.err != nil {
+			return rowDiffError(o.err)
+		}
+		right = o.items()
+	case []map[string]interface{}:
+		right = o
+	default:
+		return rowDiffError(wrongType("MapArrayCollection or []map[string]interface{}", other))
+	}
+	if len(keys) == 0 {
+		return rowDiffError(fmt.Errorf("%w: no key column", ErrInvalidArgument))
+	}
+
+	left := c.items()
+	leftRows, err := diffKeyRows(left, keys)
+	if err != nil {
+		return rowDiffError(err)
+	}
+	rightRows, err := diffKeyRows(right, keys)
+	if err != nil {
+		return rowDiffError(err)
+	}
+
+	var (
+		added   = make([]map[string]interface{}, 0)
+		removed = make([]map[string]interface{}, 0)
+		changed = make([]map[string]interface{}, 0)
+		isKey   = make(map[string]bool, len(keys))
+	)
+	for _, k := range keys {
+		isKey[k] = true
+	}
+	for i, row := range left {
+		j, ok := rightRows.index[leftRows.hashes[i]]
+		if !ok {
+			removed = append(removed, row)
+			continue
+		}
+		if changes := diffColumns(row, right[j], isKey); len(changes) > 0 {
+			var d = make(map[string]interface{}, len(keys)+1)
+			for _, k := range keys {
+				d[k] = lookupPath(row, k)
+			}
+			d[DiffChanges] = changes
+			changed = append(changed, d)
+		}
+	}
+	for j, row := range right {
+		if _, ok := leftRows.index[rightRows.hashes[j]]; !ok {
+			added = append(added, row)
+		}
+	}
+	return RowDiff{
+		Added:   newMapArrayCollection(mapList(added)),
+		Removed: newMapArrayCollection(mapList(removed)),
+		Changed: newMapArrayCollection(mapList(changed)),
+	}
+}
+
+// keyRows are the hashes of the keys of rows in their order, and the position of the row of every hash.
+type keyRows struct {
+	hashes []string
+	index  map[string]int
+}
+
+func diffKeyRows(rows []map[string]interface{}, keys []string) (keyRows, error) {
+	var d = keyRows{hashes: make([]string, len(rows)), index: make(map[string]int, len(rows))}
+	for i, row := range rows {
+		var key = make([]interface{}, len(keys))
+		for j, k := range keys {
+			if key[j] = lookupPath(row, k); key[j] == nil {
+				return d, fmt.Errorf("%w: row %d has no key column %q", ErrWrongValue, i, k)
+			}
+		}
+		h, _ := valueHash(key)
+		if _, ok := d.index[h]; ok {
+			return d, fmt.Errorf("%w: duplicate key %v in row %d", ErrWrongValue, key, i)
+		}
+		d.hashes[i], d.index[h] = h, i
+	}
+	return d, nil
+}
+
+// diffColumns returns the columns of the rows but the keys whose values differ, with their values before
+// and after.
+func diffColumns(before, after map[string]interface{}, isKey map[string]bool) map[string]interface{} {
+	var d = make(map[string]interface{})
+	diff := func(k string) {
+		if isKey[k] || equalValues(before[k], after[k]) || jsonEqual(before[k], after[k]) {
+			return
+		}
+		d[k] = map[string]interface{}{"before": before[k], "after": after[k]}
+	}
+	for k := range before {
+		diff(k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			diff(k)
+		}
+	}
+	return d
+}
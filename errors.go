@@ -0,0 +1,35 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors returned by the collections. They may be wrapped with more details, so use errors.Is to check
+// for them.
+var (
+	// ErrNotImplemented is returned when the method is not supported by the type of the collection.
+	ErrNotImplemented = errors.New("not implemented")
+
+	// ErrWrongType is returned when a value has an unexpected type.
+	ErrWrongType = errors.New("wrong type")
+
+	// ErrWrongValue is returned when a value can not be collected, such as an empty slice.
+	ErrWrongValue = errors.New("wrong value")
+
+	// ErrInvalidArgument is returned when the arguments of a method are invalid or out of range.
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrPanic is returned when a callback which runs on another goroutine panics.
+	ErrPanic = errors.New("panic")
+)
+
+// notImplemented returns ErrNotImplemented with the name of the method.
+func notImplemented(method string) error {
+	return fmt.Errorf("%w: %s", ErrNotImplemented, method)
+}
+
+// wrongType returns ErrWrongType with the expected type of the value.
+func wrongType(expected string, value interface{}) error {
+	return fmt.Errorf("%w: expected %s, got %T", ErrWrongType, expected, value)
+}
@@ -1,6 +1,6 @@
 module github.com/hulklab/collection
 
-go 1.12
+go 1.21
 
 require (
 	github.com/magiconair/properties v1.8.1
@@ -0,0 +1,234 @@
+package collection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hulklab/collection/hamt"
+	"github.com/shopspring/decimal"
+)
+
+// GroupedCollection is the result of GroupBy. It is a MapCollection whose keys are the formatted group keys
+// and whose values are the rows of each group, and it keeps the groups in the order of their first row so
+// Aggregate can turn them into one row per group.
+type GroupedCollection struct {
+	MapCollection
+	keys   []string
+	groups []group
+}
+
+type group struct {
+	key  []interface{}
+	rows []map[string]interface{}
+}
+
+// Aggregator reduces the rows of a group to a single value, which is put into the column Name of the row of
+// the group.
+type Aggregator struct {
+	Name string
+	fn   func(rows []map[string]interface{}) interface{}
+}
+
+// NewAggregator returns an Aggregator which puts the result of fn into the column name.
+func NewAggregator(name string, fn func(rows []map[string]interface{}) interface{}) Aggregator {
+	return Aggregator{Name: name, fn: fn}
+}
+
+// As returns the aggregator with its result put into the column name.
+func (a Aggregator) As(name string) Aggregator {
+	a.Name = name
+	return a
+}
+
+// groupBy groups rows by the values at the given keys or paths. A row without all of the keys is left out.
+func groupBy(rows []map[string]interface{}, keys []string) GroupedCollection {
+	var (
+		index  = make(map[string]int)
+		groups = make([]group, 0)
+		// buckets are the rows by the formatted key, which is the same for keys like 1 and "1" whose groups
+		// differ, so the rows of such groups share a bucket.
+		buckets = make(map[string]interface{})
+	)
+	for _, row := range rows {
+		var (
+			key       = make([]interface{}, len(keys))
+			formatted = make([]string, len(keys))
+			missing   = false
+		)
+		for i, k := range keys {
+			v, ok := getPath(row, k)
+			if !ok {
+				missing = true
+				break
+			}
+			key[i], formatted[i] = v, fmt.Sprintf("%v", v)
+		}
+		if missing {
+			continue
+		}
+		name := strings.Join(formatted, ",")
+		hash, _ := valueHash(key)
+		i, ok := index[hash]
+		if !ok {
+			i = len(groups)
+			index[hash] = i
+			groups = append(groups, group{key: key})
+		}
+		groups[i].rows = append(groups[i].rows, row)
+		bucket, _ := buckets[name].([]map[string]interface{})
+		buckets[name] = append(bucket, row)
+	}
+	return GroupedCollection{
+		MapCollection: newMapCollection(hamt.FromMap(buckets)),
+		keys:          keys,
+		groups:        groups,
+	}
+}
+
+// Aggregate returns a MapArrayCollection with one row per group. A row holds the group keys and the result of
+// every aggregator. Without aggregators the row only holds the group keys.
+func (c GroupedCollection) Aggregate(aggregators ...Aggregator) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var d = make([]map[string]interface{}, len(c.groups))
+	for i, g := range c.groups {
+		var row = make(map[string]interface{}, len(c.keys)+len(aggregators))
+		for j, k := range c.keys {
+			row[k] = g.key[j]
+		}
+		for _, a := range aggregators {
+			row[a.Name] = a.fn(g.rows)
+		}
+		d[i] = row
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// Sum returns an Aggregator of the sum of column, in the column "sum_<column>".
+func Sum(column string) Aggregator {
+	return NewAggregator("sum_"+column, func(rows []map[string]interface{}) interface{} {
+		var sum = decimal.New(0, 0)
+		for _, v := range aggregateValues(rows, column) {
+			sum = sum.Add(nd(v))
+		}
+		return sum
+	})
+}
+
+// Avg returns an Aggregator of the average of column, in the column "avg_<column>". The rows without the
+// column are left out, and the average of no value is nil.
+func Avg(column string) Aggregator {
+	return NewAggregator("avg_"+column, func(rows []map[string]interface{}) interface{} {
+		values := aggregateValues(rows, column)
+		if len(values) == 0 {
+			return nil
+		}
+		var sum = decimal.New(0, 0)
+		for _, v := range values {
+			sum = sum.Add(nd(v))
+		}
+		return sum.Div(decimal.New(int64(len(values)), 0))
+	})
+}
+
+// Min returns an Aggregator of the minimum value of column, in the column "min_<column>".
+func Min(column string) Aggregator {
+	return NewAggregator("min_"+column, func(rows []map[string]interface{}) interface{} {
+		var smallest interface{}
+		for _, v := range aggregateValues(rows, column) {
+			if smallest == nil || compareValues(v, smallest) < 0 {
+				smallest = v
+			}
+		}
+		return smallest
+	})
+}
+
+// Max returns an Aggregator of the maximum value of column, in the column "max_<column>".
+func Max(column string) Aggregator {
+	return NewAggregator("max_"+column, func(rows []map[string]interface{}) interface{} {
+		var biggest interface{}
+		for _, v := range aggregateValues(rows, column) {
+			if biggest == nil || compareValues(v, biggest) > 0 {
+				biggest = v
+			}
+		}
+		return biggest
+	})
+}
+
+// Count returns an Aggregator of the number of rows, in the column "count". With a column, only the rows
+// which have a value in it are counted, in the column "count_<column>".
+func Count(column ...string) Aggregator {
+	if len(column) == 0 {
+		return NewAggregator("count", func(rows []map[string]interface{}) interface{} {
+			return len(rows)
+		})
+	}
+	return NewAggregator("count_"+column[0], func(rows []map[string]interface{}) interface{} {
+		return len(aggregateValues(rows, column[0]))
+	})
+}
+
+// CountDistinct returns an Aggregator of the number of distinct values of column, in the column
+// "count_distinct_<column>".
+func CountDistinct(column string) Aggregator {
+	return NewAggregator("count_distinct_"+column, func(rows []map[string]interface{}) interface{} {
+		var seen = make(map[string]struct{})
+		for _, v := range aggregateValues(rows, column) {
+			h, _ := valueHash(v)
+			seen[h] = struct{}{}
+		}
+		return len(seen)
+	})
+}
+
+// First returns an Aggregator of the value of column in the first row of the group, in the column
+// "first_<column>".
+func First(column string) Aggregator {
+	return NewAggregator("first_"+column, func(rows []map[string]interface{}) interface{} {
+		return lookupPath(rows[0], column)
+	})
+}
+
+// Last returns an Aggregator of the value of column in the last row of the group, in the column
+// "last_<column>".
+func Last(column string) Aggregator {
+	return NewAggregator("last_"+column, func(rows []map[string]interface{}) interface{} {
+		return lookupPath(rows[len(rows)-1], column)
+	})
+}
+
+// Median returns an Aggregator of the median of column, in the column "median_<column>".
+func Median(column string) Aggregator {
+	return NewAggregator("median_"+column, func(rows []map[string]interface{}) interface{} {
+		values := aggregateValues(rows, column)
+		if len(values) == 0 {
+			return nil
+		}
+		var f = make([]decimal.Decimal, len(values))
+		for i, v := range values {
+			f[i] = nd(v)
+		}
+		sort.Slice(f, func(i, j int) bool {
+			return f[i].LessThan(f[j])
+		})
+		if len(f)%2 == 1 {
+			return f[len(f)/2]
+		}
+		return f[len(f)/2-1].Add(f[len(f)/2]).Div(decimal.New(2, 0))
+	})
+}
+
+// aggregateValues returns the values of column in rows, leaving out the nil ones.
+func aggregateValues(rows []map[string]interface{}, column string) []interface{} {
+	var d = make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		if v := lookupPath(row, column); v != nil {
+			d = append(d, v)
+		}
+	}
+	return d
+}
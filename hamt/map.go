@@ -1 +1,402 @@
+// Package hamt implements a persistent hash array mapped trie with string keys.
+//
+// Every update returns a new Map which shares all untouched nodes with the old
+// one, so an Assoc or Dissoc costs O(log32 n) instead of copying the whole map.
+// A Transient may be used for batch edits, it mutates the nodes it owns in place
+// and turns back into a persistent Map with Persistent.
 package hamt
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	bitsPerLevel = 5
+	branchFactor = 1 << bitsPerLevel
+	levelMask    = branchFactor - 1
+	maxShift     = 64
+)
+
+// owner marks the nodes created by a transient, those nodes may be edited in place.
+type owner struct{ _ byte }
+
+type entry struct {
+	hash  uint64
+	key   string
+	value interface{}
+	// node is not nil when the slot holds a sub trie instead of a key / value pair.
+	node *node
+}
+
+type node struct {
+	bitmap uint32
+	// collision nodes live below the last level and hold entries with the same hash.
+	collision bool
+	entries   []entry
+	edit      *owner
+}
+
+// Map is a persistent map from string to interface{}. The zero value is an empty map.
+type Map struct {
+	root  *node
+	count int
+}
+
+// New returns an empty Map.
+func New() *Map {
+	return &Map{}
+}
+
+// FromMap builds a Map which contains all of the key / value pairs of m.
+func FromMap(m map[string]interface{}) *Map {
+	t := New().Transient()
+	for k, v := range m {
+		t.Assoc(k, v)
+	}
+	return t.Persistent()
+}
+
+// Len returns the number of key / value pairs in the map.
+func (m *Map) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.count
+}
+
+// Get returns the value stored under key and whether the key exists.
+func (m *Map) Get(key string) (interface{}, bool) {
+	if m == nil || m.root == nil {
+		return nil, false
+	}
+	return m.root.find(0, hashKey(key), key)
+}
+
+// Has determines if the key exists in the map.
+func (m *Map) Has(key string) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Assoc returns a new map with key set to value.
+func (m *Map) Assoc(key string, value interface{}) *Map {
+	var root *node
+	if m != nil {
+		root = m.root
+	}
+	added := false
+	if root == nil {
+		root = &node{}
+	}
+	newRoot := root.assoc(nil, 0, hashKey(key), key, value, &added)
+	if newRoot == root && m != nil && m.root != nil {
+		return m
+	}
+	count := m.Len()
+	if added {
+		count++
+	}
+	return &Map{root: newRoot, count: count}
+}
+
+// Dissoc returns a new map without key.
+func (m *Map) Dissoc(key string) *Map {
+	if m == nil || m.root == nil {
+		return m
+	}
+	removed := false
+	newRoot := m.root.dissoc(nil, 0, hashKey(key), key, &removed)
+	if !removed {
+		return m
+	}
+	return &Map{root: newRoot, count: m.count - 1}
+}
+
+// Range calls f for every key / value pair in the map until f returns false.
+// The iteration order is unspecified but deterministic.
+func (m *Map) Range(f func(key string, value interface{}) bool) {
+	if m == nil || m.root == nil {
+		return
+	}
+	m.root.each(f)
+}
+
+// Keys returns all of the keys in the map, sorted.
+func (m *Map) Keys() []string {
+	keys := make([]string, 0, m.Len())
+	m.Range(func(key string, _ interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// ToMap converts the map into a plain golang map.
+func (m *Map) ToMap() map[string]interface{} {
+	d := make(map[string]interface{}, m.Len())
+	m.Range(func(key string, value interface{}) bool {
+		d[key] = value
+		return true
+	})
+	return d
+}
+
+// Transient returns a mutable copy of the map for batch edits. The map itself is
+// never changed by the transient.
+func (m *Map) Transient() *Transient {
+	t := &Transient{edit: &owner{}}
+	if m != nil {
+		t.root = m.root
+		t.count = m.count
+	}
+	return t
+}
+
+// Transient is a mutable view of a Map. It must not be used after Persistent has
+// been called, and it is not safe for concurrent use.
+type Transient struct {
+	root  *node
+	count int
+	edit  *owner
+}
+
+// Len returns the number of key / value pairs in the transient.
+func (t *Transient) Len() int {
+	return t.count
+}
+
+// Get returns the value stored under key and whether the key exists.
+func (t *Transient) Get(key string) (interface{}, bool) {
+	t.ensureEditable()
+	if t.root == nil {
+		return nil, false
+	}
+	return t.root.find(0, hashKey(key), key)
+}
+
+// Assoc sets key to value in place.
+func (t *Transient) Assoc(key string, value interface{}) *Transient {
+	t.ensureEditable()
+	if t.root == nil {
+		t.root = &node{edit: t.edit}
+	}
+	added := false
+	t.root = t.root.assoc(t.edit, 0, hashKey(key), key, value, &added)
+	if added {
+		t.count++
+	}
+	return t
+}
+
+// Dissoc removes key in place.
+func (t *Transient) Dissoc(key string) *Transient {
+	t.ensureEditable()
+	if t.root == nil {
+		return t
+	}
+	removed := false
+	t.root = t.root.dissoc(t.edit, 0, hashKey(key), key, &removed)
+	if removed {
+		t.count--
+	}
+	return t
+}
+
+// Persistent ends the batch edit and returns the resulting Map.
+func (t *Transient) Persistent() *Map {
+	t.ensureEditable()
+	t.edit = nil
+	return &Map{root: t.root, count: t.count}
+}
+
+func (t *Transient) ensureEditable() {
+	if t.edit == nil {
+		panic("hamt: transient used after Persistent")
+	}
+}
+
+// hashKey is the 64 bit FNV-1a hash of key.
+func hashKey(key string) uint64 {
+	var h uint64 = 14695981039346656037
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+func bitpos(hash uint64, shift uint) uint32 {
+	return 1 << ((hash >> shift) & levelMask)
+}
+
+func (n *node) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// editable returns n itself when it is owned by edit, otherwise a copy owned by edit.
+func (n *node) editable(edit *owner) *node {
+	if edit != nil && n.edit == edit {
+		return n
+	}
+	entries := make([]entry, len(n.entries), len(n.entries)+1)
+	copy(entries, n.entries)
+	return &node{bitmap: n.bitmap, collision: n.collision, entries: entries, edit: edit}
+}
+
+func (n *node) find(shift uint, hash uint64, key string) (interface{}, bool) {
+	for {
+		if n.collision {
+			for _, e := range n.entries {
+				if e.key == key {
+					return e.value, true
+				}
+			}
+			return nil, false
+		}
+		bit := bitpos(hash, shift)
+		if n.bitmap&bit == 0 {
+			return nil, false
+		}
+		e := n.entries[n.index(bit)]
+		if e.node == nil {
+			if e.key == key {
+				return e.value, true
+			}
+			return nil, false
+		}
+		n = e.node
+		shift += bitsPerLevel
+	}
+}
+
+func (n *node) assoc(edit *owner, shift uint, hash uint64, key string, value interface{}, added *bool) *node {
+	if n.collision {
+		for i, e := range n.entries {
+			if e.key == key {
+				d := n.editable(edit)
+				d.entries[i].value = value
+				return d
+			}
+		}
+		d := n.editable(edit)
+		d.entries = append(d.entries, entry{hash: hash, key: key, value: value})
+		*added = true
+		return d
+	}
+
+	bit := bitpos(hash, shift)
+	idx := n.index(bit)
+
+	if n.bitmap&bit == 0 {
+		d := n.editable(edit)
+		d.entries = append(d.entries, entry{})
+		copy(d.entries[idx+1:], d.entries[idx:])
+		d.entries[idx] = entry{hash: hash, key: key, value: value}
+		d.bitmap |= bit
+		*added = true
+		return d
+	}
+
+	e := n.entries[idx]
+	if e.node != nil {
+		child := e.node.assoc(edit, shift+bitsPerLevel, hash, key, value, added)
+		if child == e.node {
+			return n
+		}
+		d := n.editable(edit)
+		d.entries[idx].node = child
+		return d
+	}
+
+	if e.key == key {
+		d := n.editable(edit)
+		d.entries[idx].value = value
+		return d
+	}
+
+	d := n.editable(edit)
+	d.entries[idx] = entry{node: newPair(edit, shift+bitsPerLevel, e, entry{hash: hash, key: key, value: value})}
+	*added = true
+	return d
+}
+
+// newPair builds the smallest sub trie holding the two given entries.
+func newPair(edit *owner, shift uint, a, b entry) *node {
+	if shift >= maxShift {
+		return &node{collision: true, entries: []entry{a, b}, edit: edit}
+	}
+	bitA, bitB := bitpos(a.hash, shift), bitpos(b.hash, shift)
+	if bitA == bitB {
+		return &node{bitmap: bitA, entries: []entry{{node: newPair(edit, shift+bitsPerLevel, a, b)}}, edit: edit}
+	}
+	if bitA < bitB {
+		return &node{bitmap: bitA | bitB, entries: []entry{a, b}, edit: edit}
+	}
+	return &node{bitmap: bitA | bitB, entries: []entry{b, a}, edit: edit}
+}
+
+func (n *node) dissoc(edit *owner, shift uint, hash uint64, key string, removed *bool) *node {
+	if n.collision {
+		for i, e := range n.entries {
+			if e.key == key {
+				d := n.editable(edit)
+				d.entries = append(d.entries[:i], d.entries[i+1:]...)
+				*removed = true
+				return d
+			}
+		}
+		return n
+	}
+
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n
+	}
+	idx := n.index(bit)
+	e := n.entries[idx]
+
+	if e.node != nil {
+		child := e.node.dissoc(edit, shift+bitsPerLevel, hash, key, removed)
+		if child == e.node {
+			return n
+		}
+		d := n.editable(edit)
+		switch {
+		case len(child.entries) == 0:
+			d.entries = append(d.entries[:idx], d.entries[idx+1:]...)
+			d.bitmap ^= bit
+		case len(child.entries) == 1 && child.entries[0].node == nil:
+			// Pull a lonely key / value pair up into this node.
+			d.entries[idx] = child.entries[0]
+		default:
+			d.entries[idx].node = child
+		}
+		return d
+	}
+
+	if e.key != key {
+		return n
+	}
+	d := n.editable(edit)
+	d.entries = append(d.entries[:idx], d.entries[idx+1:]...)
+	d.bitmap ^= bit
+	*removed = true
+	return d
+}
+
+func (n *node) each(f func(key string, value interface{}) bool) bool {
+	for _, e := range n.entries {
+		if e.node != nil {
+			if !e.node.each(f) {
+				return false
+			}
+			continue
+		}
+		if !f(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
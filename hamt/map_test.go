@@ -0,0 +1,82 @@
+package hamt
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestMap_AssocGet(t *testing.T) {
+	m := New().Assoc("name", "mike").Assoc("sex", 1)
+
+	v, ok := m.Get("name")
+	assert.Equal(t, v, "mike")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, m.Len(), 2)
+
+	_, ok = m.Get("city")
+	assert.Equal(t, ok, false)
+
+	m2 := m.Assoc("name", "mary")
+	v, _ = m.Get("name")
+	assert.Equal(t, v, "mike")
+	v, _ = m2.Get("name")
+	assert.Equal(t, v, "mary")
+	assert.Equal(t, m2.Len(), 2)
+}
+
+func TestMap_Dissoc(t *testing.T) {
+	m := FromMap(map[string]interface{}{"name": "mike", "sex": 1})
+	m2 := m.Dissoc("name")
+
+	assert.Equal(t, m.Has("name"), true)
+	assert.Equal(t, m2.Has("name"), false)
+	assert.Equal(t, m2.Len(), 1)
+	assert.Equal(t, m2.Dissoc("city"), m2)
+}
+
+func TestMap_Large(t *testing.T) {
+	m := New()
+	for i := 0; i < 10000; i++ {
+		m = m.Assoc(strconv.Itoa(i), i)
+	}
+	assert.Equal(t, m.Len(), 10000)
+
+	for i := 0; i < 10000; i += 2 {
+		m = m.Dissoc(strconv.Itoa(i))
+	}
+	assert.Equal(t, m.Len(), 5000)
+
+	for i := 0; i < 10000; i++ {
+		v, ok := m.Get(strconv.Itoa(i))
+		assert.Equal(t, ok, i%2 == 1)
+		if ok {
+			assert.Equal(t, v, i)
+		}
+	}
+	assert.Equal(t, len(m.ToMap()), 5000)
+}
+
+func TestMap_Transient(t *testing.T) {
+	m := FromMap(map[string]interface{}{"a": 1, "b": 2})
+
+	tr := m.Transient()
+	tr.Assoc("c", 3).Dissoc("a").Assoc("b", 20)
+	m2 := tr.Persistent()
+
+	assert.Equal(t, m.ToMap(), map[string]interface{}{"a": 1, "b": 2})
+	assert.Equal(t, m2.ToMap(), map[string]interface{}{"b": 20, "c": 3})
+	assert.Equal(t, m2.Keys(), []string{"b", "c"})
+}
+
+func BenchmarkMap_Assoc(b *testing.B) {
+	m := New()
+	for i := 0; i < 10000; i++ {
+		m = m.Assoc(strconv.Itoa(i), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Assoc("key", i)
+	}
+}
@@ -0,0 +1,264 @@
+package collection
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// rowIndex maps the values at a key to the positions of the rows which have them. The index answers a
+// condition only when it gives the same rows as a scan with the condition would.
+type rowIndex struct {
+	// hash holds the positions of the rows by the valueHash of their value. It is nil unless the values are
+	// all strings, bools and numbers, as times equal strings in a date layout and other values may format alike.
+	hash map[string][]int
+	// sorted holds the rows in the order of their values, when the index is sorted and the values are all
+	// numbers or all times.
+	sorted []indexEntry
+	times  bool
+}
+
+type indexEntry struct {
+	value interface{}
+	row   int
+}
+
+// Index returns the collection with a hash index on every given key or path. Later calls of Where, WhereIn
+// and FirstWhere on the returned collection look up the rows with the = and in conditions of an indexed key
+// in the index instead of scanning every row. The collections derived from it are not indexed.
+func (c MapArrayCollection) Index(keys ...string) Collection {
+	return c.buildIndexes(keys, false)
+}
+
+// SortedIndex is the same as Index, but the indexes are sorted as well, so the range conditions >, >=, <,
+// <= and between on a key whose values are all numbers or all times are looked up too.
+func (c MapArrayCollection) SortedIndex(keys ...string) Collection {
+	return c.buildIndexes(keys, true)
+}
+
+func (c MapArrayCollection) buildIndexes(keys []string, sorted bool) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	if len(keys) == 0 {
+		return BaseCollection{err: fmt.Errorf("%w: no index key", ErrInvalidArgument)}
+	}
+	var indexes = make(map[string]*rowIndex, len(c.indexes)+len(keys))
+	for k, x := range c.indexes {
+		indexes[k] = x
+	}
+	items := c.items()
+	for _, key := range keys {
+		if isWildcardPath(key) {
+			return BaseCollection{err: fmt.Errorf("%w: index on the wildcard path %q", ErrInvalidArgument, key)}
+		}
+		indexes[key] = newRowIndex(items, key, sorted)
+	}
+	c.indexes = indexes
+	return c
+}
+
+func newRowIndex(items []map[string]interface{}, key string, sorted bool) *rowIndex {
+	var (
+		x       = &rowIndex{hash: make(map[string][]int)}
+		numbers = sorted
+		times   = sorted
+		entries = make([]indexEntry, 0, len(items))
+	)
+	for i, item := range items {
+		value := lookupPath(item, key)
+		if value == nil {
+			continue
+		}
+		if t, ok := value.(time.Time); ok {
+			x.hash = nil
+			numbers = false
+			entries = append(entries, indexEntry{value: t, row: i})
+			continue
+		}
+		times = false
+		switch value.(type) {
+		case string, bool:
+		default:
+			if !isNumber(value) {
+				x.hash = nil
+			}
+		}
+		if !isNumber(value) {
+			numbers = false
+		} else if numbers {
+			entries = append(entries, indexEntry{value: nd(value), row: i})
+		}
+		if x.hash != nil {
+			h, _ := valueHash(value)
+			x.hash[h] = append(x.hash[h], i)
+		}
+	}
+	if numbers || times {
+		sort.SliceStable(entries, func(i, j int) bool {
+			c, _ := orderValues(entries[i].value, entries[j].value)
+			return c < 0
+		})
+		x.sorted, x.times = entries, times
+	}
+	return x
+}
+
+// lookup returns the positions of the rows which pass the condition of Where in ascending order, and
+// whether the index can answer the condition at all.
+func (x *rowIndex) lookup(values []interface{}) ([]int, bool) {
+	switch len(values) {
+	case 0:
+		return nil, false
+	case 1:
+		return x.equal(values[0])
+	}
+	op, ok := values[0].(string)
+	if !ok {
+		return nil, false
+	}
+	operands := values[1:]
+	switch normalizeOperator(op) {
+	case "=", "==":
+		if len(operands) == 1 {
+			return x.equal(operands[0])
+		}
+	case "in":
+		if len(operands) == 1 {
+			return x.in(operands[0])
+		}
+	case ">":
+		if len(operands) == 1 {
+			return x.between(operands[0], nil, false, true)
+		}
+	case ">=":
+		if len(operands) == 1 {
+			return x.between(operands[0], nil, true, true)
+		}
+	case "<":
+		if len(operands) == 1 {
+			return x.between(nil, operands[0], true, false)
+		}
+	case "<=":
+		if len(operands) == 1 {
+			return x.between(nil, operands[0], true, true)
+		}
+	case "between":
+		if bounds, err := betweenBounds(operands); err == nil {
+			return x.between(bounds[0], bounds[1], true, true)
+		}
+	}
+	return nil, false
+}
+
+func (x *rowIndex) equal(operand interface{}) ([]int, bool) {
+	if operand == nil {
+		return nil, false
+	}
+	if x.hash != nil {
+		h, _ := valueHash(operand)
+		rows := x.hash[h]
+		var d = make([]int, len(rows))
+		copy(d, rows)
+		return d, true
+	}
+	if x.sorted != nil && x.times {
+		return x.between(operand, operand, true, true)
+	}
+	return nil, false
+}
+
+func (x *rowIndex) in(operand interface{}) ([]int, bool) {
+	list := reflect.ValueOf(operand)
+	if x.hash == nil || (list.Kind() != reflect.Slice && list.Kind() != reflect.Array) {
+		return nil, false
+	}
+	var (
+		d    = make([]int, 0)
+		seen = make(map[string]bool)
+	)
+	for i := 0; i < list.Len(); i++ {
+		v := list.Index(i).Interface()
+		if v == nil {
+			return nil, false
+		}
+		h, _ := valueHash(v)
+		if !seen[h] {
+			seen[h] = true
+			d = append(d, x.hash[h]...)
+		}
+	}
+	sort.Ints(d)
+	return d, true
+}
+
+// between returns the rows whose values lie between low and high, a nil bound is open. The bounds are
+// compared like orderValues does, so a bound which can not be compared with the values matches no row.
+func (x *rowIndex) between(low, high interface{}, includeLow, includeHigh bool) ([]int, bool) {
+	if x.sorted == nil {
+		return nil, false
+	}
+	var (
+		n     = len(x.sorted)
+		start = 0
+		end   = n
+	)
+	if low != nil {
+		bound, ok := x.bound(low)
+		if !ok {
+			return []int{}, true
+		}
+		start = sort.Search(n, func(i int) bool {
+			c, _ := orderValues(x.sorted[i].value, bound)
+			return c > 0 || (includeLow && c == 0)
+		})
+	}
+	if high != nil {
+		bound, ok := x.bound(high)
+		if !ok {
+			return []int{}, true
+		}
+		end = sort.Search(n, func(i int) bool {
+			c, _ := orderValues(x.sorted[i].value, bound)
+			return c > 0 || (!includeHigh && c == 0)
+		})
+	}
+	var d = make([]int, 0, max(end-start, 0))
+	for i := start; i < end; i++ {
+		d = append(d, x.sorted[i].row)
+	}
+	sort.Ints(d)
+	return d, true
+}
+
+// bound converts a bound into the type of the sorted values.
+func (x *rowIndex) bound(v interface{}) (interface{}, bool) {
+	if x.times {
+		t, ok := asTime(v)
+		return t, ok
+	}
+	if _, ok := v.(time.Time); ok {
+		return nil, false
+	}
+	d, ok := asDecimal(v)
+	return d, ok
+}
+
+// indexLookup looks up the rows which pass the condition of Where in the index on key, and reports whether
+// there is an index which can answer the condition.
+func (c MapArrayCollection) indexLookup(key string, values []interface{}) ([]map[string]interface{}, bool) {
+	x, ok := c.indexes[key]
+	if !ok {
+		return nil, false
+	}
+	rows, ok := x.lookup(values)
+	if !ok {
+		return nil, false
+	}
+	var d = make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		d[i] = c.value.Get(row).(map[string]interface{})
+	}
+	return d, true
+}
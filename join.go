@@ -0,0 +1,227 @@
+package collection
+
+import "fmt"
+
+// JoinKey returns the key a row is joined on. Rows whose key is nil never match.
+type JoinKey func(row map[string]interface{}) interface{}
+
+// JoinPrefix is put before the columns of the right side of a join which the left side has as well.
+const JoinPrefix = "right_"
+
+type joinKind int
+
+const (
+	innerJoin joinKind = iota
+	leftJoin
+	rightJoin
+	fullOuterJoin
+	semiJoin
+	antiJoin
+)
+
+// InnerJoin joins the collection with other, keeping the pairs of rows which have the same key. Other is a
+// MapArrayCollection or a []map[string]interface{}. The rows are joined on one or more column names which
+// both sides share, on a JoinKey for both sides, or on two JoinKeys for the left and the right side.
+// The columns of the right side which collide with the left side are prefixed with JoinPrefix.
+func (c MapArrayCollection) InnerJoin(other interface{}, on ...interface{}) Collection {
+	return c.join(innerJoin, other, on)
+}
+
+// LeftJoin is the same as InnerJoin, but also keeps the rows of the collection without a match.
+func (c MapArrayCollection) LeftJoin(other interface{}, on ...interface{}) Collection {
+	return c.join(leftJoin, other, on)
+}
+
+// RightJoin is the same as InnerJoin, but also keeps the rows of other without a match. The result is in the
+// order of other.
+func (c MapArrayCollection) RightJoin(other interface{}, on ...interface{}) Collection {
+	return c.join(rightJoin, other, on)
+}
+
+// FullOuterJoin is the same as InnerJoin, but also keeps the rows of both sides without a match. The rows of
+// other without a match come last.
+func (c MapArrayCollection) FullOuterJoin(other interface{}, on ...interface{}) Collection {
+	return c.join(fullOuterJoin, other, on)
+}
+
+// SemiJoin returns the rows of the collection which have a match in other, the rows are not merged.
+func (c MapArrayCollection) SemiJoin(other interface{}, on ...interface{}) Collection {
+	return c.join(semiJoin, other, on)
+}
+
+// AntiJoin returns the rows of the collection which have no match in other.
+func (c MapArrayCollection) AntiJoin(other interface{}, on ...interface{}) Collection {
+	return c.join(antiJoin, other, on)
+}
+
+func (c MapArrayCollection) join(kind joinKind, other interface{}, on []interface{}) Collection {
+	var right []map[string]interface{}
+	switch o := other.(type) {
+	case MapArrayCollection:
+		if o.err != nil {
+			return BaseCollection{err: o.err}
+		}
+		right = o.items()
+	case []map[string]interface{}:
+		right = o
+	default:
+		return BaseCollection{err: wrongType("MapArrayCollection or []map[string]interface{}", other)}
+	}
+
+	leftKey, rightKey, columns, err := joinKeys(on)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	left := c.items()
+
+	// Build the hash table on the side whose rows are only looked up, and probe it with the other side in
+	// order, so the cost is O(n+m) plus the size of the result.
+	build, probe, buildKey, probeKey := right, left, rightKey, leftKey
+	if kind == rightJoin {
+		build, probe, buildKey, probeKey = left, right, leftKey, rightKey
+	}
+	var table = make(map[string][]int, len(build))
+	for i, row := range build {
+		if k, ok := valueHash(buildKey(row)); ok {
+			table[k] = append(table[k], i)
+		}
+	}
+
+	var (
+		d       = make([]map[string]interface{}, 0)
+		matched = make([]bool, len(build))
+		merge   = joinMerger(left, columns)
+	)
+	for _, row := range probe {
+		var matches []int
+		if k, ok := valueHash(probeKey(row)); ok {
+			matches = table[k]
+		}
+		switch kind {
+		case semiJoin:
+			if len(matches) > 0 {
+				d = append(d, row)
+			}
+			continue
+		case antiJoin:
+			if len(matches) == 0 {
+				d = append(d, row)
+			}
+			continue
+		}
+		for _, i := range matches {
+			matched[i] = true
+			if kind == rightJoin {
+				d = append(d, merge(build[i], row))
+			} else {
+				d = append(d, merge(row, build[i]))
+			}
+		}
+		if len(matches) == 0 {
+			switch kind {
+			case leftJoin, fullOuterJoin:
+				d = append(d, merge(row, nil))
+			case rightJoin:
+				d = append(d, merge(nil, row))
+			}
+		}
+	}
+	if kind == fullOuterJoin {
+		for i, row := range build {
+			if !matched[i] {
+				d = append(d, merge(nil, row))
+			}
+		}
+	}
+
+	return newMapArrayCollection(mapList(d))
+}
+
+// joinKeys turns the arguments of a join into the key functions of both sides. The shared column names are
+// returned as well, those are not prefixed.
+func joinKeys(on []interface{}) (JoinKey, JoinKey, []string, error) {
+	if len(on) == 0 {
+		return nil, nil, nil, fmt.Errorf("%w: no join key", ErrInvalidArgument)
+	}
+	if k, ok := asJoinKey(on[0]); ok {
+		switch len(on) {
+		case 1:
+			return k, k, nil, nil
+		case 2:
+			if rk, ok := asJoinKey(on[1]); ok {
+				return k, rk, nil, nil
+			}
+			return nil, nil, nil, wrongType("JoinKey", on[1])
+		default:
+			return nil, nil, nil, fmt.Errorf("%w: too many join keys", ErrInvalidArgument)
+		}
+	}
+	var columns = make([]string, len(on))
+	for i, v := range on {
+		s, ok := v.(string)
+		if !ok {
+			return nil, nil, nil, wrongType("string", v)
+		}
+		columns[i] = s
+	}
+	key := JoinKey(func(row map[string]interface{}) interface{} {
+		if len(columns) == 1 {
+			return lookupPath(row, columns[0])
+		}
+		var k = make([]interface{}, len(columns))
+		for i, col := range columns {
+			if k[i] = lookupPath(row, col); k[i] == nil {
+				return nil
+			}
+		}
+		return k
+	})
+	return key, key, columns, nil
+}
+
+// asJoinKey accepts a JoinKey as well as a plain function literal with its signature.
+func asJoinKey(v interface{}) (JoinKey, bool) {
+	switch k := v.(type) {
+	case JoinKey:
+		return k, true
+	case func(row map[string]interface{}) interface{}:
+		return k, true
+	default:
+		return nil, false
+	}
+}
+
+// joinMerger returns a function which merges a left and a right row, either of them may be nil. The columns
+// of the right side which any row of the left side has too are prefixed, except for the shared join columns.
+func joinMerger(left []map[string]interface{}, columns []string) func(l, r map[string]interface{}) map[string]interface{} {
+	var shared = make(map[string]bool, len(columns))
+	for _, col := range columns {
+		shared[col] = true
+	}
+	var leftColumns = make(map[string]bool)
+	for _, row := range left {
+		for k := range row {
+			leftColumns[k] = true
+		}
+	}
+
+	return func(l, r map[string]interface{}) map[string]interface{} {
+		var d = make(map[string]interface{}, len(l)+len(r))
+		for k, v := range l {
+			d[k] = v
+		}
+		for k, v := range r {
+			switch {
+			case shared[k]:
+				if _, ok := d[k]; !ok {
+					d[k] = v
+				}
+			case leftColumns[k]:
+				d[JoinPrefix+k] = v
+			default:
+				d[k] = v
+			}
+		}
+		return d
+	}
+}
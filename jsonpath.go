@@ -0,0 +1,1133 @@
+package collection
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hulklab/collection/hamt"
+	"github.com/hulklab/collection/vector_trie"
+	"github.com/shopspring/decimal"
+)
+
+// JSONPath returns the values which the JSONPath expression selects, as defined by RFC 9535. The root $ is
+// the map of a MapCollection and the array of the rows of a MapArrayCollection.
+//
+//	Collect(store).JSONPath(`$.store.book[?(@.price < 10)].title`)
+//
+// A singular path, which only has names and indexes such as $.store.bicycle, selects at most one value. An
+// object is returned as a MapCollection, and an array is collected like Collect does. Any other path returns
+// the selected values in order, as a MapArrayCollection when they are all objects, a StringArrayCollection
+// or NumberArrayCollection when they are all strings or numbers, and a MultiDimensionalArrayCollection when
+// they are all arrays. Nothing selected is an empty MapArrayCollection. Values of mixed types return
+// ErrWrongType, JSONPathValues returns them as they are.
+func (c MapCollection) JSONPath(expr string) Collection {
+	return jsonPathCollection(c.err, c.value, expr)
+}
+
+// JSONPathValues returns the values which the JSONPath expression selects, in order.
+func (c MapCollection) JSONPathValues(expr string) ([]interface{}, error) {
+	return jsonPathValues(c.err, c.value, expr)
+}
+
+// JSONPath is the same as the JSONPath of MapCollection, the root $ is the array of the rows.
+func (c MapArrayCollection) JSONPath(expr string) Collection {
+	return jsonPathCollection(c.err, c.items(), expr)
+}
+
+// JSONPathValues returns the values which the JSONPath expression selects, in order.
+func (c MapArrayCollection) JSONPathValues(expr string) ([]interface{}, error) {
+	return jsonPathValues(c.err, c.items(), expr)
+}
+
+func jsonPathValues(err error, root interface{}, expr string) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	p, err := compileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	nodes := p.query(root, root)
+	for i, node := range nodes {
+		// Only the root of a MapCollection is a persistent map.
+		if m, ok := node.(*hamt.Map); ok {
+			nodes[i] = m.ToMap()
+		}
+	}
+	return nodes, nil
+}
+
+func jsonPathCollection(err error, root interface{}, expr string) Collection {
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	p, err := compileJSONPath(expr)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	nodes := p.query(root, root)
+	if p.singular() && len(nodes) == 1 {
+		switch t := nodes[0].(type) {
+		case *hamt.Map:
+			return newMapCollection(t)
+		case map[string]interface{}:
+			return newMapCollection(hamt.FromMap(t))
+		case []interface{}, []map[string]interface{}, *vector_trie.List:
+			nodes = jsonItems(t)
+		}
+	}
+	return collectNodes(nodes, "use JSONPathValues")
+}
+
+// collectNodes collects values of one kind into the collection of that kind. Values of mixed kinds return an
+// ErrWrongType whose message ends with hint, which tells how the caller can read them instead.
+func collectNodes(nodes []interface{}, hint string) Collection {
+	if len(nodes) == 0 {
+		return newMapArrayCollection(mapList(nil))
+	}
+	var maps, strs, numbers, arrays = true, true, true, true
+	for _, node := range nodes {
+		switch node.(type) {
+		case map[string]interface{}, *hamt.Map:
+			strs, numbers, arrays = false, false, false
+		case string:
+			maps, numbers, arrays = false, false, false
+		case []interface{}, []map[string]interface{}, *vector_trie.List:
+			maps, strs, numbers = false, false, false
+		default:
+			maps, strs, arrays = false, false, false
+			numbers = numbers && isNumber(node)
+		}
+	}
+	switch {
+	case maps:
+		var d = make([]map[string]interface{}, len(nodes))
+		for i, node := range nodes {
+			if m, ok := node.(*hamt.Map); ok {
+				d[i] = m.ToMap()
+			} else {
+				d[i] = node.(map[string]interface{})
+			}
+		}
+		return newMapArrayCollection(mapList(d))
+	case strs:
+		var d = make([]string, len(nodes))
+		for i, node := range nodes {
+			d[i] = node.(string)
+		}
+		return newStringArrayCollection(stringList(d))
+	case numbers:
+		var d = make([]decimal.Decimal, len(nodes))
+		for i, node := range nodes {
+			d[i] = nd(node)
+		}
+		return newNumberArrayCollection(decimalList(d))
+	case arrays:
+		var d = make([][]interface{}, len(nodes))
+		for i, node := range nodes {
+			d[i] = jsonItems(node)
+		}
+		return MultiDimensionalArrayCollection{value: d, BaseCollection: BaseCollection{length: len(d)}}
+	}
+	return BaseCollection{err: fmt.Errorf("%w: the values have mixed types, %s", ErrWrongType, hint)}
+}
+
+// jsonItems returns the items of an array.
+func jsonItems(v interface{}) []interface{} {
+	if items, ok := v.([]interface{}); ok {
+		return items
+	}
+	var d = make([]interface{}, 0)
+	pathEach(v, func(_ string, value interface{}) {
+		d = append(d, value)
+	})
+	return d
+}
+
+func isJSONObject(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, *hamt.Map:
+		return true
+	}
+	return false
+}
+
+func isJSONArray(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, []map[string]interface{}, *vector_trie.List:
+		return true
+	}
+	return false
+}
+
+// jsonLen returns the number of items of an array or members of an object.
+func jsonLen(v interface{}) int {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return len(t)
+	case *hamt.Map:
+		return t.Len()
+	case []interface{}:
+		return len(t)
+	case []map[string]interface{}:
+		return len(t)
+	case *vector_trie.List:
+		return t.Len()
+	}
+	return 0
+}
+
+type jsonPath struct {
+	segments []jsonPathSegment
+}
+
+// jsonPathSegment applies its selectors to the nodes, or to the nodes and all of their descendants.
+type jsonPathSegment struct {
+	descendant bool
+	selectors  []jsonPathSelector
+}
+
+type jsonPathSelectorKind int
+
+const (
+	selectName jsonPathSelectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+type jsonPathSelector struct {
+	kind   jsonPathSelectorKind
+	name   string
+	index  int
+	slice  [3]*int
+	filter func(root, current interface{}) bool
+}
+
+func compileJSONPath(expr string) (*jsonPath, error) {
+	parser := &jsonPathParser{src: expr}
+	if !parser.accept("$") {
+		return nil, parser.errorf("expected $")
+	}
+	p, err := parser.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(expr) {
+		return nil, parser.errorf("unexpected %q", expr[parser.pos:])
+	}
+	return p, nil
+}
+
+// singular determines if the path only has name and index selectors, so it selects at most one node.
+func (p *jsonPath) singular() bool {
+	for _, s := range p.segments {
+		if s.descendant || len(s.selectors) != 1 || (s.selectors[0].kind != selectName && s.selectors[0].kind != selectIndex) {
+			return false
+		}
+	}
+	return true
+}
+
+// query returns the nodes the path selects from the node start.
+func (p *jsonPath) query(root, start interface{}) []interface{} {
+	var nodes = []interface{}{start}
+	for _, s := range p.segments {
+		var d = make([]interface{}, 0, len(nodes))
+		for _, node := range nodes {
+			if s.descendant {
+				d = s.descend(root, node, d)
+			} else {
+				d = s.apply(root, node, d)
+			}
+		}
+		nodes = d
+	}
+	return nodes
+}
+
+func (s jsonPathSegment) apply(root, node interface{}, d []interface{}) []interface{} {
+	for _, sel := range s.selectors {
+		d = sel.apply(root, node, d)
+	}
+	return d
+}
+
+// descend applies the selectors to the node and then to its children, in document order.
+func (s jsonPathSegment) descend(root, node interface{}, d []interface{}) []interface{} {
+	d = s.apply(root, node, d)
+	pathEach(node, func(_ string, value interface{}) {
+		d = s.descend(root, value, d)
+	})
+	return d
+}
+
+func (sel jsonPathSelector) apply(root, node interface{}, d []interface{}) []interface{} {
+	switch sel.kind {
+	case selectName:
+		if isJSONObject(node) {
+			if v, ok := pathChild(node, sel.name); ok {
+				d = append(d, v)
+			}
+		}
+	case selectWildcard:
+		pathEach(node, func(_ string, value interface{}) {
+			d = append(d, value)
+		})
+	case selectIndex:
+		if isJSONArray(node) {
+			i := sel.index
+			if i < 0 {
+				i += jsonLen(node)
+			}
+			if v, ok := pathChild(node, strconv.Itoa(i)); ok {
+				d = append(d, v)
+			}
+		}
+	case selectSlice:
+		if isJSONArray(node) {
+			items := jsonItems(node)
+			for _, i := range sliceIndexes(sel.slice, len(items)) {
+				d = append(d, items[i])
+			}
+		}
+	case selectFilter:
+		pathEach(node, func(_ string, value interface{}) {
+			if sel.filter(root, value) {
+				d = append(d, value)
+			}
+		})
+	}
+	return d
+}
+
+// sliceIndexes returns the indexes a slice selects from an array of length n, as RFC 9535 section 2.3.4.2
+// defines them.
+func sliceIndexes(slice [3]*int, n int) []int {
+	var step = 1
+	if slice[2] != nil {
+		step = *slice[2]
+	}
+	if step == 0 {
+		return nil
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	var start, end int
+	if step > 0 {
+		start, end = 0, n
+	} else {
+		start, end = n-1, -n-1
+	}
+	if slice[0] != nil {
+		start = normalize(*slice[0])
+	}
+	if slice[1] != nil {
+		end = normalize(*slice[1])
+	}
+
+	var d []int
+	if step > 0 {
+		lower, upper := min(max(start, 0), n), min(max(end, 0), n)
+		for i := lower; i < upper; i += step {
+			d = append(d, i)
+		}
+	} else {
+		upper, lower := min(max(start, -1), n-1), min(max(end, -1), n-1)
+		for i := upper; lower < i; i += step {
+			d = append(d, i)
+		}
+	}
+	return d
+}
+
+// The types of the filter expressions of RFC 9535 section 2.4.1.
+type jsonPathType int
+
+const (
+	jsonPathValueType jsonPathType = iota
+	jsonPathLogicalType
+	jsonPathNodesType
+)
+
+// jsonPathOperand is a literal, a query or a function call of a filter. A value which is Nothing, such as
+// the value of a query which selects no node, is returned with false.
+type jsonPathOperand struct {
+	typ      jsonPathType
+	literal  bool
+	singular bool
+	value    func(root, current interface{}) (interface{}, bool)
+	logical  func(root, current interface{}) bool
+	nodes    func(root, current interface{}) []interface{}
+}
+
+// asValue converts the operand into a value, a query must be singular.
+func (o jsonPathOperand) asValue() (func(root, current interface{}) (interface{}, bool), bool) {
+	switch {
+	case o.typ == jsonPathValueType:
+		return o.value, true
+	case o.typ == jsonPathNodesType && o.singular:
+		return func(root, current interface{}) (interface{}, bool) {
+			nodes := o.nodes(root, current)
+			if len(nodes) != 1 {
+				return nil, false
+			}
+			return nodes[0], true
+		}, true
+	}
+	return nil, false
+}
+
+// jsonPathFunction is a function extension of RFC 9535 section 2.4. A function which keeps a state for every
+// call in a path, such as the compiled pattern of match, has newValue instead of value.
+type jsonPathFunction struct {
+	params   []jsonPathType
+	result   jsonPathType
+	value    func(args []interface{}, ok []bool) (interface{}, bool)
+	newValue func() func(args []interface{}, ok []bool) (interface{}, bool)
+}
+
+var jsonPathFunctions = map[string]jsonPathFunction{
+	"length": {params: []jsonPathType{jsonPathValueType}, result: jsonPathValueType,
+		value: func(args []interface{}, ok []bool) (interface{}, bool) {
+			switch t := args[0].(type) {
+			case string:
+				return utf8.RuneCountInString(t), ok[0]
+			}
+			if isJSONObject(args[0]) || isJSONArray(args[0]) {
+				return jsonLen(args[0]), ok[0]
+			}
+			return nil, false
+		}},
+	"count": {params: []jsonPathType{jsonPathNodesType}, result: jsonPathValueType,
+		value: func(args []interface{}, ok []bool) (interface{}, bool) {
+			return len(args[0].([]interface{})), true
+		}},
+	"value": {params: []jsonPathType{jsonPathNodesType}, result: jsonPathValueType,
+		value: func(args []interface{}, ok []bool) (interface{}, bool) {
+			nodes := args[0].([]interface{})
+			if len(nodes) != 1 {
+				return nil, false
+			}
+			return nodes[0], true
+		}},
+	"match": {params: []jsonPathType{jsonPathValueType, jsonPathValueType}, result: jsonPathLogicalType,
+		newValue: func() func(args []interface{}, ok []bool) (interface{}, bool) {
+			return iRegexpMatcher(true)
+		}},
+	"search": {params: []jsonPathType{jsonPathValueType, jsonPathValueType}, result: jsonPathLogicalType,
+		newValue: func() func(args []interface{}, ok []bool) (interface{}, bool) {
+			return iRegexpMatcher(false)
+		}},
+}
+
+// iRegexpMatcher returns the value of match or search, which determines if the string matches the I-Regexp
+// of RFC 9485, as a whole or in part. A value which is not a string and an invalid pattern never match. The
+// last pattern is kept compiled, which is every time for a literal pattern.
+func iRegexpMatcher(full bool) func(args []interface{}, ok []bool) (interface{}, bool) {
+	var (
+		last     string
+		re       *regexp.Regexp
+		compiled = false
+	)
+	return func(args []interface{}, ok []bool) (interface{}, bool) {
+		s, isString := args[0].(string)
+		pattern, isPattern := args[1].(string)
+		if !ok[0] || !ok[1] || !isString || !isPattern {
+			return false, true
+		}
+		if !compiled || pattern != last {
+			last, re, compiled = pattern, iRegexp(pattern, full), true
+		}
+		return re != nil && re.MatchString(s), true
+	}
+}
+
+// iRegexp compiles an I-Regexp. Its dot matches any character but a line feed and a carriage return.
+func iRegexp(pattern string, full bool) *regexp.Regexp {
+	var (
+		b       strings.Builder
+		inClass = false
+	)
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteString(pattern[i : i+2])
+			i++
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+		case c == ']':
+			inClass = false
+			b.WriteByte(c)
+		case c == '.' && !inClass:
+			b.WriteString(`[^\n\r]`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	expr := b.String()
+	if full {
+		expr = `^(?:` + expr + `)$`
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// jsonEqual compares two values as the == of RFC 9535 section 2.3.5.2.2 does.
+func jsonEqual(a, b interface{}) bool {
+	switch {
+	case isNumber(a) && isNumber(b):
+		return nd(a).Equal(nd(b))
+	case isJSONArray(a) && isJSONArray(b):
+		x, y := jsonItems(a), jsonItems(b)
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case isJSONObject(a) && isJSONObject(b):
+		if jsonLen(a) != jsonLen(b) {
+			return false
+		}
+		var equal = true
+		pathEach(a, func(k string, value interface{}) {
+			other, ok := pathChild(b, k)
+			equal = equal && ok && jsonEqual(value, other)
+		})
+		return equal
+	}
+	switch t := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		s, ok := b.(string)
+		return ok && t == s
+	case bool:
+		v, ok := b.(bool)
+		return ok && t == v
+	}
+	// The values which json does not decode into, such as a []string, are equal when they are deeply equal.
+	return reflect.DeepEqual(a, b)
+}
+
+// jsonPathLess compares numbers and strings, other values are not ordered.
+func jsonPathLess(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return nd(a).LessThan(nd(b))
+	}
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	return okA && okB && sa < sb
+}
+
+// jsonPathCompare compares two values which may be Nothing. Nothing only equals Nothing.
+func jsonPathCompare(op string, a interface{}, okA bool, b interface{}, okB bool) bool {
+	equal := func() bool {
+		if !okA || !okB {
+			return okA == okB
+		}
+		return jsonEqual(a, b)
+	}
+	less := func(a interface{}, okA bool, b interface{}, okB bool) bool {
+		return okA && okB && jsonPathLess(a, b)
+	}
+	switch op {
+	case "==":
+		return equal()
+	case "!=":
+		return !equal()
+	case "<":
+		return less(a, okA, b, okB)
+	case "<=":
+		return less(a, okA, b, okB) || equal()
+	case ">":
+		return less(b, okB, a, okA)
+	default:
+		return less(b, okB, a, okA) || equal()
+	}
+}
+
+// jsonPathParser parses the syntax of RFC 9535.
+type jsonPathParser struct {
+	src string
+	pos int
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: jsonpath at position %d: %s", ErrInvalidArgument, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonPathParser) peek(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *jsonPathParser) accept(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf("expected %q", s)
+	}
+	return nil
+}
+
+// skipBlank skips the white space of RFC 9535, which is a space, a tab, a line feed or a carriage return.
+func (p *jsonPathParser) skipBlank() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n\r", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// parseSegments parses the segments of a query. The blank before a segment is only skipped when a segment
+// follows, so the blank after a query in a filter is left to the filter.
+func (p *jsonPathParser) parseSegments() (*jsonPath, error) {
+	var path = &jsonPath{}
+	for {
+		start := p.pos
+		p.skipBlank()
+		if !p.peek(".") && !p.peek("[") {
+			p.pos = start
+			return path, nil
+		}
+		s, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		path.segments = append(path.segments, s)
+	}
+}
+
+func (p *jsonPathParser) parseSegment() (jsonPathSegment, error) {
+	var s jsonPathSegment
+	switch {
+	case p.accept(".."):
+		s.descendant = true
+		if p.peek("[") {
+			return p.parseBracketed(s)
+		}
+	case p.accept("."):
+	default:
+		return p.parseBracketed(s)
+	}
+	if p.accept("*") {
+		s.selectors = []jsonPathSelector{{kind: selectWildcard}}
+		return s, nil
+	}
+	name, ok := p.parseMemberName()
+	if !ok {
+		return s, p.errorf("expected a member name or *")
+	}
+	s.selectors = []jsonPathSelector{{kind: selectName, name: name}}
+	return s, nil
+}
+
+// parseMemberName parses the member name of the shorthand .name, which starts with a letter, an underscore or
+// a non ASCII character and goes on with digits as well.
+func (p *jsonPathParser) parseMemberName() (string, bool) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		first := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= 0x80 && r != utf8.RuneError)
+		if !first && (p.pos == start || r < '0' || r > '9') {
+			break
+		}
+		p.pos += size
+	}
+	return p.src[start:p.pos], p.pos > start
+}
+
+func (p *jsonPathParser) parseBracketed(s jsonPathSegment) (jsonPathSegment, error) {
+	if err := p.expect("["); err != nil {
+		return s, err
+	}
+	for {
+		p.skipBlank()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return s, err
+		}
+		s.selectors = append(s.selectors, sel)
+		p.skipBlank()
+		if p.accept("]") {
+			return s, nil
+		}
+		if err := p.expect(","); err != nil {
+			return s, err
+		}
+	}
+}
+
+func (p *jsonPathParser) parseSelector() (jsonPathSelector, error) {
+	switch {
+	case p.peek("'"), p.peek(`"`):
+		name, err := p.parseString()
+		return jsonPathSelector{kind: selectName, name: name}, err
+	case p.accept("*"):
+		return jsonPathSelector{kind: selectWildcard}, nil
+	case p.accept("?"):
+		p.skipBlank()
+		filter, err := p.parseLogicalOr()
+		return jsonPathSelector{kind: selectFilter, filter: filter}, err
+	}
+
+	var (
+		slice [3]*int
+		part  = 0
+	)
+	for {
+		if p.pos < len(p.src) && (p.src[p.pos] == '-' || isDigitByte(p.src[p.pos])) {
+			i, err := p.parseInt()
+			if err != nil {
+				return jsonPathSelector{}, err
+			}
+			slice[part] = &i
+			p.skipBlank()
+		}
+		if part == 2 || !p.accept(":") {
+			break
+		}
+		part++
+		p.skipBlank()
+	}
+	switch {
+	case part == 0 && slice[0] != nil:
+		return jsonPathSelector{kind: selectIndex, index: *slice[0]}, nil
+	case part > 0:
+		return jsonPathSelector{kind: selectSlice, slice: slice}, nil
+	}
+	return jsonPathSelector{}, p.errorf("expected a selector")
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseInt parses an integer in the range of I-JSON, without leading zeros and without -0.
+func (p *jsonPathParser) parseInt() (int, error) {
+	start := p.pos
+	p.accept("-")
+	digits := p.pos
+	for p.pos < len(p.src) && isDigitByte(p.src[p.pos]) {
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	if p.pos == digits || (p.src[digits] == '0' && (p.pos-digits > 1 || digits > start)) {
+		return 0, p.errorf("invalid integer %q", text)
+	}
+	i, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || i > 1<<53-1 || i < -(1<<53-1) {
+		return 0, p.errorf("integer %q out of range", text)
+	}
+	return int(i), nil
+}
+
+// parseString parses a string literal in single or double quotes with the escapes of RFC 9535.
+func (p *jsonPathParser) parseString() (string, error) {
+	var (
+		quote = p.src[p.pos]
+		b     strings.Builder
+	)
+	p.pos++
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c < 0x20:
+			return "", p.errorf("control character in a string")
+		case c != '\\':
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			break
+		}
+		e := p.src[p.pos+1]
+		p.pos += 2
+		switch e {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '/', '\\':
+			b.WriteByte(e)
+		case 'u':
+			r, err := p.parseUnicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			if e != quote {
+				return "", p.errorf("invalid escape \\%c", e)
+			}
+			b.WriteByte(e)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseUnicodeEscape parses the hex digits of \u, and of the low surrogate which must follow a high one.
+func (p *jsonPathParser) parseUnicodeEscape() (rune, error) {
+	hex := func() (rune, error) {
+		if p.pos+4 > len(p.src) {
+			return 0, p.errorf("invalid unicode escape")
+		}
+		v, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+		if err != nil {
+			return 0, p.errorf("invalid unicode escape")
+		}
+		p.pos += 4
+		return rune(v), nil
+	}
+	r, err := hex()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case r >= 0xDC00 && r <= 0xDFFF:
+		return 0, p.errorf("unpaired low surrogate")
+	case r >= 0xD800 && r <= 0xDBFF:
+		if !p.accept(`\u`) {
+			return 0, p.errorf("unpaired high surrogate")
+		}
+		low, err := hex()
+		if err != nil {
+			return 0, err
+		}
+		if low < 0xDC00 || low > 0xDFFF {
+			return 0, p.errorf("unpaired high surrogate")
+		}
+		return utf16.DecodeRune(r, low), nil
+	}
+	return r, nil
+}
+
+func (p *jsonPathParser) parseLogicalOr() (func(root, current interface{}) bool, error) {
+	x, err := p.parseLogicalAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		start := p.pos
+		p.skipBlank()
+		if !p.accept("||") {
+			p.pos = start
+			return x, nil
+		}
+		p.skipBlank()
+		y, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		left := x
+		x = func(root, current interface{}) bool {
+			return left(root, current) || y(root, current)
+		}
+	}
+}
+
+func (p *jsonPathParser) parseLogicalAnd() (func(root, current interface{}) bool, error) {
+	x, err := p.parseBasic()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		start := p.pos
+		p.skipBlank()
+		if !p.accept("&&") {
+			p.pos = start
+			return x, nil
+		}
+		p.skipBlank()
+		y, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		left := x
+		x = func(root, current interface{}) bool {
+			return left(root, current) && y(root, current)
+		}
+	}
+}
+
+var jsonPathComparisons = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseBasic parses a parenthesized expression, a comparison or a test, any of them may be negated with !
+// except for a comparison.
+func (p *jsonPathParser) parseBasic() (func(root, current interface{}) bool, error) {
+	if p.accept("!") {
+		p.skipBlank()
+		var (
+			x   func(root, current interface{}) bool
+			err error
+		)
+		if p.peek("(") {
+			x, err = p.parseParen()
+		} else {
+			x, err = p.parseTest()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(root, current interface{}) bool {
+			return !x(root, current)
+		}, nil
+	}
+	if p.peek("(") {
+		return p.parseParen()
+	}
+
+	x, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	start := p.pos
+	p.skipBlank()
+	var op string
+	for _, o := range jsonPathComparisons {
+		if p.accept(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		p.pos = start
+		return p.test(x)
+	}
+	p.skipBlank()
+	y, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	vx, okX := x.asValue()
+	vy, okY := y.asValue()
+	if !okX || !okY {
+		return nil, p.errorf("a comparison takes literals, singular queries and functions of a value")
+	}
+	return func(root, current interface{}) bool {
+		a, okA := vx(root, current)
+		b, okB := vy(root, current)
+		return jsonPathCompare(op, a, okA, b, okB)
+	}, nil
+}
+
+func (p *jsonPathParser) parseParen() (func(root, current interface{}) bool, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	x, err := p.parseLogicalOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	return x, p.expect(")")
+}
+
+func (p *jsonPathParser) parseTest() (func(root, current interface{}) bool, error) {
+	x, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return p.test(x)
+}
+
+// test turns a query into a test of existence. A function must return a logical or a nodes type.
+func (p *jsonPathParser) test(x jsonPathOperand) (func(root, current interface{}) bool, error) {
+	switch x.typ {
+	case jsonPathLogicalType:
+		return x.logical, nil
+	case jsonPathNodesType:
+		return func(root, current interface{}) bool {
+			return len(x.nodes(root, current)) > 0
+		}, nil
+	}
+	if x.literal {
+		return nil, p.errorf("a literal is not a test")
+	}
+	return nil, p.errorf("a function which returns a value is not a test")
+}
+
+// parseOperand parses a literal, a query or a function call.
+func (p *jsonPathParser) parseOperand() (jsonPathOperand, error) {
+	switch {
+	case p.peek("@"), p.peek("$"):
+		relative := p.accept("@")
+		if !relative {
+			p.accept("$")
+		}
+		path, err := p.parseSegments()
+		if err != nil {
+			return jsonPathOperand{}, err
+		}
+		return jsonPathOperand{typ: jsonPathNodesType, singular: path.singular(), nodes: func(root, current interface{}) []interface{} {
+			if relative {
+				return path.query(root, current)
+			}
+			return path.query(root, root)
+		}}, nil
+	case p.peek("'"), p.peek(`"`):
+		s, err := p.parseString()
+		return jsonPathLiteral(s), err
+	case p.accept("true"):
+		return jsonPathLiteral(true), nil
+	case p.accept("false"):
+		return jsonPathLiteral(false), nil
+	case p.accept("null"):
+		return jsonPathLiteral(nil), nil
+	case p.pos < len(p.src) && (p.src[p.pos] == '-' || isDigitByte(p.src[p.pos])):
+		return p.parseNumber()
+	case p.pos < len(p.src) && p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z':
+		return p.parseFunction()
+	}
+	return jsonPathOperand{}, p.errorf("expected a literal, a query or a function")
+}
+
+func jsonPathLiteral(v interface{}) jsonPathOperand {
+	return jsonPathOperand{typ: jsonPathValueType, literal: true, value: func(root, current interface{}) (interface{}, bool) {
+		return v, true
+	}}
+}
+
+// parseNumber parses a number literal like JSON does, with a -0 allowed.
+func (p *jsonPathParser) parseNumber() (jsonPathOperand, error) {
+	start := p.pos
+	p.accept("-")
+	digits := p.pos
+	for p.pos < len(p.src) && isDigitByte(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == digits || (p.src[digits] == '0' && p.pos-digits > 1) {
+		return jsonPathOperand{}, p.errorf("invalid number %q", p.src[start:p.pos])
+	}
+	if p.accept(".") {
+		fraction := p.pos
+		for p.pos < len(p.src) && isDigitByte(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == fraction {
+			return jsonPathOperand{}, p.errorf("invalid number %q", p.src[start:p.pos])
+		}
+	}
+	if p.accept("e") || p.accept("E") {
+		if !p.accept("-") {
+			p.accept("+")
+		}
+		exponent := p.pos
+		for p.pos < len(p.src) && isDigitByte(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == exponent {
+			return jsonPathOperand{}, p.errorf("invalid number %q", p.src[start:p.pos])
+		}
+	}
+	d, err := decimal.NewFromString(p.src[start:p.pos])
+	if err != nil {
+		return jsonPathOperand{}, p.errorf("invalid number %q", p.src[start:p.pos])
+	}
+	return jsonPathLiteral(d), nil
+}
+
+// parseFunction parses the call of a function extension, and checks the types of its arguments.
+func (p *jsonPathParser) parseFunction() (jsonPathOperand, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' || p.src[p.pos] == '_' || isDigitByte(p.src[p.pos])) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	f, ok := jsonPathFunctions[name]
+	if !ok {
+		p.pos = start
+		return jsonPathOperand{}, p.errorf("unknown function %q", name)
+	}
+	if err := p.expect("("); err != nil {
+		return jsonPathOperand{}, err
+	}
+
+	var args []func(root, current interface{}) (interface{}, bool)
+	for i, param := range f.params {
+		p.skipBlank()
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return jsonPathOperand{}, err
+			}
+			p.skipBlank()
+		}
+		arg, err := p.parseOperand()
+		if err != nil {
+			return jsonPathOperand{}, err
+		}
+		switch {
+		case param == jsonPathNodesType && arg.typ == jsonPathNodesType:
+			args = append(args, func(root, current interface{}) (interface{}, bool) {
+				return arg.nodes(root, current), true
+			})
+		case param == jsonPathValueType:
+			value, ok := arg.asValue()
+			if !ok {
+				return jsonPathOperand{}, p.errorf("argument %d of %s must be a value", i+1, name)
+			}
+			args = append(args, value)
+		default:
+			return jsonPathOperand{}, p.errorf("argument %d of %s must be a query", i+1, name)
+		}
+	}
+	p.skipBlank()
+	if err := p.expect(")"); err != nil {
+		return jsonPathOperand{}, err
+	}
+
+	value := f.value
+	if f.newValue != nil {
+		value = f.newValue()
+	}
+	call := func(root, current interface{}) (interface{}, bool) {
+		var (
+			values = make([]interface{}, len(args))
+			ok     = make([]bool, len(args))
+		)
+		for i, arg := range args {
+			values[i], ok[i] = arg(root, current)
+		}
+		return value(values, ok)
+	}
+	if f.result == jsonPathLogicalType {
+		return jsonPathOperand{typ: jsonPathLogicalType, logical: func(root, current interface{}) bool {
+			v, _ := call(root, current)
+			return v == true
+		}}, nil
+	}
+	return jsonPathOperand{typ: jsonPathValueType, value: call}, nil
+}
@@ -0,0 +1,507 @@
+package collection
+
+import (
+	"fmt"
+
+	"github.com/hulklab/collection/vector_trie"
+	"github.com/shopspring/decimal"
+)
+
+// LazyCollection records a chain of operations over an array collection and runs them only when a
+// terminal method such as ToMapArray, First or Count is called. The items are pulled through the whole
+// chain one by one, so no intermediate collection is built, and Take and First stop pulling as soon as
+// they have enough items.
+type LazyCollection struct {
+	// source passes the items to yield until it returns false, and returns the error of reading them.
+	source func(yield func(value interface{}) bool) error
+	// wrap turns the result back into the collection type of the source.
+	wrap   func(l *vector_trie.List) Collection
+	stages []stage
+	mapped bool
+	err    error
+}
+
+// stage creates a fresh step for every run, so stateful operations like Take can be run again. A step
+// reports an item it can not handle through fail.
+type stage func(fail func(err error)) step
+
+// step handles one item. It returns the new value, whether the item is kept, and whether more items
+// may be pulled after this one.
+type step func(value interface{}) (newValue interface{}, keep bool, more bool)
+
+// Lazy returns a LazyCollection which runs the chained operations on demand.
+func (c MapArrayCollection) Lazy() LazyCollection {
+	return LazyCollection{source: listSource(c.value), wrap: func(l *vector_trie.List) Collection {
+		return newMapArrayCollection(l)
+	}, err: c.err}
+}
+
+// Lazy returns a LazyCollection which runs the chained operations on demand.
+func (c NumberArrayCollection) Lazy() LazyCollection {
+	return LazyCollection{source: listSource(c.value), wrap: func(l *vector_trie.List) Collection {
+		return newNumberArrayCollection(l)
+	}, err: c.err}
+}
+
+// Lazy returns a LazyCollection which runs the chained operations on demand.
+func (c StringArrayCollection) Lazy() LazyCollection {
+	return LazyCollection{source: listSource(c.value), wrap: func(l *vector_trie.List) Collection {
+		return newStringArrayCollection(l)
+	}, err: c.err}
+}
+
+// listSource passes the items of l in order.
+func listSource(l *vector_trie.List) func(yield func(value interface{}) bool) error {
+	return func(yield func(value interface{}) bool) error {
+		l.Range(func(_ int, value interface{}) bool {
+			return yield(value)
+		})
+		return nil
+	}
+}
+
+// Err returns the error of the collection the pipeline was built from, or of an invalid argument.
+func (c LazyCollection) Err() error {
+	return c.err
+}
+
+func (c LazyCollection) then(s stage) LazyCollection {
+	var d = make([]stage, len(c.stages), len(c.stages)+1)
+	copy(d, c.stages)
+	c.stages = append(d, s)
+	return c
+}
+
+// Filter keeps only those items that pass a given truth test.
+func (c LazyCollection) Filter(cb CB) LazyCollection {
+	return c.then(func(fail func(err error)) step {
+		i := 0
+		return func(value interface{}) (interface{}, bool, bool) {
+			keep := cb(i, value)
+			i++
+			return value, keep, true
+		}
+	})
+}
+
+// Reject removes the items that pass a given truth test.
+func (c LazyCollection) Reject(cb CB) LazyCollection {
+	return c.Filter(func(item, value interface{}) bool {
+		return !cb(item, value)
+	})
+}
+
+// Where keeps the items which match the given key / value pair, or key, operator and operands.
+func (c LazyCollection) Where(key string, values ...interface{}) LazyCollection {
+	cond, err := compileWhere(key, values)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	return c.filterMap(cond)
+}
+
+// WhereGroup keeps the items which pass the conditions built by fn.
+func (c LazyCollection) WhereGroup(fn func(q *WhereQuery)) LazyCollection {
+	q, err := buildWhereQuery(fn)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	return c.filterMap(q.match)
+}
+
+// WhereIn keeps the items whose value of key is contained within the given array.
+func (c LazyCollection) WhereIn(key string, in []interface{}) LazyCollection {
+	return c.filterMap(func(value map[string]interface{}) bool {
+		item := lookupPath(value, key)
+		for _, v := range in {
+			if equalValues(item, v) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WhereNotIn keeps the items whose value of key is not contained within the given array.
+func (c LazyCollection) WhereNotIn(key string, in []interface{}) LazyCollection {
+	return c.filterMap(func(value map[string]interface{}) bool {
+		item := lookupPath(value, key)
+		for _, v := range in {
+			if equalValues(item, v) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Select keeps only the given keys of every item. The items are copied, the source is not changed.
+func (c LazyCollection) Select(keys ...string) LazyCollection {
+	return c.then(func(fail func(err error)) step {
+		return func(value interface{}) (interface{}, bool, bool) {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				fail(wrongType("map[string]interface{}", value))
+				return value, false, false
+			}
+			var d = make(map[string]interface{}, len(keys))
+			for _, k := range keys {
+				if v, ok := m[k]; ok {
+					d[k] = v
+				}
+			}
+			return d, true, true
+		}
+	})
+}
+
+// Map replaces every item with the value returned from the given callback.
+func (c LazyCollection) Map(cb func(value interface{}) interface{}) LazyCollection {
+	c = c.then(func(fail func(err error)) step {
+		return func(value interface{}) (interface{}, bool, bool) {
+			return cb(value), true, true
+		}
+	})
+	c.mapped = true
+	return c
+}
+
+// Take stops the pipeline after the specified number of items.
+func (c LazyCollection) Take(num int) LazyCollection {
+	if num < 0 {
+		c.err = ErrInvalidArgument
+		return c
+	}
+	return c.then(func(fail func(err error)) step {
+		taken := 0
+		return func(value interface{}) (interface{}, bool, bool) {
+			if taken >= num {
+				return value, false, false
+			}
+			taken++
+			return value, true, taken < num
+		}
+	})
+}
+
+// filterMap is Filter for the items which must be maps, other items end the pipeline with an error.
+func (c LazyCollection) filterMap(cb func(value map[string]interface{}) bool) LazyCollection {
+	return c.then(func(fail func(err error)) step {
+		return func(value interface{}) (interface{}, bool, bool) {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				fail(wrongType("map[string]interface{}", value))
+				return value, false, false
+			}
+			return m, cb(m), true
+		}
+	})
+}
+
+// each pulls the items through the pipeline and passes every kept item to f until f returns false.
+func (c LazyCollection) each(f func(value interface{}) bool) error {
+	if c.err != nil {
+		return c.err
+	}
+	var (
+		steps = make([]step, len(c.stages))
+		err   error
+	)
+	for i, s := range c.stages {
+		steps[i] = s(func(e error) {
+			if err == nil {
+				err = e
+			}
+		})
+	}
+	sourceErr := c.source(func(value interface{}) bool {
+		more := true
+		for _, s := range steps {
+			var keep, next bool
+			value, keep, next = s(value)
+			more = more && next
+			if !keep {
+				return more
+			}
+		}
+		return f(value) && more
+	})
+	if err == nil {
+		err = sourceErr
+	}
+	return err
+}
+
+// Collect runs the pipeline and returns the result as an eager collection. The result has the type of the
+// source unless Map has been used, then the type is decided by Collect.
+func (c LazyCollection) Collect() Collection {
+	var d = vector_trie.New().Transient()
+	if err := c.each(func(value interface{}) bool {
+		d.Append(value)
+		return true
+	}); err != nil {
+		return BaseCollection{err: err}
+	}
+	if !c.mapped {
+		return c.wrap(d.Persistent())
+	}
+	if d.Len() == 0 {
+		return BaseCollection{err: ErrWrongValue}
+	}
+	return Collect(d.Persistent().ToSlice())
+}
+
+// All runs the pipeline and returns the resulting items.
+func (c LazyCollection) All() []interface{} {
+	d, _ := c.AllE()
+	return d
+}
+
+func (c LazyCollection) AllE() ([]interface{}, error) {
+	var d = make([]interface{}, 0)
+	err := c.each(func(value interface{}) bool {
+		d = append(d, value)
+		return true
+	})
+	return d, err
+}
+
+// ToMapArray runs the pipeline and converts the result into a plain golang slice which contains map.
+func (c LazyCollection) ToMapArray() []map[string]interface{} {
+	d, _ := c.ToMapArrayE()
+	return d
+}
+
+func (c LazyCollection) ToMapArrayE() ([]map[string]interface{}, error) {
+	var (
+		d       = make([]map[string]interface{}, 0)
+		typeErr error
+	)
+	err := c.each(func(value interface{}) bool {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			typeErr = wrongType("map[string]interface{}", value)
+			return false
+		}
+		d = append(d, m)
+		return true
+	})
+	if err == nil {
+		err = typeErr
+	}
+	return d, err
+}
+
+// ToStringArray runs the pipeline and converts the result into a plain golang slice which contains string.
+func (c LazyCollection) ToStringArray() []string {
+	d, _ := c.ToStringArrayE()
+	return d
+}
+
+func (c LazyCollection) ToStringArrayE() ([]string, error) {
+	var (
+		d       = make([]string, 0)
+		typeErr error
+	)
+	err := c.each(func(value interface{}) bool {
+		s, ok := value.(string)
+		if !ok {
+			typeErr = wrongType("string", value)
+			return false
+		}
+		d = append(d, s)
+		return true
+	})
+	if err == nil {
+		err = typeErr
+	}
+	return d, err
+}
+
+// ToNumberArray runs the pipeline and converts the result into a plain golang slice which contains
+// decimal.Decimal.
+func (c LazyCollection) ToNumberArray() []decimal.Decimal {
+	d, _ := c.ToNumberArrayE()
+	return d
+}
+
+func (c LazyCollection) ToNumberArrayE() ([]decimal.Decimal, error) {
+	var (
+		d       = make([]decimal.Decimal, 0)
+		typeErr error
+	)
+	err := c.each(func(value interface{}) bool {
+		n, ok := value.(decimal.Decimal)
+		if !ok {
+			typeErr = wrongType("decimal.Decimal", value)
+			return false
+		}
+		d = append(d, n)
+		return true
+	})
+	if err == nil {
+		err = typeErr
+	}
+	return d, err
+}
+
+// First returns the first item of the result that passes a given truth test. It stops the pipeline as
+// soon as the item is found.
+func (c LazyCollection) First(cbs ...CB) interface{} {
+	d, _ := c.FirstE(cbs...)
+	return d
+}
+
+func (c LazyCollection) FirstE(cbs ...CB) (interface{}, error) {
+	var (
+		first interface{}
+		i     = 0
+	)
+	err := c.each(func(value interface{}) bool {
+		if len(cbs) == 0 || cbs[0](i, value) {
+			first = value
+			return false
+		}
+		i++
+		return true
+	})
+	return first, err
+}
+
+// Count runs the pipeline and returns the number of resulting items.
+func (c LazyCollection) Count() int {
+	d, _ := c.CountE()
+	return d
+}
+
+func (c LazyCollection) CountE() (int, error) {
+	var count = 0
+	err := c.each(func(value interface{}) bool {
+		count++
+		return true
+	})
+	return count, err
+}
+
+// Each runs the pipeline and passes every resulting item with its position to cb, until cb returns false.
+func (c LazyCollection) Each(cb CB) error {
+	i := 0
+	return c.each(func(value interface{}) bool {
+		more := cb(i, value)
+		i++
+		return more
+	})
+}
+
+// Sum runs the pipeline and returns the sum of the resulting numbers, or of the values of the given key of
+// the resulting maps.
+func (c LazyCollection) Sum(key ...string) decimal.Decimal {
+	d, _ := c.SumE(key...)
+	return d
+}
+
+func (c LazyCollection) SumE(key ...string) (decimal.Decimal, error) {
+	var sum = decimal.New(0, 0)
+	err := c.eachNumber(key, func(n decimal.Decimal) {
+		sum = sum.Add(n)
+	})
+	return sum, err
+}
+
+// Avg runs the pipeline and returns the average of the resulting numbers, or of the values of the given key
+// of the resulting maps. The average of no values is an ErrWrongValue.
+func (c LazyCollection) Avg(key ...string) decimal.Decimal {
+	d, _ := c.AvgE(key...)
+	return d
+}
+
+func (c LazyCollection) AvgE(key ...string) (decimal.Decimal, error) {
+	var (
+		sum   = decimal.New(0, 0)
+		count = 0
+	)
+	err := c.eachNumber(key, func(n decimal.Decimal) {
+		sum = sum.Add(n)
+		count++
+	})
+	if err == nil && count == 0 {
+		err = fmt.Errorf("%w: average of no values", ErrWrongValue)
+	}
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return sum.Div(nd(count)), nil
+}
+
+// Min runs the pipeline and returns the smallest of the resulting numbers, or of the values of the given key
+// of the resulting maps. The minimum of no values is an ErrWrongValue.
+func (c LazyCollection) Min(key ...string) decimal.Decimal {
+	d, _ := c.MinE(key...)
+	return d
+}
+
+func (c LazyCollection) MinE(key ...string) (decimal.Decimal, error) {
+	return c.extreme(key, -1)
+}
+
+// Max runs the pipeline and returns the largest of the resulting numbers, or of the values of the given key
+// of the resulting maps. The maximum of no values is an ErrWrongValue.
+func (c LazyCollection) Max(key ...string) decimal.Decimal {
+	d, _ := c.MaxE(key...)
+	return d
+}
+
+func (c LazyCollection) MaxE(key ...string) (decimal.Decimal, error) {
+	return c.extreme(key, 1)
+}
+
+// extreme returns the value which compares to all of the others as sign.
+func (c LazyCollection) extreme(key []string, sign int) (decimal.Decimal, error) {
+	var (
+		d     decimal.Decimal
+		found = false
+	)
+	err := c.eachNumber(key, func(n decimal.Decimal) {
+		if !found || n.Cmp(d) == sign {
+			d, found = n, true
+		}
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("%w: extreme of no values", ErrWrongValue)
+	}
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return d, nil
+}
+
+// eachNumber runs the pipeline and passes every resulting number, or value of the key of every resulting
+// map, to f. Nil values are skipped, other values which are not numbers end the pipeline with an error.
+func (c LazyCollection) eachNumber(key []string, f func(n decimal.Decimal)) error {
+	var typeErr error
+	err := c.each(func(value interface{}) bool {
+		if len(key) > 0 {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				typeErr = wrongType("map[string]interface{}", value)
+				return false
+			}
+			value = lookupPath(m, key[0])
+		}
+		if value == nil {
+			return true
+		}
+		if !isNumber(value) {
+			typeErr = wrongType("number", value)
+			return false
+		}
+		f(nd(value))
+		return true
+	})
+	if err == nil {
+		err = typeErr
+	}
+	return err
+}
@@ -2,27 +2,55 @@ package collection
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"time"
 
+	"github.com/hulklab/collection/hamt"
+	"github.com/hulklab/collection/vector_trie"
 	"github.com/mitchellh/mapstructure"
 	"github.com/shopspring/decimal"
 )
 
+// MapArrayCollection is backed by a persistent vector trie, so Push, Pop, Shift, Splice and the other
+// immutable updates share the untouched items with the original collection instead of copying them.
 type MapArrayCollection struct {
-	value []map[string]interface{}
+	value *vector_trie.List
 	BaseCollection
+	// indexes are the indexes built by Index and SortedIndex, by key.
+	indexes map[string]*rowIndex
+}
+
+func newMapArrayCollection(l *vector_trie.List) MapArrayCollection {
+	return MapArrayCollection{value: l, BaseCollection: BaseCollection{length: l.Len()}}
+}
+
+func mapList(s []map[string]interface{}) *vector_trie.List {
+	var l = vector_trie.New().Transient()
+	for _, v := range s {
+		l.Append(v)
+	}
+	return l.Persistent()
+}
+
+// items returns the collection's values as a plain golang slice.
+func (c MapArrayCollection) items() []map[string]interface{} {
+	var s = make([]map[string]interface{}, 0, c.value.Len())
+	c.value.Range(func(_ int, v interface{}) bool {
+		s = append(s, v.(map[string]interface{}))
+		return true
+	})
+	return s
 }
 
 // Sum returns the sum of all items in the collection.
 func (c MapArrayCollection) Sum(key ...string) decimal.Decimal {
+	items := c.items()
 	var sum = decimal.New(0, 0)
 
-	for i := 0; i < len(c.value); i++ {
-		sum = sum.Add(nd(c.value[i][key[0]]))
+	for i := 0; i < len(items); i++ {
+		sum = sum.Add(nd(items[i][key[0]]))
 	}
 
 	return sum
@@ -30,57 +58,48 @@ func (c MapArrayCollection) Sum(key ...string) decimal.Decimal {
 
 // Length return the length of the collection.
 func (c MapArrayCollection) Length() int {
-	return len(c.value)
+	return c.value.Len()
 }
 
 // ToStruct turn the collection to the specified struct using mapstructure.
 // https://github.com/mitchellh/mapstructure
 func (c MapArrayCollection) ToStruct(dist interface{}) {
-	if err := mapstructure.Decode(c.value, dist); err != nil {
+	items := c.items()
+	if err := mapstructure.Decode(items, dist); err != nil {
 		dist = nil
 	}
 }
 
 func (c MapArrayCollection) ToStructE(dist interface{}) error {
-	if err := mapstructure.Decode(c.value, dist); err != nil {
+	items := c.items()
+	if err := mapstructure.Decode(items, dist); err != nil {
 		dist = nil
-		c.errorHandle(err.Error())
+		c.errorHandle(err)
 	}
 	return c.err
 }
 
 // Select select the keys of collection and delete others.
 func (c MapArrayCollection) Select(keys ...string) Collection {
-	var n = make([]map[string]interface{}, len(c.value))
-	copy(n, c.value)
-
-	for _, value := range n {
-		for k := range value {
-			exist := false
-			for i := 0; i < len(keys); i++ {
-				if keys[i] == k {
-					exist = true
-					break
-				}
-			}
-			if !exist {
-				delete(value, k)
+	var n = c.items()
+
+	for i, value := range n {
+		var d = make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			if v, ok := value[k]; ok {
+				d[k] = v
 			}
 		}
+		n[i] = d
 	}
 
-	return MapArrayCollection{
-		value: n,
-	}
+	return newMapArrayCollection(mapList(n))
 }
 
 // Column select the values of collection by the given key
 func (c MapArrayCollection) Column(key string) Collection {
-	var n = make([]map[string]interface{}, len(c.value))
-	copy(n, c.value)
-
 	var a []interface{}
-	for _, value := range n {
+	for _, value := range c.items() {
 		for k, v := range value {
 			if key == k {
 				a = append(a, v)
@@ -94,35 +113,37 @@ func (c MapArrayCollection) Column(key string) Collection {
 
 // Sum returns the sum of all items in the collection.
 func (c MapArrayCollection) Avg(key ...string) decimal.Decimal {
+	items := c.items()
 	var sum = decimal.New(0, 0)
 
-	for i := 0; i < len(c.value); i++ {
-		sum = sum.Add(nd(c.value[i][key[0]]))
+	for i := 0; i < len(items); i++ {
+		sum = sum.Add(nd(items[i][key[0]]))
 	}
 
-	return sum.Div(nd(len(c.value)))
+	return sum.Div(nd(len(items)))
 }
 
 // Median returns the median value of a given key.
 func (c MapArrayCollection) Median(key ...string) decimal.Decimal {
-	var f = make([]decimal.Decimal, len(c.value))
-	for i := 0; i < len(c.value); i++ {
-		f = append(f, nd(c.value[i][key[0]]))
+	items := c.items()
+	var f = make([]decimal.Decimal, len(items))
+	for i := 0; i < len(items); i++ {
+		f = append(f, nd(items[i][key[0]]))
 	}
-	f = qsort(f, true)
+	sortDecimals(f, false)
 	return f[len(f)/2].Add(f[len(f)/2-1]).Div(nd(2))
 }
 
 // Min returns the minimum value of a given key.
 func (c MapArrayCollection) Min(key ...string) decimal.Decimal {
-
+	items := c.items()
 	var (
 		smallest = decimal.New(0, 0)
 		number   decimal.Decimal
 	)
 
-	for i := 0; i < len(c.value); i++ {
-		number = nd(c.value[i][key[0]])
+	for i := 0; i < len(items); i++ {
+		number = nd(items[i][key[0]])
 		if i == 0 {
 			smallest = number
 			continue
@@ -137,14 +158,14 @@ func (c MapArrayCollection) Min(key ...string) decimal.Decimal {
 
 // Max returns the maximum value of a given key.
 func (c MapArrayCollection) Max(key ...string) decimal.Decimal {
-
+	items := c.items()
 	var (
 		biggest = decimal.New(0, 0)
 		number  decimal.Decimal
 	)
 
-	for i := 0; i < len(c.value); i++ {
-		number = nd(c.value[i][key[0]])
+	for i := 0; i < len(items); i++ {
+		number = nd(items[i][key[0]])
 		if i == 0 {
 			biggest = number
 			continue
@@ -157,110 +178,136 @@ func (c MapArrayCollection) Max(key ...string) decimal.Decimal {
 	return biggest
 }
 
-// Pluck retrieves all of the values for a given key.
+// Pluck retrieves all of the values for a given key. The key may be a path, the values matched by a path
+// with a wildcard are flattened into the result.
 func (c MapArrayCollection) Pluck(key string) Collection {
-	var s = make([]interface{}, 0)
-	for i := 0; i < len(c.value); i++ {
-		s = append(s, c.value[i][key])
+	items := c.items()
+	var (
+		s        = make([]interface{}, 0)
+		wildcard = isWildcardPath(key)
+	)
+	for i := 0; i < len(items); i++ {
+		if wildcard {
+			s = append(s, lookupPath(items[i], key).([]interface{})...)
+		} else {
+			s = append(s, lookupPath(items[i], key))
+		}
 	}
 	return Collect(s)
 }
 
 // Each iterates over the items in the collection and passes each item to a callback.
 func (c MapArrayCollection) Each(cb func(item, value interface{}) (interface{}, bool)) Collection {
+	items := c.items()
 	var d = make([]map[string]interface{}, 0)
 	var (
 		newValue interface{}
 		stop     = false
 	)
-	for key, value := range c.value {
+	for key, value := range items {
 		if !stop {
 			newValue, stop = cb(key, value)
-			d = append(d, newValue.(map[string]interface{}))
+			m, ok := newValue.(map[string]interface{})
+			if !ok {
+				return BaseCollection{err: wrongType("map[string]interface{}", newValue)}
+			}
+			d = append(d, m)
 		} else {
 			d = append(d, value)
 		}
 	}
-	return MapArrayCollection{
-		value: d,
+	return newMapArrayCollection(mapList(d))
+}
+
+// ParallelEach is the same as Each, but runs the callbacks on at most workers goroutines and keeps the order
+// of the items. The items after the first one whose callback asks to stop keep their original value, though
+// their callbacks may have run.
+func (c MapArrayCollection) ParallelEach(workers int, cb func(item, value interface{}) (interface{}, bool)) Collection {
+	items := c.items()
+	var (
+		d    = make([]map[string]interface{}, len(items))
+		stop = make([]bool, len(items))
+	)
+	err := parallel(len(items), workers, func(i int) {
+		var newValue interface{}
+		newValue, stop[i] = cb(i, items[i])
+		d[i] = newValue.(map[string]interface{})
+	})
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	for i := range stop {
+		if stop[i] {
+			copy(d[i+1:], items[i+1:])
+			break
+		}
 	}
+	return newMapArrayCollection(mapList(d))
 }
 
 // Prepend adds an item to the beginning of the collection.
 func (c MapArrayCollection) Prepend(values ...interface{}) Collection {
+	m, ok := values[0].(map[string]interface{})
+	if !ok {
+		return BaseCollection{err: wrongType("map[string]interface{}", values[0])}
+	}
+	var d = vector_trie.New().Transient()
 
-	var d MapArrayCollection
-
-	var n = make([]map[string]interface{}, len(c.value))
-	copy(n, c.value)
-
-	d.value = append([]map[string]interface{}{values[0].(map[string]interface{})}, n...)
-	d.length = len(d.value)
+	d.Append(m)
+	c.value.Range(func(_ int, v interface{}) bool {
+		d.Append(v)
+		return true
+	})
 
-	return d
+	return newMapArrayCollection(d.Persistent())
 }
 
 // Only returns the items in the collection with the specified keys.
 func (c MapArrayCollection) Only(keys []string) Collection {
-	var d MapArrayCollection
+	items := c.items()
 
 	var ma = make([]map[string]interface{}, 0)
 	for _, k := range keys {
 		m := make(map[string]interface{}, 0)
-		for _, v := range c.value {
+		for _, v := range items {
 			m[k] = v[k]
 		}
 		ma = append(ma, m)
 	}
-	d.value = ma
-	d.length = len(ma)
 
-	return d
+	return newMapArrayCollection(mapList(ma))
 }
 
 // Splice removes and returns a slice of items starting at the specified index.
 func (c MapArrayCollection) Splice(index ...int) Collection {
 
 	if len(index) == 1 {
-		var n = make([]map[string]interface{}, len(c.value))
-		copy(n, c.value)
-		n = n[index[0]:]
-
-		return MapArrayCollection{n, BaseCollection{length: len(n)}}
+		return newMapArrayCollection(c.value.Slice(index[0], c.value.Len()))
 	} else if len(index) > 1 {
-		var n = make([]map[string]interface{}, len(c.value))
-		copy(n, c.value)
-		n = n[index[0] : index[0]+index[1]]
-
-		return MapArrayCollection{n, BaseCollection{length: len(n)}}
+		return newMapArrayCollection(c.value.Slice(index[0], index[0]+index[1]))
 	} else {
-		return BaseCollection{err: errors.New("invalid argument")}
+		return BaseCollection{err: ErrInvalidArgument}
 	}
 }
 
 // Take returns a new collection with the specified number of items.
 func (c MapArrayCollection) Take(num int) Collection {
-	var d MapArrayCollection
-	if num > c.length {
-		return BaseCollection{err: errors.New("not enough elements to take")}
+	if num > c.value.Len() {
+		return BaseCollection{err: fmt.Errorf("%w: not enough elements to take", ErrInvalidArgument)}
 	}
 
 	if num >= 0 {
-		d.value = c.value[:num]
-		d.length = num
-	} else {
-		d.value = c.value[len(c.value)+num:]
-		d.length = 0 - num
+		return newMapArrayCollection(c.value.Slice(0, num))
 	}
-
-	return d
+	return newMapArrayCollection(c.value.Slice(c.value.Len()+num, c.value.Len()))
 }
 
 // All returns the underlying array represented by the collection.
 func (c MapArrayCollection) All() []interface{} {
-	s := make([]interface{}, len(c.value))
-	for i := 0; i < len(c.value); i++ {
-		s[i] = c.value[i]
+	items := c.items()
+	s := make([]interface{}, len(items))
+	for i := 0; i < len(items); i++ {
+		s[i] = items[i]
 	}
 
 	return s
@@ -272,9 +319,10 @@ func (c MapArrayCollection) AllE() ([]interface{}, error) {
 
 // Mode returns the mode value of a given key.
 func (c MapArrayCollection) Mode(key ...string) []interface{} {
+	items := c.items()
 	valueCount := make(map[interface{}]int)
 	for i := 0; i < c.length; i++ {
-		if v, ok := c.value[i][key[0]]; ok {
+		if v, ok := items[i][key[0]]; ok {
 			valueCount[v]++
 		}
 	}
@@ -301,11 +349,11 @@ func (c MapArrayCollection) ModeE(key ...string) ([]interface{}, error) {
 
 // ToMapArray converts the collection into a plain golang slice which contains map.
 func (c MapArrayCollection) ToMapArray() []map[string]interface{} {
-	return c.value
+	return c.items()
 }
 
 func (c MapArrayCollection) ToMapArrayE() ([]map[string]interface{}, error) {
-	return c.value, c.err
+	return c.items(), c.err
 }
 
 // Chunk breaks the collection into multiple, smaller collections of a given size.
@@ -337,18 +385,24 @@ func (c MapArrayCollection) Chunk(num int) MultiDimensionalArrayCollection {
 
 // Concat appends the given array or collection values onto the end of the collection.
 func (c MapArrayCollection) Concat(value interface{}) Collection {
-	return MapArrayCollection{
-		value:          append(c.value, value.([]map[string]interface{})...),
-		BaseCollection: BaseCollection{length: c.length + len(value.([]map[string]interface{}))},
+	m, ok := value.([]map[string]interface{})
+	if !ok {
+		return BaseCollection{err: wrongType("[]map[string]interface{}", value)}
+	}
+	var d = c.value.Transient()
+	for _, v := range m {
+		d.Append(v)
 	}
+	return newMapArrayCollection(d.Persistent())
 }
 
 // CrossJoin cross joins the collection's values among the given arrays or collections, returning a Cartesian product with all possible permutations.
 func (c MapArrayCollection) CrossJoin(array ...[]interface{}) MultiDimensionalArrayCollection {
+	items := c.items()
 	var d MultiDimensionalArrayCollection
 
 	// A two-dimensional-slice's initial
-	length := len(c.value)
+	length := len(items)
 	for _, s := range array {
 		length *= len(s)
 	}
@@ -359,7 +413,7 @@ func (c MapArrayCollection) CrossJoin(array ...[]interface{}) MultiDimensionalAr
 
 	offset := length / c.length
 	for i := 0; i < length; i++ {
-		value[i][0] = c.value[i/offset]
+		value[i][0] = items[i/offset]
 	}
 	assignmentToValue(value, array, length, 1, 0, offset)
 
@@ -409,7 +463,8 @@ func (c MapArrayCollection) DumpE() error {
 
 // Every may be used to verify that all elements of a collection pass a given truth test.
 func (c MapArrayCollection) Every(cb CB) bool {
-	for key, value := range c.value {
+	items := c.items()
+	for key, value := range items {
 		if !cb(key, value) {
 			return false
 		}
@@ -423,30 +478,49 @@ func (c MapArrayCollection) EveryE(cb CB) (bool, error) {
 
 // Filter filters the collection using the given callback, keeping only those items that pass a given truth test.
 func (c MapArrayCollection) Filter(cb CB) Collection {
+	items := c.items()
 	var d = make([]map[string]interface{}, 0)
-	copy(d, c.value)
-	for key, value := range c.value {
-		if !cb(key, value) {
-			d = append(d[:key], d[key+1:]...)
+	for key, value := range items {
+		if cb(key, value) {
+			d = append(d, value)
 		}
 	}
-	return MapArrayCollection{
-		value: d,
+	return newMapArrayCollection(mapList(d))
+}
+
+// ParallelFilter is the same as Filter, but runs the callbacks on at most workers goroutines and keeps the
+// order of the items.
+func (c MapArrayCollection) ParallelFilter(workers int, cb CB) Collection {
+	items := c.items()
+	var keep = make([]bool, len(items))
+	err := parallel(len(items), workers, func(i int) {
+		keep[i] = cb(i, items[i])
+	})
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	var d = make([]map[string]interface{}, 0)
+	for i, value := range items {
+		if keep[i] {
+			d = append(d, value)
+		}
 	}
+	return newMapArrayCollection(mapList(d))
 }
 
 // First returns the first element in the collection that passes a given truth test.
 func (c MapArrayCollection) First(cbs ...CB) interface{} {
+	items := c.items()
 	if len(cbs) > 0 {
-		for key, value := range c.value {
+		for key, value := range items {
 			if cbs[0](key, value) {
 				return value
 			}
 		}
 		return nil
 	} else {
-		if len(c.value) > 0 {
-			return c.value[0]
+		if len(items) > 0 {
+			return items[0]
 		} else {
 			return nil
 		}
@@ -459,85 +533,81 @@ func (c MapArrayCollection) FirstE(cbs ...CB) (interface{}, error) {
 
 // FirstWhere returns the first element in the collection with the given key / value pair.
 func (c MapArrayCollection) FirstWhere(key string, values ...interface{}) map[string]interface{} {
-	if len(values) < 1 {
-		for _, value := range c.value {
-			if isTrue(value[key]) {
-				return value
-			}
-		}
-	} else if len(values) < 2 {
-		for _, value := range c.value {
-			if value[key] == values[0] {
-				return value
-			}
+	d, _ := c.FirstWhereE(key, values...)
+	return d
+}
+
+func (c MapArrayCollection) FirstWhereE(key string, values ...interface{}) (map[string]interface{}, error) {
+	if c.err != nil {
+		return map[string]interface{}{}, c.err
+	}
+	cond, err := compileWhere(key, values)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+	if rows, ok := c.indexLookup(key, values); ok {
+		if len(rows) == 0 {
+			return map[string]interface{}{}, nil
 		}
-	} else {
-		switch values[0].(string) {
-		case ">":
-			for _, value := range c.value {
-				if nd(value[key]).GreaterThan(nd(values[1])) {
-					return value
-				}
-			}
-		case ">=":
-			for _, value := range c.value {
-				if nd(value[key]).GreaterThanOrEqual(nd(values[1])) {
-					return value
-				}
-			}
-		case "<":
-			for _, value := range c.value {
-				if nd(value[key]).LessThan(nd(values[1])) {
-					return value
-				}
-			}
-		case "<=":
-			for _, value := range c.value {
-				if nd(value[key]).LessThanOrEqual(nd(values[1])) {
-					return value
-				}
-			}
-		case "=":
-			for _, value := range c.value {
-				if value[key] == values[1] {
-					return value
-				}
-			}
+		return rows[0], nil
+	}
+	for _, value := range c.items() {
+		if cond(value) {
+			return value, nil
 		}
 	}
-	return map[string]interface{}{}
+	return map[string]interface{}{}, nil
 }
 
-func (c MapArrayCollection) FirstWhereE(key string, values ...interface{}) (map[string]interface{}, error) {
-	return c.FirstWhere(key, values...), c.err
+// GroupBy groups the collection's items by the given keys, a key may be a path. The groups are keyed by the
+// formatted values of the keys joined with a comma, and Aggregate turns them into one row per group.
+func (c MapArrayCollection) GroupBy(keys ...string) GroupedCollection {
+	if len(keys) == 0 {
+		return GroupedCollection{MapCollection: MapCollection{BaseCollection: BaseCollection{err: ErrInvalidArgument}}}
+	}
+	return groupBy(c.items(), keys)
 }
 
-// GroupBy groups the collection's items by a given key.
-func (c MapArrayCollection) GroupBy(k string) Collection {
-	var d = make(map[string]interface{}, 0)
-	for _, value := range c.value {
-		for kk, vv := range value {
-			if kk == k {
-				vvKey := fmt.Sprintf("%v", vv)
-				if _, ok := d[vvKey]; ok {
-					am := d[vvKey].([]map[string]interface{})
-					am = append(am, value)
-					d[vvKey] = am
-				} else {
-					d[vvKey] = []map[string]interface{}{value}
-				}
-			}
+// Get returns the value at a given path such as "0.name" or "*.name". If the path does not exist, the default
+// value or nil is returned.
+func (c MapArrayCollection) Get(path string, v ...interface{}) interface{} {
+	value, ok := getPath(c.value, path)
+	if !ok && len(v) > 0 {
+		return v[0]
+	}
+	return value
+}
+
+func (c MapArrayCollection) GetE(path string, v ...interface{}) (interface{}, error) {
+	return c.Get(path, v...), c.err
+}
+
+// Set returns a new collection with the value at a given path such as "0.name" or "*.address.city" set to
+// value.
+func (c MapArrayCollection) Set(path string, value interface{}) Collection {
+	if len(splitPath(path)) == 1 {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return BaseCollection{err: wrongType("map[string]interface{}", value)}
 		}
 	}
-	return MapCollection{
-		value: d,
+	d, err := setPath(c.value, path, value)
+	if err != nil {
+		return BaseCollection{err: err}
 	}
+	return newMapArrayCollection(d.(*vector_trie.List))
+}
+
+// Forget returns a new collection without the value at a given path, a path of a single index removes the
+// item.
+func (c MapArrayCollection) Forget(path string) Collection {
+	return newMapArrayCollection(forgetPath(c.value, path).(*vector_trie.List))
 }
 
 // Implode joins the items in a collection. Its arguments depend on the type of items in the collection.
 func (c MapArrayCollection) Implode(key string, delimiter string) string {
+	items := c.items()
 	var res = ""
-	for _, value := range c.value {
+	for _, value := range items {
 		for kk, vv := range value {
 			if kk == key {
 				res += fmt.Sprintf("%v", vv) + delimiter
@@ -553,7 +623,7 @@ func (c MapArrayCollection) ImplodeE(key string, delimiter string) (string, erro
 
 // IsEmpty returns true if the collection is empty; otherwise, false is returned.
 func (c MapArrayCollection) IsEmpty() bool {
-	return len(c.value) == 0
+	return c.value.Len() == 0
 }
 
 func (c MapArrayCollection) IsEmptyE() (bool, error) {
@@ -562,7 +632,7 @@ func (c MapArrayCollection) IsEmptyE() (bool, error) {
 
 // IsNotEmpty returns true if the collection is not empty; otherwise, false is returned.
 func (c MapArrayCollection) IsNotEmpty() bool {
-	return len(c.value) != 0
+	return c.value.Len() != 0
 }
 
 func (c MapArrayCollection) IsNotEmptyE() (bool, error) {
@@ -572,9 +642,10 @@ func (c MapArrayCollection) IsNotEmptyE() (bool, error) {
 // KeyBy keys the collection by the given key. If multiple items have the same key, only the last one will
 // appear in the new collection.
 func (c MapArrayCollection) KeyBy(v interface{}) Collection {
+	items := c.items()
 	var d = make(map[string]interface{}, 0)
 	if k, ok := v.(string); ok {
-		for _, value := range c.value {
+		for _, value := range items {
 			for kk, vv := range value {
 				if kk == k {
 					d[fmt.Sprintf("%v", vv)] = []map[string]interface{}{value}
@@ -583,7 +654,7 @@ func (c MapArrayCollection) KeyBy(v interface{}) Collection {
 		}
 	} else {
 		vb := v.(FilterFun)
-		for _, value := range c.value {
+		for _, value := range items {
 			for kk, vv := range value {
 				if kk == k {
 					d[fmt.Sprintf("%v", vb(vv))] = []map[string]interface{}{value}
@@ -591,24 +662,23 @@ func (c MapArrayCollection) KeyBy(v interface{}) Collection {
 			}
 		}
 	}
-	return MapCollection{
-		value: d,
-	}
+	return newMapCollection(hamt.FromMap(d))
 }
 
 // Last returns the last element in the collection that passes a given truth test.
 func (c MapArrayCollection) Last(cbs ...CB) interface{} {
+	items := c.items()
 	if len(cbs) > 0 {
 		var last interface{}
-		for key, value := range c.value {
+		for key, value := range items {
 			if cbs[0](key, value) {
 				last = value
 			}
 		}
 		return last
 	} else {
-		if len(c.value) > 0 {
-			return c.value[len(c.value)-1]
+		if len(items) > 0 {
+			return items[len(items)-1]
 		} else {
 			return nil
 		}
@@ -621,8 +691,9 @@ func (c MapArrayCollection) LastE(cbs ...CB) (interface{}, error) {
 
 // MapToGroups groups the collection's items by the given callback.
 func (c MapArrayCollection) MapToGroups(cb MapCB) Collection {
+	items := c.items()
 	var d = make(map[string]interface{}, 0)
-	for _, value := range c.value {
+	for _, value := range items {
 		nk, nv := cb(value)
 		if _, ok := d[nk]; ok {
 			am := d[nk].([]interface{})
@@ -632,48 +703,40 @@ func (c MapArrayCollection) MapToGroups(cb MapCB) Collection {
 			d[nk] = []interface{}{nv}
 		}
 	}
-	return MapCollection{
-		value: d,
-	}
+	return newMapCollection(hamt.FromMap(d))
 }
 
 // MapWithKeys iterates through the collection and passes each value to the given callback.
 func (c MapArrayCollection) MapWithKeys(cb MapCB) Collection {
+	items := c.items()
 	var d = make(map[string]interface{}, 0)
-	for _, value := range c.value {
+	for _, value := range items {
 		nk, nv := cb(value)
 		d[nk] = nv
 	}
-	return MapCollection{
-		value: d,
-	}
+	return newMapCollection(hamt.FromMap(d))
 }
 
 // Partition separate elements that pass a given truth test from those that do not.
 func (c MapArrayCollection) Partition(cb PartCB) (Collection, Collection) {
+	items := c.items()
 	var d1 = make([]map[string]interface{}, 0)
 	var d2 = make([]map[string]interface{}, 0)
 
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if cb(i) {
-			d1 = append(d1, c.value[i])
+			d1 = append(d1, items[i])
 		} else {
-			d2 = append(d2, c.value[i])
+			d2 = append(d2, items[i])
 		}
 	}
 
-	return MapArrayCollection{
-			value: d1,
-		}, MapArrayCollection{
-			value: d2,
-		}
+	return newMapArrayCollection(mapList(d1)), newMapArrayCollection(mapList(d2))
 }
 
 // Pop removes and returns the last item from the collection.
 func (c MapArrayCollection) Pop() interface{} {
-	last := c.value[len(c.value)-1]
-	c.value = c.value[:len(c.value)-1]
-	return last
+	return c.value.Last()
 }
 
 func (c MapArrayCollection) PopE() (interface{}, error) {
@@ -682,48 +745,40 @@ func (c MapArrayCollection) PopE() (interface{}, error) {
 
 // Push appends an item to the end of the collection.
 func (c MapArrayCollection) Push(v interface{}) Collection {
-	var d = make([]map[string]interface{}, len(c.value)+1)
-	for i := 0; i < len(d); i++ {
-		if i < len(c.value) {
-			d[i] = c.value[i]
-		} else {
-			d[i] = v.(map[string]interface{})
-		}
-	}
-
-	return MapArrayCollection{
-		value: d,
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return BaseCollection{err: wrongType("map[string]interface{}", v)}
 	}
+	return newMapArrayCollection(c.value.Append(m))
 }
 
 // Random returns a random item from the collection.
 func (c MapArrayCollection) Random(num ...int) Collection {
+	items := c.items()
 	if len(num) == 0 {
 		return BaseCollection{
-			value: c.value[rand.Intn(len(c.value))],
+			value: items[rand.Intn(len(items))],
 		}
 	} else {
-		if num[0] > len(c.value) {
-			return BaseCollection{err: errors.New("wrong num")}
+		if num[0] > len(items) {
+			return BaseCollection{err: fmt.Errorf("%w: wrong num", ErrInvalidArgument)}
 		}
-		var d = make([]map[string]interface{}, len(c.value))
-		copy(d, c.value)
-		for i := 0; i < len(c.value)-num[0]; i++ {
+		var d = items
+		for i := 0; i < len(items)-num[0]; i++ {
 			index := rand.Intn(len(d))
 			d = append(d[:index], d[index+1:]...)
 		}
-		return MapArrayCollection{
-			value: d,
-		}
+		return newMapArrayCollection(mapList(d))
 	}
 }
 
 // Reduce reduces the collection to a single value, passing the result of each iteration into the subsequent iteration.
 func (c MapArrayCollection) Reduce(cb ReduceCB) interface{} {
+	items := c.items()
 	var res interface{}
 
-	for i := 0; i < len(c.value); i++ {
-		res = cb(res, c.value[i])
+	for i := 0; i < len(items); i++ {
+		res = cb(res, items[i])
 	}
 
 	return res
@@ -735,36 +790,43 @@ func (c MapArrayCollection) ReduceE(cb ReduceCB) (interface{}, error) {
 
 // Reject filters the collection using the given callback.
 func (c MapArrayCollection) Reject(cb CB) Collection {
+	items := c.items()
 	var d = make([]map[string]interface{}, 0)
-	for key, value := range c.value {
+	for key, value := range items {
 		if !cb(key, value) {
 			d = append(d, value)
 		}
 	}
-	return MapArrayCollection{
-		value: d,
-	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// ParallelReject is the same as Reject, but runs the callbacks on at most workers goroutines and keeps the
+// order of the items.
+func (c MapArrayCollection) ParallelReject(workers int, cb CB) Collection {
+	return c.ParallelFilter(workers, func(item, value interface{}) bool {
+		return !cb(item, value)
+	})
 }
 
 // Reverse reverses the order of the collection's items, preserving the original keys.
 func (c MapArrayCollection) Reverse() Collection {
-	var d = make([]map[string]interface{}, len(c.value))
+	items := c.items()
+	var d = make([]map[string]interface{}, len(items))
 	j := 0
-	for i := len(c.value) - 1; i > -1; i-- {
-		d[j] = c.value[i]
+	for i := len(items) - 1; i > -1; i-- {
+		d[j] = items[i]
 		j++
 	}
-	return MapArrayCollection{
-		value: d,
-	}
+	return newMapArrayCollection(mapList(d))
 }
 
 // Search searches the collection for the given value and returns its key if found. If the item is not found,
 // -1 is returned.
 func (c MapArrayCollection) Search(v interface{}) int {
+	items := c.items()
 	cb := v.(CB)
-	for i := 0; i < len(c.value); i++ {
-		if cb(i, c.value[i]) {
+	for i := 0; i < len(items); i++ {
+		if cb(i, items[i]) {
 			return i
 		}
 	}
@@ -777,56 +839,43 @@ func (c MapArrayCollection) SearchE(v interface{}) (int, error) {
 
 // Shift removes and returns the first item from the collection.
 func (c MapArrayCollection) Shift() Collection {
-	var d = make([]map[string]interface{}, len(c.value))
-	copy(d, c.value)
-	d = d[1:]
-	return MapArrayCollection{
-		value: d,
-	}
+	return newMapArrayCollection(c.value.Slice(1, c.value.Len()))
 }
 
 // Shuffle randomly shuffles the items in the collection.
 func (c MapArrayCollection) Shuffle() Collection {
-	var d = make([]map[string]interface{}, len(c.value))
-	copy(d, c.value)
+	var d = c.items()
 	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(c.value), func(i, j int) { d[i], d[j] = d[j], d[i] })
-	return MapArrayCollection{
-		value: d,
-	}
+	rand.Shuffle(len(d), func(i, j int) { d[i], d[j] = d[j], d[i] })
+	return newMapArrayCollection(mapList(d))
 }
 
 // Slice returns a slice of the collection starting at the given index.
 func (c MapArrayCollection) Slice(keys ...int) Collection {
-	var d = make([]map[string]interface{}, len(c.value))
-	copy(d, c.value)
 	if len(keys) == 1 {
-		return MapArrayCollection{
-			value: d[keys[0]:],
-		}
+		return newMapArrayCollection(c.value.Slice(keys[0], c.value.Len()))
 	} else {
-		return MapArrayCollection{
-			value: d[keys[0] : keys[0]+keys[1]],
-		}
+		return newMapArrayCollection(c.value.Slice(keys[0], keys[0]+keys[1]))
 	}
 }
 
 // Split breaks a collection into the given number of groups.
 func (c MapArrayCollection) Split(num int) Collection {
-	var d = make([][]interface{}, int(math.Ceil(float64(len(c.value))/float64(num))))
+	items := c.items()
+	var d = make([][]interface{}, int(math.Ceil(float64(len(items))/float64(num))))
 
 	j := -1
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if i%num == 0 {
 			j++
-			if i+num <= len(c.value) {
+			if i+num <= len(items) {
 				d[j] = make([]interface{}, num)
 			} else {
-				d[j] = make([]interface{}, len(c.value)-i)
+				d[j] = make([]interface{}, len(items)-i)
 			}
-			d[j][i%num] = c.value[i]
+			d[j][i%num] = items[i]
 		} else {
-			d[j][i%num] = c.value[i]
+			d[j][i%num] = items[i]
 		}
 	}
 
@@ -837,97 +886,66 @@ func (c MapArrayCollection) Split(num int) Collection {
 
 // WhereIn filters the collection by a given key / value contained within the given array.
 func (c MapArrayCollection) WhereIn(key string, in []interface{}) Collection {
+	if rows, ok := c.indexLookup(key, []interface{}{"in", in}); ok {
+		return newMapArrayCollection(mapList(rows))
+	}
+	items := c.items()
 	var d = make([]map[string]interface{}, 0)
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
+		value := lookupPath(items[i], key)
 		for j := 0; j < len(in); j++ {
-			if c.value[i][key] == in[j] {
-				d = append(d, copyMap(c.value[i]))
+			if equalValues(value, in[j]) {
+				d = append(d, items[i])
 				break
 			}
 		}
 	}
-	return MapArrayCollection{
-		value: d,
-	}
+	return newMapArrayCollection(mapList(d))
 }
 
 // WhereNotIn filters the collection by a given key / value not contained within the given array.
 func (c MapArrayCollection) WhereNotIn(key string, in []interface{}) Collection {
+	items := c.items()
 	var d = make([]map[string]interface{}, 0)
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		isIn := false
+		value := lookupPath(items[i], key)
 		for j := 0; j < len(in); j++ {
-			if c.value[i][key] == in[j] {
+			if equalValues(value, in[j]) {
 				isIn = true
 				break
 			}
 		}
 		if !isIn {
-			d = append(d, copyMap(c.value[i]))
+			d = append(d, items[i])
 		}
 	}
-	return MapArrayCollection{
-		value: d,
-	}
+	return newMapArrayCollection(mapList(d))
 }
 
-// Where filters the collection by a given key / value pair.
+// Where filters the collection by a given key / value pair, or by a key, an operator and its operands such
+// as Where("age", "between", 18, 30) or Where("name", "like", "j%"). An unknown operator is an error.
 func (c MapArrayCollection) Where(key string, values ...interface{}) Collection {
+	cond, err := compileWhere(key, values)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	if rows, ok := c.indexLookup(key, values); ok {
+		return newMapArrayCollection(mapList(rows))
+	}
+	items := c.items()
 	var d = make([]map[string]interface{}, 0)
-	if len(values) < 1 {
-		for _, value := range c.value {
-			if isTrue(value[key]) {
-				d = append(d, copyMap(value))
-			}
-		}
-	} else if len(values) < 2 {
-		for _, value := range c.value {
-			if value[key] == values[0] {
-				d = append(d, copyMap(value))
-			}
-		}
-	} else {
-		switch values[0].(string) {
-		case ">":
-			for _, value := range c.value {
-				if nd(value[key]).GreaterThan(nd(values[1])) {
-					d = append(d, copyMap(value))
-				}
-			}
-		case ">=":
-			for _, value := range c.value {
-				if nd(value[key]).GreaterThanOrEqual(nd(values[1])) {
-					d = append(d, copyMap(value))
-				}
-			}
-		case "<":
-			for _, value := range c.value {
-				if nd(value[key]).LessThan(nd(values[1])) {
-					d = append(d, copyMap(value))
-				}
-			}
-		case "<=":
-			for _, value := range c.value {
-				if nd(value[key]).LessThanOrEqual(nd(values[1])) {
-					d = append(d, copyMap(value))
-				}
-			}
-		case "=":
-			for _, value := range c.value {
-				if value[key] == values[1] {
-					d = append(d, copyMap(value))
-				}
-			}
+	for _, value := range items {
+		if cond(value) {
+			d = append(d, value)
 		}
 	}
-	return MapArrayCollection{
-		value: d,
-	}
+	return newMapArrayCollection(mapList(d))
 }
 
 // ToJson converts the collection into a json string.
 func (c MapArrayCollection) ToJson() string {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.items())
 	if err != nil {
 		return ""
 	}
@@ -935,10 +953,35 @@ func (c MapArrayCollection) ToJson() string {
 }
 
 func (c MapArrayCollection) ToJsonE() (string, error) {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.items())
 	if err != nil {
-		c.errorHandle(err.Error())
+		c.errorHandle(err)
 		return "", c.err
 	}
 	return string(s), c.err
 }
+
+// Merge appends the rows of the given MapArrayCollection or []map[string]interface{} to the collection.
+func (c MapArrayCollection) Merge(i interface{}) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var d = c.value.Transient()
+	switch r := i.(type) {
+	case MapArrayCollection:
+		if r.err != nil {
+			return BaseCollection{err: r.err}
+		}
+		r.value.Range(func(_ int, v interface{}) bool {
+			d = d.Append(v)
+			return true
+		})
+	case []map[string]interface{}:
+		for _, row := range r {
+			d = d.Append(row)
+		}
+	default:
+		return BaseCollection{err: wrongType("MapArrayCollection or []map[string]interface{}", i)}
+	}
+	return newMapArrayCollection(d.Persistent())
+}
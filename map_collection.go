@@ -4,41 +4,59 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/hulklab/collection/hamt"
 	"github.com/mitchellh/mapstructure"
 )
 
+// MapCollection is backed by a persistent hash array mapped trie, so every update shares the untouched
+// items with the original collection instead of copying the whole map.
 type MapCollection struct {
-	value map[string]interface{}
+	value *hamt.Map
 	BaseCollection
 }
 
+func newMapCollection(m *hamt.Map) MapCollection {
+	return MapCollection{m, BaseCollection{length: m.Len()}}
+}
+
+// Value returns the collection's items as a plain golang map.
+func (c MapCollection) Value() interface{} {
+	return c.value.ToMap()
+}
+
+// String returns the collection's items formatted like a plain golang map.
+func (c MapCollection) String() string {
+	return fmt.Sprint(c.value.ToMap())
+}
+
+// Length return the length of the collection.
+func (c MapCollection) Length() int {
+	return c.value.Len()
+}
+
 // Only returns the items in the collection with the specified keys.
 func (c MapCollection) Only(keys []string) Collection {
-	var (
-		d MapCollection
-		m = make(map[string]interface{}, 0)
-	)
+	var d = hamt.New().Transient()
 
 	for _, k := range keys {
-		m[k] = c.value[k]
+		v, _ := c.value.Get(k)
+		d.Assoc(k, v)
 	}
-	d.value = m
-	d.length = len(m)
 
-	return d
+	return newMapCollection(d.Persistent())
 }
 
 // ToStruct turn the collection to the specified struct using mapstructure.
 // https://github.com/mitchellh/mapstructure
 func (c MapCollection) ToStruct(dist interface{}) {
-	if err := mapstructure.Decode(c.value, dist); err != nil {
+	if err := mapstructure.Decode(c.value.ToMap(), dist); err != nil {
 		dist = nil
 	}
 }
 
 func (c MapCollection) ToStructE(dist interface{}) error {
-	if err := mapstructure.Decode(c.value, dist); err != nil {
-		c.errorHandle(err.Error())
+	if err := mapstructure.Decode(c.value.ToMap(), dist); err != nil {
+		c.errorHandle(err)
 		dist = nil
 	}
 	return c.err
@@ -46,65 +64,58 @@ func (c MapCollection) ToStructE(dist interface{}) error {
 
 // Select select the keys of collection and delete others.
 func (c MapCollection) Select(keys ...string) Collection {
-	n := copyMap(c.value)
-
-	for key := range n {
-		exist := false
-		for i := 0; i < len(keys); i++ {
-			if keys[i] == key {
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			delete(n, key)
+	var d = hamt.New().Transient()
+
+	for _, key := range keys {
+		if v, ok := c.value.Get(key); ok {
+			d.Assoc(key, v)
 		}
 	}
 
-	return MapCollection{
-		value: n,
-	}
+	return newMapCollection(d.Persistent())
 }
 
 // Prepend adds an item to the beginning of the collection.
 func (c MapCollection) Prepend(values ...interface{}) Collection {
-
-	var m = copyMap(c.value)
-	m[values[0].(string)] = values[1]
-
-	return MapCollection{m, BaseCollection{length: len(m)}}
+	if len(values) < 2 {
+		return BaseCollection{err: ErrInvalidArgument}
+	}
+	k, ok := values[0].(string)
+	if !ok {
+		return BaseCollection{err: wrongType("string", values[0])}
+	}
+	return newMapCollection(c.value.Assoc(k, values[1]))
 }
 
 // ToMap converts the collection into a plain golang map.
 func (c MapCollection) ToMap() map[string]interface{} {
-	return c.value
+	return c.value.ToMap()
 }
 
 func (c MapCollection) ToMapE() (map[string]interface{}, error) {
-	return c.value, c.err
+	return c.value.ToMap(), c.err
 }
 
 // Contains determines whether the collection contains a given item.
 func (c MapCollection) Contains(value ...interface{}) bool {
+	var found = false
 	if callback, ok := value[0].(CB); ok {
-		for k, v := range c.value {
-			if callback(k, v) {
-				return true
-			}
-		}
-		return false
+		c.value.Range(func(k string, v interface{}) bool {
+			found = callback(k, v)
+			return !found
+		})
+		return found
 	}
 
-	for _, v := range c.value {
-		if v == value[0] {
-			return true
-		}
-	}
-	return false
+	c.value.Range(func(_ string, v interface{}) bool {
+		found = v == value[0]
+		return !found
+	})
+	return found
 }
 
 func (c MapCollection) ContainsE(value ...interface{}) (bool, error) {
-	return c.Contains(), c.err
+	return c.Contains(value...), c.err
 }
 
 // Dd dumps the collection's items and ends execution of the script.
@@ -130,61 +141,50 @@ func (c MapCollection) DumpE() error {
 // DiffAssoc compares the collection against another collection or a plain PHP  array based on its keys and values.
 // This method will return the key / value pairs in the original collection that are not present in the given collection.
 func (c MapCollection) DiffAssoc(m map[string]interface{}) Collection {
-	var d = make(map[string]interface{}, 0)
+	var d = hamt.New().Transient()
 	for key, value := range m {
-		if v, ok := c.value[key]; ok {
+		if v, ok := c.value.Get(key); ok {
 			if v != value {
-				d[key] = value
+				d.Assoc(key, value)
 			}
 		}
 	}
-	return MapCollection{
-		value: d,
-	}
+	return newMapCollection(d.Persistent())
 }
 
 // DiffKeys compares the collection against another collection or a plain PHP array based on its keys.
 // This method will return the key / value pairs in the original collection that are not present in the given collection.
 func (c MapCollection) DiffKeys(m map[string]interface{}) Collection {
-	var d = make(map[string]interface{}, 0)
-	for key, value := range c.value {
-		if _, ok := m[key]; !ok {
-			d[key] = value
-		}
-	}
-	return MapCollection{
-		value: d,
+	var d = c.value.Transient()
+	for key := range m {
+		d.Dissoc(key)
 	}
+	return newMapCollection(d.Persistent())
 }
 
 // Each iterates over the items in the collection and passes each item to a callback.
 func (c MapCollection) Each(cb func(item, value interface{}) (interface{}, bool)) Collection {
-	var d = make(map[string]interface{}, 0)
+	var d = c.value.Transient()
 	var (
 		newValue interface{}
 		stop     = false
 	)
-	for key, value := range c.value {
-		if !stop {
-			newValue, stop = cb(key, value)
-			d[key] = newValue
-		} else {
-			d[key] = value
-		}
-	}
-	return MapCollection{
-		value: d,
-	}
+	c.value.Range(func(key string, value interface{}) bool {
+		newValue, stop = cb(key, value)
+		d.Assoc(key, newValue)
+		return !stop
+	})
+	return newMapCollection(d.Persistent())
 }
 
 // Every may be used to verify that all elements of a collection pass a given truth test.
 func (c MapCollection) Every(cb CB) bool {
-	for key, value := range c.value {
-		if !cb(key, value) {
-			return false
-		}
-	}
-	return true
+	var every = true
+	c.value.Range(func(key string, value interface{}) bool {
+		every = cb(key, value)
+		return every
+	})
+	return every
 }
 
 func (c MapCollection) EveryE(cb CB) (bool, error) {
@@ -193,64 +193,59 @@ func (c MapCollection) EveryE(cb CB) (bool, error) {
 
 // Except returns all items in the collection except for those with the specified keys.
 func (c MapCollection) Except(keys []string) Collection {
-	var d = copyMap(c.value)
+	var d = c.value.Transient()
 
 	for _, key := range keys {
-		delete(d, key)
-	}
-	return MapCollection{
-		value: d,
+		d.Dissoc(key)
 	}
+	return newMapCollection(d.Persistent())
 }
 
 // FlatMap iterates through the collection and passes each value to the given callback.
 func (c MapCollection) FlatMap(cb func(value interface{}) interface{}) Collection {
-	var d = make(map[string]interface{}, 0)
-	for key, value := range c.value {
-		d[key] = cb(value)
-	}
-	return MapCollection{
-		value: d,
-	}
+	var d = c.value.Transient()
+	c.value.Range(func(key string, value interface{}) bool {
+		d.Assoc(key, cb(value))
+		return true
+	})
+	return newMapCollection(d.Persistent())
 }
 
 // Flip swaps the collection's keys with their corresponding values.
 func (c MapCollection) Flip() Collection {
-	var d = make(map[string]interface{}, 0)
-	for key, value := range c.value {
-		d[fmt.Sprintf("%v", value)] = key
-	}
-	return MapCollection{
-		value: d,
-	}
+	var d = hamt.New().Transient()
+	c.value.Range(func(key string, value interface{}) bool {
+		d.Assoc(fmt.Sprintf("%v", value), key)
+		return true
+	})
+	return newMapCollection(d.Persistent())
 }
 
-// Forget removes an item from the collection by its key.
+// Forget removes an item from the collection by its key. The key may be a path such as "user.address.city"
+// or "items.*.price", only the maps and slices along the path are copied.
 func (c MapCollection) Forget(k string) Collection {
-	var d = copyMap(c.value)
-
-	for key := range c.value {
-		if key == k {
-			delete(d, key)
-		}
-	}
+	return newMapCollection(forgetPath(c.value, k).(*hamt.Map))
+}
 
-	return MapCollection{
-		value: d,
+// Get returns the item at a given key. If the key does not exist, null is returned. The key may be a path
+// such as "user.address.city" or "items.0.sku", a path with a wildcard such as "items.*.price" returns all
+// of the matched values in a slice.
+func (c MapCollection) Get(k string, v ...interface{}) interface{} {
+	value, ok := getPath(c.value, k)
+	if !ok && len(v) > 0 {
+		return v[0]
 	}
+	return value
 }
 
-// Get returns the item at a given key. If the key does not exist, null is returned.
-func (c MapCollection) Get(k string, v ...interface{}) interface{} {
-	if len(v) > 0 {
-		if value, ok := c.value[k]; ok {
-			return value
-		} else {
-			return v[0]
-		}
-	} else {
-		return c.value[k]
+// Set returns a new collection with the value at a given key or path set to value. Missing maps along the
+// path are created.
+func (c MapCollection) Set(k string, value interface{}) Collection {
+	d, err := setPath(c.value, k, value)
+	if err != nil {
+		return BaseCollection{err: err}
 	}
+	return newMapCollection(d.(*hamt.Map))
 }
 
 func (c MapCollection) GetE(k string, v ...interface{}) (interface{}, error) {
@@ -260,14 +255,7 @@ func (c MapCollection) GetE(k string, v ...interface{}) (interface{}, error) {
 // Has determines if a given key exists in the collection.
 func (c MapCollection) Has(keys ...string) bool {
 	for _, key := range keys {
-		exist := false
-		for kk := range c.value {
-			if key == kk {
-				exist = true
-				break
-			}
-		}
-		if !exist {
+		if !c.value.Has(key) {
 			return false
 		}
 	}
@@ -275,27 +263,23 @@ func (c MapCollection) Has(keys ...string) bool {
 }
 
 func (c MapCollection) HasE(keys ...string) (bool, error) {
-	return c.Has(), c.err
+	return c.Has(keys...), c.err
 }
 
 // IntersectByKeys removes any keys from the original collection that are not present in the given array or collection.
 func (c MapCollection) IntersectByKeys(m map[string]interface{}) Collection {
-	var d = make(map[string]interface{}, 0)
-	for key, value := range c.value {
-		for kk := range m {
-			if kk == key {
-				d[kk] = value
-			}
+	var d = hamt.New().Transient()
+	for key := range m {
+		if value, ok := c.value.Get(key); ok {
+			d.Assoc(key, value)
 		}
 	}
-	return MapCollection{
-		value: d,
-	}
+	return newMapCollection(d.Persistent())
 }
 
 // IsEmpty returns true if the collection is empty; otherwise, false is returned.
 func (c MapCollection) IsEmpty() bool {
-	return len(c.value) == 0
+	return c.value.Len() == 0
 }
 
 func (c MapCollection) IsEmptyE() (bool, error) {
@@ -304,7 +288,7 @@ func (c MapCollection) IsEmptyE() (bool, error) {
 
 // IsNotEmpty returns true if the collection is not empty; otherwise, false is returned.
 func (c MapCollection) IsNotEmpty() bool {
-	return len(c.value) != 0
+	return c.value.Len() != 0
 }
 
 func (c MapCollection) IsNotEmptyE() (bool, error) {
@@ -313,34 +297,29 @@ func (c MapCollection) IsNotEmptyE() (bool, error) {
 
 // Keys returns all of the collection's keys.
 func (c MapCollection) Keys() Collection {
-	var d = make([]string, 0)
-	for key := range c.value {
-		d = append(d, key)
-	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(c.value.Keys()))
 }
 
 // Merge merges the given array or collection with the original collection. If a string key in the given items
 // matches a string key in the original collection, the given items's value will overwrite the value in the
 // original collection.
 func (c MapCollection) Merge(i interface{}) Collection {
-	m := i.(map[string]interface{})
-	var d = copyMap(c.value)
+	m, err := mergeMap(i)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	var d = c.value.Transient()
 
 	for key, value := range m {
-		d[key] = value
+		d.Assoc(key, value)
 	}
 
-	return MapCollection{
-		value: d,
-	}
+	return newMapCollection(d.Persistent())
 }
 
 // ToJson converts the collection into a json string.
 func (c MapCollection) ToJson() string {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.value.ToMap())
 	if err != nil {
 		return ""
 	}
@@ -348,9 +327,9 @@ func (c MapCollection) ToJson() string {
 }
 
 func (c MapCollection) ToJsonE() (string, error) {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.value.ToMap())
 	if err != nil {
-		c.errorHandle(err.Error())
+		c.errorHandle(err)
 		return "", c.err
 	}
 	return string(s), c.err
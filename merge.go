@@ -0,0 +1,220 @@
+package collection
+
+import (
+	"fmt"
+
+	"github.com/hulklab/collection/hamt"
+)
+
+// MergeStrategy resolves a conflict of DeepMerge and UpsertBy, a key at path which both sides have and whose
+// values are not both maps. It returns the merged value, or an error which stops the merge. Maps are always
+// merged key by key.
+type MergeStrategy func(path string, left, right interface{}) (interface{}, error)
+
+// MergeReplace is the default MergeStrategy, the value of the right side replaces the value of the left side.
+func MergeReplace(path string, left, right interface{}) (interface{}, error) {
+	return right, nil
+}
+
+// MergeAppend appends an array of the right side to an array of the left side, and replaces other values.
+func MergeAppend(path string, left, right interface{}) (interface{}, error) {
+	if !isJSONArray(left) || !isJSONArray(right) {
+		return right, nil
+	}
+	var d = make([]interface{}, 0, jsonLen(left)+jsonLen(right))
+	return append(append(d, jsonItems(left)...), jsonItems(right)...), nil
+}
+
+// MergeUnion appends the items of an array of the right side which an array of the left side does not have,
+// and replaces other values.
+func MergeUnion(path string, left, right interface{}) (interface{}, error) {
+	if !isJSONArray(left) || !isJSONArray(right) {
+		return right, nil
+	}
+	var d = append(make([]interface{}, 0, jsonLen(left)+jsonLen(right)), jsonItems(left)...)
+	for _, v := range jsonItems(right) {
+		exist := false
+		for _, w := range d {
+			if jsonEqual(v, w) {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			d = append(d, v)
+		}
+	}
+	return d, nil
+}
+
+// MergeKeepLeft keeps the value of the left side, so the right side only adds the keys which are missing.
+func MergeKeepLeft(path string, left, right interface{}) (interface{}, error) {
+	return left, nil
+}
+
+// MergeError returns ErrWrongValue if the sides have different values.
+func MergeError(path string, left, right interface{}) (interface{}, error) {
+	if !jsonEqual(left, right) {
+		return nil, fmt.Errorf("%w: merge conflict at %s, %v and %v", ErrWrongValue, path, left, right)
+	}
+	return left, nil
+}
+
+func mergeStrategy(strategy []MergeStrategy) MergeStrategy {
+	if len(strategy) == 0 || strategy[0] == nil {
+		return MergeReplace
+	}
+	return strategy[0]
+}
+
+// deepMerge merges right into left, the maps on both sides key by key and the other values with strategy.
+func deepMerge(path string, left, right interface{}, strategy MergeStrategy) (interface{}, error) {
+	if !isJSONObject(left) || !isJSONObject(right) {
+		return strategy(path, left, right)
+	}
+	var (
+		d   = make(map[string]interface{}, jsonLen(left)+jsonLen(right))
+		err error
+	)
+	pathEach(left, func(k string, v interface{}) {
+		d[k] = v
+	})
+	pathEach(right, func(k string, v interface{}) {
+		if err != nil {
+			return
+		}
+		l, ok := d[k]
+		if !ok {
+			d[k] = v
+			return
+		}
+		p := k
+		if path != "" {
+			p = path + pathSeparator + k
+		}
+		d[k], err = deepMerge(p, l, v, strategy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeepMerge merges the given map or MapCollection into the collection. The maps which both have at a key are
+// merged recursively, and the other values which both have are merged with the strategy, MergeReplace when
+// none is given.
+func (c MapCollection) DeepMerge(other interface{}, strategy ...MergeStrategy) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	m, err := mergeMap(other)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	d, err := deepMerge("", c.value.ToMap(), m, mergeStrategy(strategy))
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	return newMapCollection(hamt.FromMap(d.(map[string]interface{})))
+}
+
+// DeepMerge merges the given map or MapCollection into every row of the collection, like
+// MapCollection.DeepMerge.
+func (c MapArrayCollection) DeepMerge(other interface{}, strategy ...MergeStrategy) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	m, err := mergeMap(other)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	var (
+		items = c.items()
+		d     = make([]map[string]interface{}, len(items))
+		s     = mergeStrategy(strategy)
+	)
+	for i, row := range items {
+		v, err := deepMerge("", row, m, s)
+		if err != nil {
+			return BaseCollection{err: fmt.Errorf("row %d: %w", i, err)}
+		}
+		d[i] = v.(map[string]interface{})
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// UpsertBy merges the given rows, a MapArrayCollection or a []map[string]interface{}, into the rows of the
+// collection which have the same values at the key columns, like DeepMerge, and appends the rows without
+// a match. A row which lacks a key column never matches.
+func (c MapArrayCollection) UpsertBy(keys []string, rows interface{}, strategy ...MergeStrategy) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var upserts []map[string]interface{}
+	switch r := rows.(type) {
+	case MapArrayCollection:
+		if r.err != nil {
+			return BaseCollection{err: r.err}
+		}
+		upserts = r.items()
+	case []map[string]interface{}:
+		upserts = r
+	default:
+		return BaseCollection{err: wrongType("MapArrayCollection or []map[string]interface{}", rows)}
+	}
+	if len(keys) == 0 {
+		return BaseCollection{err: fmt.Errorf("%w: no key column", ErrInvalidArgument)}
+	}
+
+	var (
+		d     = c.items()
+		table = make(map[string][]int, len(d))
+		s     = mergeStrategy(strategy)
+	)
+	hash := func(row map[string]interface{}) (string, bool) {
+		var key = make([]interface{}, len(keys))
+		for i, k := range keys {
+			if key[i] = lookupPath(row, k); key[i] == nil {
+				return "", false
+			}
+		}
+		return valueHash(key)
+	}
+	for i, row := range d {
+		if h, ok := hash(row); ok {
+			table[h] = append(table[h], i)
+		}
+	}
+	for _, row := range upserts {
+		h, ok := hash(row)
+		if !ok || len(table[h]) == 0 {
+			if ok {
+				table[h] = append(table[h], len(d))
+			}
+			d = append(d, row)
+			continue
+		}
+		for _, i := range table[h] {
+			v, err := deepMerge("", d[i], row, s)
+			if err != nil {
+				return BaseCollection{err: fmt.Errorf("row %d: %w", i, err)}
+			}
+			d[i] = v.(map[string]interface{})
+		}
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// mergeMap returns the map which a MapCollection or a map[string]interface{} holds.
+func mergeMap(other interface{}) (map[string]interface{}, error) {
+	switch o := other.(type) {
+	case MapCollection:
+		if o.err != nil {
+			return nil, o.err
+		}
+		return o.value.ToMap(), nil
+	case map[string]interface{}:
+		return o, nil
+	}
+	return nil, wrongType("MapCollection or map[string]interface{}", other)
+}
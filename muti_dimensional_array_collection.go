@@ -21,6 +21,9 @@ func (c MultiDimensionalArrayCollection) ToMultiDimensionalArrayE() ([][]interfa
 
 // Collapse collapses a collection of arrays into a single, flat collection.
 func (c MultiDimensionalArrayCollection) Collapse() Collection {
+	if c.err != nil {
+		return c.BaseCollection
+	}
 	if len(c.value[0]) == 0 {
 		return Collect([]interface{}{})
 	}
@@ -81,7 +84,7 @@ func (c MultiDimensionalArrayCollection) ToJson() string {
 func (c MultiDimensionalArrayCollection) ToJsonE() (string, error) {
 	s, err := json.Marshal(c.value)
 	if err != nil {
-		c.errorHandle(err.Error())
+		c.errorHandle(err)
 		return "", err
 	}
 	return string(s), c.err
@@ -0,0 +1,158 @@
+package collection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hulklab/collection/vector_trie"
+	"github.com/shopspring/decimal"
+)
+
+// StreamNDJSON returns a LazyCollection of the json objects of the newline delimited json read from r, one
+// object per line. The lines are read and decoded one at a time while the pipeline runs, so only the line
+// at hand is held in memory, and blank lines are skipped. The numbers are decoded into decimal.Decimal like
+// CollectReader does, so large ids are not rounded. A line which is not valid json ends the pipeline with an
+// ErrWrongValue, and a line which is not an object with an ErrWrongType, both carrying its line number. The
+// reader is consumed by the first run of the pipeline, a second run returns an ErrWrongValue.
+func StreamNDJSON(r io.Reader) LazyCollection {
+	reader := bufio.NewReader(r)
+	return LazyCollection{source: onceSource(func(yield func(value interface{}) bool) error {
+		for line := 1; ; line++ {
+			b, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			if b = bytes.TrimSpace(b); len(b) > 0 {
+				v, e := decodeNDJSON(b)
+				if e != nil {
+					return fmt.Errorf("%w: line %d: %v", ErrWrongValue, line, e)
+				}
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("line %d: %w", line, wrongType("json object", v))
+				}
+				if !yield(m) {
+					return nil
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+		}
+	}), wrap: func(l *vector_trie.List) Collection {
+		return newMapArrayCollection(l)
+	}}
+}
+
+// decodeNDJSON decodes a line, which must hold a single json value.
+func decodeNDJSON(b []byte) (interface{}, error) {
+	dec := newJSONDecoder(bytes.NewReader(b))
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("data after the json value")
+	}
+	return jsonNumbers(v), nil
+}
+
+// onceSource returns a source which runs source on its first call, and returns an ErrWrongValue on the
+// calls after it, as the reader source reads from has been consumed.
+func onceSource(source func(yield func(value interface{}) bool) error) func(yield func(value interface{}) bool) error {
+	var used bool
+	return func(yield func(value interface{}) bool) error {
+		if used {
+			return fmt.Errorf("%w: the stream has already been read", ErrWrongValue)
+		}
+		used = true
+		return source(yield)
+	}
+}
+
+// WriteNDJSON writes the items of the collection to w as newline delimited json, one object per line.
+func (c MapArrayCollection) WriteNDJSON(w io.Writer) error {
+	if c.err != nil {
+		return c.err
+	}
+	return writeNDJSON(w, listSource(c.value))
+}
+
+// WriteNDJSON runs the pipeline and writes the resulting items to w as newline delimited json, one item per
+// line, without holding them in memory.
+func (c LazyCollection) WriteNDJSON(w io.Writer) error {
+	return writeNDJSON(w, c.each)
+}
+
+func writeNDJSON(w io.Writer, source func(yield func(value interface{}) bool) error) error {
+	var (
+		buf     = bufio.NewWriter(w)
+		encoder = json.NewEncoder(buf)
+		line    = 0
+		err     error
+	)
+	encoder.SetEscapeHTML(false)
+	if e := source(func(value interface{}) bool {
+		line++
+		value, _ = jsonDecimals(value)
+		if err = encoder.Encode(value); err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			return false
+		}
+		return true
+	}); e != nil {
+		return e
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// jsonDecimals returns v with its decimal.Decimal values, also the nested ones, replaced with json.Number,
+// so they are written as json numbers and not as strings, and whether it replaced any. Only the maps and
+// slices which hold a decimal are copied.
+func jsonDecimals(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case decimal.Decimal:
+		return json.Number(t.String()), true
+	case map[string]interface{}:
+		var d map[string]interface{}
+		for k, value := range t {
+			n, ok := jsonDecimals(value)
+			if !ok {
+				continue
+			}
+			if d == nil {
+				d = make(map[string]interface{}, len(t))
+				for k, value := range t {
+					d[k] = value
+				}
+			}
+			d[k] = n
+		}
+		if d != nil {
+			return d, true
+		}
+	case []interface{}:
+		var d []interface{}
+		for i, value := range t {
+			n, ok := jsonDecimals(value)
+			if !ok {
+				continue
+			}
+			if d == nil {
+				d = append(make([]interface{}, 0, len(t)), t...)
+			}
+			d[i] = n
+		}
+		if d != nil {
+			return d, true
+		}
+	}
+	return v, false
+}
@@ -2,26 +2,51 @@ package collection
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"math"
 	"math/rand"
 	"time"
 
+	"github.com/hulklab/collection/vector_trie"
 	"github.com/shopspring/decimal"
 )
 
+// NumberArrayCollection is backed by a persistent vector trie, so Push, Pop, Shift, Splice and the other
+// immutable updates share the untouched items with the original collection instead of copying them.
 type NumberArrayCollection struct {
-	value []decimal.Decimal
+	value *vector_trie.List
 	BaseCollection
 }
 
+func newNumberArrayCollection(l *vector_trie.List) NumberArrayCollection {
+	return NumberArrayCollection{l, BaseCollection{length: l.Len()}}
+}
+
+func decimalList(s []decimal.Decimal) *vector_trie.List {
+	var l = vector_trie.New().Transient()
+	for _, v := range s {
+		l.Append(v)
+	}
+	return l.Persistent()
+}
+
+// items returns the collection's values as a plain golang slice.
+func (c NumberArrayCollection) items() []decimal.Decimal {
+	var s = make([]decimal.Decimal, 0, c.value.Len())
+	c.value.Range(func(_ int, v interface{}) bool {
+		s = append(s, v.(decimal.Decimal))
+		return true
+	})
+	return s
+}
+
 // Sum returns the sum of all items in the collection.
 func (c NumberArrayCollection) Sum(key ...string) decimal.Decimal {
-
+	items := c.items()
 	var sum = decimal.New(0, 0)
 
-	for i := 0; i < len(c.value); i++ {
-		sum = sum.Add(c.value[i])
+	for i := 0; i < len(items); i++ {
+		sum = sum.Add(items[i])
 	}
 
 	return sum
@@ -29,33 +54,33 @@ func (c NumberArrayCollection) Sum(key ...string) decimal.Decimal {
 
 // Length return the length of the collection.
 func (c NumberArrayCollection) Length() int {
-	return len(c.value)
+	return c.value.Len()
 }
 
 // Avg returns the average value of a given key.
 func (c NumberArrayCollection) Avg(key ...string) decimal.Decimal {
-
+	items := c.items()
 	var sum = decimal.New(0, 0)
 
-	for i := 0; i < len(c.value); i++ {
-		sum = sum.Add(c.value[i])
+	for i := 0; i < len(items); i++ {
+		sum = sum.Add(items[i])
 	}
 
-	return sum.Div(nd(len(c.value)))
+	return sum.Div(nd(len(items)))
 }
 
 // Min returns the minimum value of a given key.
 func (c NumberArrayCollection) Min(key ...string) decimal.Decimal {
-
+	items := c.items()
 	var smallest = decimal.New(0, 0)
 
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if i == 0 {
-			smallest = c.value[i]
+			smallest = items[i]
 			continue
 		}
-		if smallest.GreaterThan(c.value[i]) {
-			smallest = c.value[i]
+		if smallest.GreaterThan(items[i]) {
+			smallest = items[i]
 		}
 	}
 
@@ -64,16 +89,16 @@ func (c NumberArrayCollection) Min(key ...string) decimal.Decimal {
 
 // Max returns the maximum value of a given key.
 func (c NumberArrayCollection) Max(key ...string) decimal.Decimal {
-
+	items := c.items()
 	var biggest = decimal.New(0, 0)
 
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if i == 0 {
-			biggest = c.value[i]
+			biggest = items[i]
 			continue
 		}
-		if biggest.LessThan(c.value[i]) {
-			biggest = c.value[i]
+		if biggest.LessThan(items[i]) {
+			biggest = items[i]
 		}
 	}
 
@@ -82,60 +107,47 @@ func (c NumberArrayCollection) Max(key ...string) decimal.Decimal {
 
 // Prepend adds an item to the beginning of the collection.
 func (c NumberArrayCollection) Prepend(values ...interface{}) Collection {
-	var d NumberArrayCollection
-
-	var n = make([]decimal.Decimal, len(c.value))
-	copy(n, c.value)
+	var d = vector_trie.New().Transient()
 
-	d.value = append([]decimal.Decimal{newDecimalFromInterface(values[0])}, n...)
-	d.length = len(d.value)
+	d.Append(nd(values[0]))
+	c.value.Range(func(_ int, v interface{}) bool {
+		d.Append(v)
+		return true
+	})
 
-	return d
+	return newNumberArrayCollection(d.Persistent())
 }
 
 // Splice removes and returns a slice of items starting at the specified index.
 func (c NumberArrayCollection) Splice(index ...int) Collection {
 
 	if len(index) == 1 {
-		var n = make([]decimal.Decimal, len(c.value))
-		copy(n, c.value)
-		n = n[index[0]:]
-
-		return NumberArrayCollection{n, BaseCollection{length: len(n)}}
+		return newNumberArrayCollection(c.value.Slice(index[0], c.value.Len()))
 	} else if len(index) > 1 {
-		var n = make([]decimal.Decimal, len(c.value))
-		copy(n, c.value)
-		n = n[index[0] : index[0]+index[1]]
-
-		return NumberArrayCollection{n, BaseCollection{length: len(n)}}
+		return newNumberArrayCollection(c.value.Slice(index[0], index[0]+index[1]))
 	} else {
-		return BaseCollection{err: errors.New("invalid argument")}
+		return BaseCollection{err: ErrInvalidArgument}
 	}
 }
 
 // Take returns a new collection with the specified number of items.
 func (c NumberArrayCollection) Take(num int) Collection {
-	var d NumberArrayCollection
-	if num > c.length {
-		return BaseCollection{err: errors.New("not enough elements to take")}
+	if num > c.value.Len() {
+		return BaseCollection{err: fmt.Errorf("%w: not enough elements to take", ErrInvalidArgument)}
 	}
 
 	if num >= 0 {
-		d.value = c.value[:num]
-		d.length = num
-	} else {
-		d.value = c.value[len(c.value)+num:]
-		d.length = 0 - num
+		return newNumberArrayCollection(c.value.Slice(0, num))
 	}
-
-	return d
+	return newNumberArrayCollection(c.value.Slice(c.value.Len()+num, c.value.Len()))
 }
 
 // All returns the underlying array represented by the collection.
 func (c NumberArrayCollection) All() []interface{} {
-	s := make([]interface{}, len(c.value))
-	for i := 0; i < len(c.value); i++ {
-		s[i] = c.value[i]
+	items := c.items()
+	s := make([]interface{}, len(items))
+	for i := 0; i < len(items); i++ {
+		s[i] = items[i]
 	}
 
 	return s
@@ -170,17 +182,18 @@ func (c NumberArrayCollection) ModeE(key ...string) ([]interface{}, error) {
 
 // ToNumberArray converts the collection into a plain golang slice which contains decimal.Decimal.
 func (c NumberArrayCollection) ToNumberArray() []decimal.Decimal {
-	return c.value
+	return c.items()
 }
 
 func (c NumberArrayCollection) ToNumberArrayE() ([]decimal.Decimal, error) {
-	return c.value, c.err
+	return c.items(), c.err
 }
 
 // ToIntArray converts the collection into a plain golang slice which contains int.
 func (c NumberArrayCollection) ToIntArray() []int {
-	var v = make([]int, len(c.value))
-	for i, value := range c.value {
+	items := c.items()
+	var v = make([]int, len(items))
+	for i, value := range items {
 		v[i] = int(value.IntPart())
 	}
 	return v
@@ -192,8 +205,9 @@ func (c NumberArrayCollection) ToIntArrayE() ([]int, error) {
 
 // ToInt64Array converts the collection into a plain golang slice which contains int64.
 func (c NumberArrayCollection) ToInt64Array() []int64 {
-	var v = make([]int64, len(c.value))
-	for i, value := range c.value {
+	items := c.items()
+	var v = make([]int64, len(items))
+	for i, value := range items {
 		v[i] = value.IntPart()
 	}
 	return v
@@ -232,16 +246,22 @@ func (c NumberArrayCollection) Chunk(num int) MultiDimensionalArrayCollection {
 
 // Concat appends the given array or collection values onto the end of the collection.
 func (c NumberArrayCollection) Concat(value interface{}) Collection {
-	return NumberArrayCollection{
-		value:          append(c.value, value.([]decimal.Decimal)...),
-		BaseCollection: BaseCollection{length: c.length + len(value.([]decimal.Decimal))},
+	n, ok := value.([]decimal.Decimal)
+	if !ok {
+		return BaseCollection{err: wrongType("[]decimal.Decimal", value)}
 	}
+	var d = c.value.Transient()
+	for _, v := range n {
+		d.Append(v)
+	}
+	return newNumberArrayCollection(d.Persistent())
 }
 
 // Contains determines whether the collection contains a given item.
 func (c NumberArrayCollection) Contains(value ...interface{}) bool {
+	items := c.items()
 	if callback, ok := value[0].(CB); ok {
-		for k, v := range c.value {
+		for k, v := range items {
 			if callback(k, v) {
 				return true
 			}
@@ -249,7 +269,7 @@ func (c NumberArrayCollection) Contains(value ...interface{}) bool {
 		return false
 	}
 
-	for _, v := range c.value {
+	for _, v := range items {
 		if v.Equal(nd(value[0])) {
 			return true
 		}
@@ -263,16 +283,17 @@ func (c NumberArrayCollection) ContainsE(value ...interface{}) (bool, error) {
 
 // CountBy counts the occurrences of values in the collection. By default, the method counts the occurrences of every element.
 func (c NumberArrayCollection) CountBy(callback ...interface{}) map[interface{}]int {
+	items := c.items()
 	valueCount := make(map[interface{}]int)
 
 	if len(callback) > 0 {
 		if cb, ok := callback[0].(FilterFun); ok {
-			for _, v := range c.value {
+			for _, v := range items {
 				valueCount[cb(v)]++
 			}
 		}
 	} else {
-		for _, v := range c.value {
+		for _, v := range items {
 			vv, _ := v.Float64()
 			valueCount[vv]++
 		}
@@ -287,10 +308,11 @@ func (c NumberArrayCollection) CountByE(callback ...interface{}) (map[interface{
 
 // CrossJoin cross joins the collection's values among the given arrays or collections, returning a Cartesian product with all possible permutations.
 func (c NumberArrayCollection) CrossJoin(array ...[]interface{}) MultiDimensionalArrayCollection {
+	items := c.items()
 	var d MultiDimensionalArrayCollection
 
 	// A two-dimensional-slice's initial
-	length := len(c.value)
+	length := len(items)
 	for _, s := range array {
 		length *= len(s)
 	}
@@ -301,7 +323,7 @@ func (c NumberArrayCollection) CrossJoin(array ...[]interface{}) MultiDimensiona
 
 	offset := length / c.length
 	for i := 0; i < length; i++ {
-		value[i][0] = c.value[i/offset]
+		value[i][0] = items[i/offset]
 	}
 	assignmentToValue(value, array, length, 1, 0, offset)
 
@@ -333,9 +355,10 @@ func (c NumberArrayCollection) DumpE() error {
 // Diff compares the collection against another collection or a plain PHP array based on its values.
 // This method will return the values in the original collection that are not present in the given collection.
 func (c NumberArrayCollection) Diff(m interface{}) Collection {
+	items := c.items()
 	ms := newDecimalArray(m)
 	var d = make([]decimal.Decimal, 0)
-	for _, value := range c.value {
+	for _, value := range items {
 		exist := false
 		for i := 0; i < len(ms); i++ {
 			if ms[i].Equal(value) {
@@ -347,19 +370,18 @@ func (c NumberArrayCollection) Diff(m interface{}) Collection {
 			d = append(d, value)
 		}
 	}
-	return NumberArrayCollection{
-		value: d,
-	}
+	return newNumberArrayCollection(decimalList(d))
 }
 
 // Each iterates over the items in the collection and passes each item to a callback.
 func (c NumberArrayCollection) Each(cb func(item, value interface{}) (interface{}, bool)) Collection {
+	items := c.items()
 	var d = make([]decimal.Decimal, 0)
 	var (
 		newValue interface{}
 		stop     = false
 	)
-	for key, value := range c.value {
+	for key, value := range items {
 		if !stop {
 			newValue, stop = cb(key, value)
 			d = append(d, newDecimalFromInterface(newValue))
@@ -367,14 +389,39 @@ func (c NumberArrayCollection) Each(cb func(item, value interface{}) (interface{
 			d = append(d, value)
 		}
 	}
-	return NumberArrayCollection{
-		value: d,
+	return newNumberArrayCollection(decimalList(d))
+}
+
+// ParallelEach is the same as Each, but runs the callbacks on at most workers goroutines and keeps the order
+// of the items. The items after the first one whose callback asks to stop keep their original value, though
+// their callbacks may have run.
+func (c NumberArrayCollection) ParallelEach(workers int, cb func(item, value interface{}) (interface{}, bool)) Collection {
+	items := c.items()
+	var (
+		d    = make([]decimal.Decimal, len(items))
+		stop = make([]bool, len(items))
+	)
+	err := parallel(len(items), workers, func(i int) {
+		var newValue interface{}
+		newValue, stop[i] = cb(i, items[i])
+		d[i] = newDecimalFromInterface(newValue)
+	})
+	if err != nil {
+		return BaseCollection{err: err}
 	}
+	for i := range stop {
+		if stop[i] {
+			copy(d[i+1:], items[i+1:])
+			break
+		}
+	}
+	return newNumberArrayCollection(decimalList(d))
 }
 
 // Every may be used to verify that all elements of a collection pass a given truth test.
 func (c NumberArrayCollection) Every(cb CB) bool {
-	for key, value := range c.value {
+	items := c.items()
+	for key, value := range items {
 		if !cb(key, value) {
 			return false
 		}
@@ -388,29 +435,49 @@ func (c NumberArrayCollection) EveryE(cb CB) (bool, error) {
 
 // Filter filters the collection using the given callback, keeping only those items that pass a given truth test.
 func (c NumberArrayCollection) Filter(cb CB) Collection {
+	items := c.items()
 	var d = make([]decimal.Decimal, 0)
-	for key, value := range c.value {
+	for key, value := range items {
 		if cb(key, value) {
 			d = append(d, value)
 		}
 	}
-	return NumberArrayCollection{
-		value: d,
+	return newNumberArrayCollection(decimalList(d))
+}
+
+// ParallelFilter is the same as Filter, but runs the callbacks on at most workers goroutines and keeps the
+// order of the items.
+func (c NumberArrayCollection) ParallelFilter(workers int, cb CB) Collection {
+	items := c.items()
+	var keep = make([]bool, len(items))
+	err := parallel(len(items), workers, func(i int) {
+		keep[i] = cb(i, items[i])
+	})
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	var d = make([]decimal.Decimal, 0)
+	for i, value := range items {
+		if keep[i] {
+			d = append(d, value)
+		}
 	}
+	return newNumberArrayCollection(decimalList(d))
 }
 
 // First returns the first element in the collection that passes a given truth test.
 func (c NumberArrayCollection) First(cbs ...CB) interface{} {
+	items := c.items()
 	if len(cbs) > 0 {
-		for key, value := range c.value {
+		for key, value := range items {
 			if cbs[0](key, value) {
 				return value
 			}
 		}
 		return nil
 	} else {
-		if len(c.value) > 0 {
-			return c.value[0]
+		if len(items) > 0 {
+			return items[0]
 		} else {
 			return nil
 		}
@@ -423,27 +490,20 @@ func (c NumberArrayCollection) FirstE(cbs ...CB) (interface{}, error) {
 
 // ForPage returns a new collection containing the items that would be present on a given page number.
 func (c NumberArrayCollection) ForPage(page, size int) Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
-	if size > len(d) || size*(page-1) > len(d) {
-		return NumberArrayCollection{
-			value: d,
-		}
+	var length = c.value.Len()
+	if size > length || size*(page-1) > length {
+		return c
 	}
-	if (page+1)*size > len(d) {
-		return NumberArrayCollection{
-			value: d[(page-1)*size:],
-		}
+	if (page+1)*size > length {
+		return newNumberArrayCollection(c.value.Slice((page-1)*size, length))
 	} else {
-		return NumberArrayCollection{
-			value: d[(page-1)*size : (page)*size],
-		}
+		return newNumberArrayCollection(c.value.Slice((page-1)*size, page*size))
 	}
 }
 
 // IsEmpty returns true if the collection is empty; otherwise, false is returned.
 func (c NumberArrayCollection) IsEmpty() bool {
-	return len(c.value) == 0
+	return c.value.Len() == 0
 }
 
 func (c NumberArrayCollection) IsEmptyE() (bool, error) {
@@ -452,7 +512,7 @@ func (c NumberArrayCollection) IsEmptyE() (bool, error) {
 
 // IsNotEmpty returns true if the collection is not empty; otherwise, false is returned.
 func (c NumberArrayCollection) IsNotEmpty() bool {
-	return len(c.value) != 0
+	return c.value.Len() != 0
 }
 
 func (c NumberArrayCollection) IsNotEmptyE() (bool, error) {
@@ -461,17 +521,18 @@ func (c NumberArrayCollection) IsNotEmptyE() (bool, error) {
 
 // Last returns the last element in the collection that passes a given truth test.
 func (c NumberArrayCollection) Last(cbs ...CB) interface{} {
+	items := c.items()
 	if len(cbs) > 0 {
 		var last interface{}
-		for key, value := range c.value {
+		for key, value := range items {
 			if cbs[0](key, value) {
 				last = value
 			}
 		}
 		return last
 	} else {
-		if len(c.value) > 0 {
-			return c.value[len(c.value)-1]
+		if len(items) > 0 {
+			return items[len(items)-1]
 		} else {
 			return nil
 		}
@@ -484,15 +545,13 @@ func (c NumberArrayCollection) LastE(cbs ...CB) (interface{}, error) {
 
 // Median returns the median value of a given key.
 func (c NumberArrayCollection) Median(key ...string) decimal.Decimal {
-
-	if len(c.value) < 2 {
-		return c.value[0]
+	items := c.items()
+	if len(items) < 2 {
+		return items[0]
 	}
 
-	var f = make([]decimal.Decimal, len(c.value))
-	copy(f, c.value)
-	f = qsort(f, true)
-	return f[len(f)/2].Add(f[len(f)/2-1]).Div(nd(2))
+	sortDecimals(items, false)
+	return items[len(items)/2].Add(items[len(items)/2-1]).Div(nd(2))
 }
 
 // Merge merges the given array or collection with the original collection. If a string key in the given items
@@ -500,76 +559,61 @@ func (c NumberArrayCollection) Median(key ...string) decimal.Decimal {
 // original collection.
 func (c NumberArrayCollection) Merge(i interface{}) Collection {
 	m := newDecimalArray(i)
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
+	var d = c.items()
 	d = append(d, m...)
 
-	return NumberArrayCollection{
-		value: d,
-	}
+	return newNumberArrayCollection(decimalList(d))
 }
 
 // Pad will fill the array with the given value until the array reaches the specified size.
 func (c NumberArrayCollection) Pad(num int, value interface{}) Collection {
-	if len(c.value) > num {
-		d := make([]decimal.Decimal, len(c.value))
-		copy(d, c.value)
-		return NumberArrayCollection{
-			value: d,
-		}
+	items := c.items()
+	if len(items) > num {
+		return c
 	}
 	if num > 0 {
 		d := make([]decimal.Decimal, num)
 		for i := 0; i < num; i++ {
-			if i < len(c.value) {
-				d[i] = c.value[i]
+			if i < len(items) {
+				d[i] = items[i]
 			} else {
 				d[i] = nd(value)
 			}
 		}
-		return NumberArrayCollection{
-			value: d,
-		}
+		return newNumberArrayCollection(decimalList(d))
 	} else {
 		d := make([]decimal.Decimal, -num)
 		for i := 0; i < -num; i++ {
-			if i < -num-len(c.value) {
+			if i < -num-len(items) {
 				d[i] = nd(value)
 			} else {
-				d[i] = c.value[i]
+				d[i] = items[i]
 			}
 		}
-		return NumberArrayCollection{
-			value: d,
-		}
+		return newNumberArrayCollection(decimalList(d))
 	}
 }
 
 // Partition separate elements that pass a given truth test from those that do not.
 func (c NumberArrayCollection) Partition(cb PartCB) (Collection, Collection) {
+	items := c.items()
 	var d1 = make([]decimal.Decimal, 0)
 	var d2 = make([]decimal.Decimal, 0)
 
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if cb(i) {
-			d1 = append(d1, c.value[i])
+			d1 = append(d1, items[i])
 		} else {
-			d2 = append(d2, c.value[i])
+			d2 = append(d2, items[i])
 		}
 	}
 
-	return NumberArrayCollection{
-			value: d1,
-		}, NumberArrayCollection{
-			value: d2,
-		}
+	return newNumberArrayCollection(decimalList(d1)), newNumberArrayCollection(decimalList(d2))
 }
 
 // Pop removes and returns the last item from the collection.
 func (c NumberArrayCollection) Pop() interface{} {
-	last := c.value[len(c.value)-1]
-	c.value = c.value[:len(c.value)-1]
-	return last
+	return c.value.Last()
 }
 
 func (c NumberArrayCollection) PopE() (interface{}, error) {
@@ -578,48 +622,36 @@ func (c NumberArrayCollection) PopE() (interface{}, error) {
 
 // Push appends an item to the end of the collection.
 func (c NumberArrayCollection) Push(v interface{}) Collection {
-	var d = make([]decimal.Decimal, len(c.value)+1)
-	for i := 0; i < len(d); i++ {
-		if i < len(c.value) {
-			d[i] = c.value[i]
-		} else {
-			d[i] = nd(v)
-		}
-	}
-
-	return NumberArrayCollection{
-		value: d,
-	}
+	return newNumberArrayCollection(c.value.Append(nd(v)))
 }
 
 // Random returns a random item from the collection.
 func (c NumberArrayCollection) Random(num ...int) Collection {
+	items := c.items()
 	if len(num) == 0 {
 		return BaseCollection{
-			value: c.value[rand.Intn(len(c.value))],
+			value: items[rand.Intn(len(items))],
 		}
 	} else {
-		if num[0] > len(c.value) {
-			return BaseCollection{err: errors.New("wrong num")}
+		if num[0] > len(items) {
+			return BaseCollection{err: fmt.Errorf("%w: wrong num", ErrInvalidArgument)}
 		}
-		var d = make([]decimal.Decimal, len(c.value))
-		copy(d, c.value)
-		for i := 0; i < len(c.value)-num[0]; i++ {
+		var d = items
+		for i := 0; i < len(items)-num[0]; i++ {
 			index := rand.Intn(len(d))
 			d = append(d[:index], d[index+1:]...)
 		}
-		return NumberArrayCollection{
-			value: d,
-		}
+		return newNumberArrayCollection(decimalList(d))
 	}
 }
 
 // Reduce reduces the collection to a single value, passing the result of each iteration into the subsequent iteration.
 func (c NumberArrayCollection) Reduce(cb ReduceCB) interface{} {
+	items := c.items()
 	var res interface{}
 
-	for i := 0; i < len(c.value); i++ {
-		res = cb(res, c.value[i])
+	for i := 0; i < len(items); i++ {
+		res = cb(res, items[i])
 	}
 
 	return res
@@ -630,43 +662,50 @@ func (c NumberArrayCollection) ReduceE(cb ReduceCB) (interface{}, error) {
 
 // Reject filters the collection using the given callback.
 func (c NumberArrayCollection) Reject(cb CB) Collection {
+	items := c.items()
 	var d = make([]decimal.Decimal, 0)
-	for key, value := range c.value {
+	for key, value := range items {
 		if !cb(key, value) {
 			d = append(d, value)
 		}
 	}
-	return NumberArrayCollection{
-		value: d,
-	}
+	return newNumberArrayCollection(decimalList(d))
+}
+
+// ParallelReject is the same as Reject, but runs the callbacks on at most workers goroutines and keeps the
+// order of the items.
+func (c NumberArrayCollection) ParallelReject(workers int, cb CB) Collection {
+	return c.ParallelFilter(workers, func(item, value interface{}) bool {
+		return !cb(item, value)
+	})
 }
 
 // Reverse reverses the order of the collection's items, preserving the original keys.
 func (c NumberArrayCollection) Reverse() Collection {
-	var d = make([]decimal.Decimal, len(c.value))
+	items := c.items()
+	var d = make([]decimal.Decimal, len(items))
 	j := 0
-	for i := len(c.value) - 1; i > -1; i-- {
-		d[j] = c.value[i]
+	for i := len(items) - 1; i > -1; i-- {
+		d[j] = items[i]
 		j++
 	}
-	return NumberArrayCollection{
-		value: d,
-	}
+	return newNumberArrayCollection(decimalList(d))
 }
 
 // Search searches the collection for the given value and returns its key if found. If the item is not found,
 // -1 is returned.
 func (c NumberArrayCollection) Search(v interface{}) int {
+	items := c.items()
 	if cb, ok := v.(CB); ok {
-		for i := 0; i < len(c.value); i++ {
-			if cb(i, c.value[i]) {
+		for i := 0; i < len(items); i++ {
+			if cb(i, items[i]) {
 				return i
 			}
 		}
 	} else {
 		n := nd(v)
-		for i := 0; i < len(c.value); i++ {
-			if n.Equal(c.value[i]) {
+		for i := 0; i < len(items); i++ {
+			if n.Equal(items[i]) {
 				return i
 			}
 		}
@@ -680,76 +719,43 @@ func (c NumberArrayCollection) SearchE(v interface{}) (int, error) {
 
 // Shift removes and returns the first item from the collection.
 func (c NumberArrayCollection) Shift() Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
-	d = d[1:]
-	return NumberArrayCollection{
-		value: d,
-	}
+	return newNumberArrayCollection(c.value.Slice(1, c.value.Len()))
 }
 
 // Shuffle randomly shuffles the items in the collection.
 func (c NumberArrayCollection) Shuffle() Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
+	var d = c.items()
 	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(c.value), func(i, j int) { d[i], d[j] = d[j], d[i] })
-	return NumberArrayCollection{
-		value: d,
-	}
+	rand.Shuffle(len(d), func(i, j int) { d[i], d[j] = d[j], d[i] })
+	return newNumberArrayCollection(decimalList(d))
 }
 
 // Slice returns a slice of the collection starting at the given index.
 func (c NumberArrayCollection) Slice(keys ...int) Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
 	if len(keys) == 1 {
-		return NumberArrayCollection{
-			value: d[keys[0]:],
-		}
+		return newNumberArrayCollection(c.value.Slice(keys[0], c.value.Len()))
 	} else {
-		return NumberArrayCollection{
-			value: d[keys[0] : keys[0]+keys[1]],
-		}
-	}
-}
-
-// Sort sorts the collection.
-func (c NumberArrayCollection) Sort() Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
-	d = qsort(d, true)
-	return NumberArrayCollection{
-		value: d,
-	}
-}
-
-// SortByDesc has the same signature as the sortBy method, but will sort the collection in the opposite order.
-func (c NumberArrayCollection) SortByDesc() Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
-	d = qsort(d, false)
-	return NumberArrayCollection{
-		value: d,
+		return newNumberArrayCollection(c.value.Slice(keys[0], keys[0]+keys[1]))
 	}
 }
 
 // Split breaks a collection into the given number of groups.
 func (c NumberArrayCollection) Split(num int) Collection {
-	var d = make([][]interface{}, int(math.Ceil(float64(len(c.value))/float64(num))))
+	items := c.items()
+	var d = make([][]interface{}, int(math.Ceil(float64(len(items))/float64(num))))
 
 	j := -1
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if i%num == 0 {
 			j++
-			if i+num <= len(c.value) {
+			if i+num <= len(items) {
 				d[j] = make([]interface{}, num)
 			} else {
-				d[j] = make([]interface{}, len(c.value)-i)
+				d[j] = make([]interface{}, len(items)-i)
 			}
-			d[j][i%num] = c.value[i]
+			d[j][i%num] = items[i]
 		} else {
-			d[j][i%num] = c.value[i]
+			d[j][i%num] = items[i]
 		}
 	}
 
@@ -760,8 +766,7 @@ func (c NumberArrayCollection) Split(num int) Collection {
 
 // Unique returns all of the unique items in the collection.
 func (c NumberArrayCollection) Unique() Collection {
-	var d = make([]decimal.Decimal, len(c.value))
-	copy(d, c.value)
+	var d = c.items()
 	x := make([]decimal.Decimal, 0)
 	for _, i := range d {
 		if len(x) == 0 {
@@ -777,14 +782,12 @@ func (c NumberArrayCollection) Unique() Collection {
 			}
 		}
 	}
-	return NumberArrayCollection{
-		value: x,
-	}
+	return newNumberArrayCollection(decimalList(x))
 }
 
 // ToJson converts the collection into a json string.
 func (c NumberArrayCollection) ToJson() string {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.items())
 	if err != nil {
 		return ""
 	}
@@ -792,10 +795,10 @@ func (c NumberArrayCollection) ToJson() string {
 }
 
 func (c NumberArrayCollection) ToJsonE() (string, error) {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.items())
 	if err != nil {
-		c.errorHandle(err.Error())
-		return "", err
+		c.errorHandle(err)
+		return "", c.err
 	}
 	return string(s), c.err
 }
@@ -0,0 +1,55 @@
+package collection
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallel calls fn for every index in [0, n) on at most workers goroutines, a workers less than 1 means
+// runtime.GOMAXPROCS(0). A panic inside fn is recovered and returned as an ErrPanic, the indexes which have
+// not been started yet are skipped then.
+func parallel(n, workers int, fn func(i int)) error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		next   int64 = -1
+		failed int32
+		once   sync.Once
+		err    error
+		wg     sync.WaitGroup
+	)
+
+	work := func() {
+		defer wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				atomic.StoreInt32(&failed, 1)
+				once.Do(func() {
+					err = fmt.Errorf("%w in parallel callback: %v", ErrPanic, r)
+				})
+			}
+		}()
+		for atomic.LoadInt32(&failed) == 0 {
+			i := int(atomic.AddInt64(&next, 1))
+			if i >= n {
+				return
+			}
+			fn(i)
+		}
+	}
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go work()
+	}
+	wg.Wait()
+
+	return err
+}
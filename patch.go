@@ -0,0 +1,526 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hulklab/collection/hamt"
+)
+
+// The operations of a JSON Patch.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// PatchOperation is an operation of an RFC 6902 JSON Patch. Path and From are RFC 6901 JSON Pointers, such as
+// "/servers/0/host". Value is the value of add, replace and test, and From the source of move and copy.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Patch is an RFC 6902 JSON Patch. It is marshalled to and unmarshalled from the json of the RFC.
+type Patch []PatchOperation
+
+// MarshalJSON writes the value of add, replace and test even when it is null, and no value for the others.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	var m = map[string]interface{}{"op": o.Op, "path": o.Path}
+	switch o.Op {
+	case PatchAdd, PatchReplace, PatchTest:
+		m["value"] = o.Value
+	case PatchMove, PatchCopy:
+		m["from"] = o.From
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON reads an operation, and returns an error if a member which the operation requires is missing.
+func (o *PatchOperation) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*o = PatchOperation{}
+	for _, member := range []struct {
+		name  string
+		value *string
+	}{{"op", &o.Op}, {"path", &o.Path}, {"from", &o.From}} {
+		if raw, ok := m[member.name]; ok {
+			if err := json.Unmarshal(raw, member.value); err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := m["path"]; !ok {
+		return fmt.Errorf("%w: patch operation without path", ErrInvalidArgument)
+	}
+	switch o.Op {
+	case PatchAdd, PatchReplace, PatchTest:
+		raw, ok := m["value"]
+		if !ok {
+			return fmt.Errorf("%w: %s operation without value", ErrInvalidArgument, o.Op)
+		}
+		return json.Unmarshal(raw, &o.Value)
+	case PatchMove, PatchCopy:
+		if _, ok := m["from"]; !ok {
+			return fmt.Errorf("%w: %s operation without from", ErrInvalidArgument, o.Op)
+		}
+	case PatchRemove:
+	default:
+		return fmt.Errorf("%w: unknown patch operation %q", ErrInvalidArgument, o.Op)
+	}
+	return nil
+}
+
+// DiffPatch returns the JSON Patch which turns the collection into other, a MapCollection or a
+// map[string]interface{}. Nested maps are compared member by member. An array is diffed item by item, an item
+// which only changed its position is moved, and an item which changed in place is diffed in turn.
+func (c MapCollection) DiffPatch(other interface{}) Patch {
+	p, _ := c.DiffPatchE(other)
+	return p
+}
+
+func (c MapCollection) DiffPatchE(other interface{}) (Patch, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var target map[string]interface{}
+	switch o := other.(type) {
+	case MapCollection:
+		if o.err != nil {
+			return nil, o.err
+		}
+		target = o.value.ToMap()
+	case map[string]interface{}:
+		target = o
+	default:
+		c.errorHandle(wrongType("MapCollection or map[string]interface{}", other))
+		return nil, c.err
+	}
+	var d = make(Patch, 0)
+	diffPatch(&d, "", c.value.ToMap(), target)
+	return d, nil
+}
+
+// ApplyPatch returns the collection with the operations of the JSON Patch applied in order. The patch is
+// applied as a whole or not at all, an operation which fails returns ErrInvalidArgument if it is malformed
+// and ErrWrongValue if it does not apply to the document, such as a path which does not exist or a failed test.
+func (c MapCollection) ApplyPatch(patch Patch) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var doc interface{} = c.value.ToMap()
+	for i, o := range patch {
+		var err error
+		if doc, err = applyPatchOperation(doc, o); err != nil {
+			return BaseCollection{err: fmt.Errorf("patch operation %d (%s %s): %w", i, o.Op, o.Path, err)}
+		}
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return BaseCollection{err: wrongType("map document", doc)}
+	}
+	return newMapCollection(hamt.FromMap(m))
+}
+
+// MergePatch returns the collection with the RFC 7396 JSON Merge Patch applied. The patch is a MapCollection
+// or a map[string]interface{}, a null member removes the member, a map is merged into the member recursively,
+// and any other value replaces the member.
+func (c MapCollection) MergePatch(patch interface{}) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var m map[string]interface{}
+	switch p := patch.(type) {
+	case MapCollection:
+		if p.err != nil {
+			return BaseCollection{err: p.err}
+		}
+		m = p.value.ToMap()
+	case map[string]interface{}:
+		m = p
+	default:
+		return BaseCollection{err: wrongType("MapCollection or map[string]interface{}", patch)}
+	}
+	return newMapCollection(hamt.FromMap(mergePatch(c.value.ToMap(), m).(map[string]interface{})))
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, _ := target.(map[string]interface{})
+	var d = make(map[string]interface{}, len(t)+len(p))
+	for k, v := range t {
+		d[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+		} else {
+			d[k] = mergePatch(d[k], v)
+		}
+	}
+	return d
+}
+
+// escapePointer escapes a member name as a token of a JSON Pointer.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// diffPatch appends the operations which turn a into b at path.
+func diffPatch(d *Patch, path string, a, b interface{}) {
+	switch {
+	case jsonEqual(a, b):
+	case isJSONObject(a) && isJSONObject(b):
+		var keys = make([]string, 0)
+		pathEach(a, func(k string, value interface{}) {
+			if other, ok := pathChild(b, k); ok {
+				diffPatch(d, path+"/"+escapePointer(k), value, other)
+			} else {
+				keys = append(keys, k)
+			}
+		})
+		for _, k := range keys {
+			*d = append(*d, PatchOperation{Op: PatchRemove, Path: path + "/" + escapePointer(k)})
+		}
+		pathEach(b, func(k string, value interface{}) {
+			if _, ok := pathChild(a, k); !ok {
+				*d = append(*d, PatchOperation{Op: PatchAdd, Path: path + "/" + escapePointer(k), Value: value})
+			}
+		})
+	case isJSONArray(a) && isJSONArray(b):
+		diffArray(d, path, jsonItems(a), jsonItems(b))
+	default:
+		*d = append(*d, PatchOperation{Op: PatchReplace, Path: path, Value: b})
+	}
+}
+
+// diffArray appends the operations which turn the array a into b. The longest common subsequence of the
+// items stays in place. Every other item of a which equals an item of b is moved there, the items left
+// between two kept items are diffed in pairs, and the rest of them are removed from a or added from b.
+func diffArray(d *Patch, path string, a, b []interface{}) {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	var lcs = make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if jsonEqual(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// source[j] is the item of a which becomes b[j], or -1 for an added item.
+	var (
+		source = make([]int, len(b))
+		used   = make([]bool, len(a))
+		gapsA  [][]int
+		gapsB  [][]int
+		gapA   []int
+		gapB   []int
+	)
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && jsonEqual(a[i], b[j]) && lcs[i][j] == lcs[i+1][j+1]+1:
+			source[j], used[i] = i, true
+			gapsA, gapsB, gapA, gapB = append(gapsA, gapA), append(gapsB, gapB), nil, nil
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			source[j] = -1
+			gapB = append(gapB, j)
+			j++
+		default:
+			gapA = append(gapA, i)
+			i++
+		}
+	}
+	gapsA, gapsB = append(gapsA, gapA), append(gapsB, gapB)
+
+	for _, gap := range gapsB {
+		for _, j := range gap {
+			for _, i := range gapsAll(gapsA) {
+				if !used[i] && jsonEqual(a[i], b[j]) {
+					source[j], used[i] = i, true
+					break
+				}
+			}
+		}
+	}
+	var changed = make(map[int]bool)
+	for g := range gapsA {
+		var rest = make([]int, 0)
+		for _, i := range gapsA[g] {
+			if !used[i] {
+				rest = append(rest, i)
+			}
+		}
+		for _, j := range gapsB[g] {
+			if source[j] < 0 && len(rest) > 0 {
+				source[j], used[rest[0]], changed[j] = rest[0], true, true
+				rest = rest[1:]
+			}
+		}
+	}
+
+	// The removals go first, from the last one, so the positions of the others do not change. Then b is
+	// built from the left, an item is added or moved from the right to its position.
+	var current = make([]int, 0, len(a))
+	for i := range a {
+		current = append(current, i)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		if !used[i] {
+			*d = append(*d, PatchOperation{Op: PatchRemove, Path: path + "/" + strconv.Itoa(i)})
+			current = append(current[:i], current[i+1:]...)
+		}
+	}
+	for j, i := range source {
+		at := path + "/" + strconv.Itoa(j)
+		switch {
+		case i < 0:
+			*d = append(*d, PatchOperation{Op: PatchAdd, Path: at, Value: b[j]})
+			current = append(current[:j], append([]int{-1}, current[j:]...)...)
+			continue
+		case current[j] != i:
+			k := j + 1
+			for current[k] != i {
+				k++
+			}
+			*d = append(*d, PatchOperation{Op: PatchMove, From: path + "/" + strconv.Itoa(k), Path: at})
+			current = append(current[:k], current[k+1:]...)
+			current = append(current[:j], append([]int{i}, current[j:]...)...)
+		}
+		if changed[j] {
+			diffPatch(d, at, a[i], b[j])
+		}
+	}
+}
+
+func gapsAll(gaps [][]int) []int {
+	var d = make([]int, 0)
+	for _, g := range gaps {
+		d = append(d, g...)
+	}
+	sort.Ints(d)
+	return d
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: json pointer %q does not start with /", ErrInvalidArgument, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: invalid escape in json pointer %q", ErrInvalidArgument, pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerIndex returns the array index of a token. The index len is allowed for an add, which appends.
+func pointerIndex(token string, length int, add bool) (int, error) {
+	if add && token == "-" {
+		return length, nil
+	}
+	// RFC 6901 only allows the index 0 or digits without a leading zero.
+	valid := token != "" && (token == "0" || token[0] != '0')
+	for j := 0; valid && j < len(token); j++ {
+		valid = isDigitByte(token[j])
+	}
+	i, err := strconv.Atoi(token)
+	if !valid || err != nil {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidArgument, token)
+	}
+	if i > length || (i == length && !add) {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrWrongValue, i)
+	}
+	return i, nil
+}
+
+// pointerGet returns the value at the tokens.
+func pointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch {
+		case isJSONObject(doc):
+			v, ok := pathChild(doc, t)
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrWrongValue, t)
+			}
+			doc = v
+		case isJSONArray(doc):
+			items := jsonItems(doc)
+			i, err := pointerIndex(t, len(items), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = items[i]
+		default:
+			return nil, fmt.Errorf("%w: %q of a value which is not a map or an array", ErrWrongValue, t)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate returns a copy of doc with the container at the tokens but the last one replaced by the
+// result of fn. Only the maps and arrays along the path are copied.
+func pointerUpdate(doc interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	child, err := pointerGet(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = pointerUpdate(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	return pointerSet(doc, tokens[0], child, false)
+}
+
+// pointerSet returns a copy of the container with the member or item at token set to value, an item is
+// inserted when insert is set.
+func pointerSet(parent interface{}, token string, value interface{}, insert bool) (interface{}, error) {
+	switch {
+	case isJSONObject(parent):
+		var d = make(map[string]interface{}, jsonLen(parent)+1)
+		pathEach(parent, func(k string, v interface{}) {
+			d[k] = v
+		})
+		d[token] = value
+		return d, nil
+	case isJSONArray(parent):
+		items := jsonItems(parent)
+		i, err := pointerIndex(token, len(items), insert)
+		if err != nil {
+			return nil, err
+		}
+		var d = make([]interface{}, 0, len(items)+1)
+		d = append(d, items[:i]...)
+		d = append(d, value)
+		if insert {
+			d = append(d, items[i:]...)
+		} else {
+			d = append(d, items[i+1:]...)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %q of a value which is not a map or an array", ErrWrongValue, token)
+}
+
+func pointerAdd(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		return pointerSet(parent, token, value, true)
+	})
+}
+
+func pointerReplace(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		if _, err := pointerGet(parent, []string{token}); err != nil {
+			return nil, err
+		}
+		return pointerSet(parent, token, value, false)
+	})
+}
+
+func pointerRemove(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: the whole document can not be removed", ErrWrongValue)
+	}
+	return pointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		if _, err := pointerGet(parent, []string{token}); err != nil {
+			return nil, err
+		}
+		if isJSONObject(parent) {
+			var d = make(map[string]interface{}, jsonLen(parent))
+			pathEach(parent, func(k string, v interface{}) {
+				if k != token {
+					d[k] = v
+				}
+			})
+			return d, nil
+		}
+		items := jsonItems(parent)
+		i, _ := strconv.Atoi(token)
+		var d = make([]interface{}, 0, len(items)-1)
+		return append(append(d, items[:i]...), items[i+1:]...), nil
+	})
+}
+
+func applyPatchOperation(doc interface{}, o PatchOperation) (interface{}, error) {
+	tokens, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case PatchAdd:
+		return pointerAdd(doc, tokens, o.Value)
+	case PatchRemove:
+		return pointerRemove(doc, tokens)
+	case PatchReplace:
+		return pointerReplace(doc, tokens, o.Value)
+	case PatchTest:
+		v, err := pointerGet(doc, tokens)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(v, o.Value) {
+			return nil, fmt.Errorf("%w: test failed, the value is %v", ErrWrongValue, v)
+		}
+		return doc, nil
+	case PatchMove, PatchCopy:
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == PatchCopy {
+			return pointerAdd(doc, tokens, deepCopy(v))
+		}
+		if o.From == o.Path {
+			return doc, nil
+		}
+		if strings.HasPrefix(o.Path, o.From+"/") {
+			return nil, fmt.Errorf("%w: %q can not be moved into itself", ErrInvalidArgument, o.From)
+		}
+		if doc, err = pointerRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, tokens, v)
+	}
+	return nil, fmt.Errorf("%w: unknown patch operation %q", ErrInvalidArgument, o.Op)
+}
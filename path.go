@@ -0,0 +1,321 @@
+package collection
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hulklab/collection/hamt"
+	"github.com/hulklab/collection/vector_trie"
+)
+
+// A path addresses a value nested in maps and slices, such as "user.address.city" or "items.0.sku". Every
+// segment is a map key or a slice index, and the wildcard "*" stands for all of the items of a map or slice.
+// A key which itself contains dots is still found when it exists in the map as a whole.
+const (
+	pathSeparator = "."
+	pathWildcard  = "*"
+)
+
+func splitPath(path string) []string {
+	return strings.Split(path, pathSeparator)
+}
+
+// isWildcardPath determines if the path contains a wildcard segment.
+func isWildcardPath(path string) bool {
+	for _, seg := range splitPath(path) {
+		if seg == pathWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// getPath returns the value at path inside v and whether it exists. If the path contains a wildcard, the
+// value is a []interface{} which holds every value matched by the path.
+func getPath(v interface{}, path string) (interface{}, bool) {
+	if value, ok := pathChild(v, path); ok {
+		return value, true
+	}
+	segs := splitPath(path)
+	if !isWildcardPath(path) {
+		for _, seg := range segs {
+			var ok bool
+			if v, ok = pathChild(v, seg); !ok {
+				return nil, false
+			}
+		}
+		return v, true
+	}
+	var d = make([]interface{}, 0)
+	collectPath(v, segs, &d)
+	return d, true
+}
+
+// lookupPath is getPath for the callers which treat a missing value as nil.
+func lookupPath(v interface{}, path string) interface{} {
+	value, _ := getPath(v, path)
+	return value
+}
+
+func collectPath(v interface{}, segs []string, d *[]interface{}) {
+	if len(segs) == 0 {
+		*d = append(*d, v)
+		return
+	}
+	if segs[0] == pathWildcard {
+		pathEach(v, func(_ string, value interface{}) {
+			collectPath(value, segs[1:], d)
+		})
+		return
+	}
+	if value, ok := pathChild(v, segs[0]); ok {
+		collectPath(value, segs[1:], d)
+	}
+}
+
+// pathChild returns the item of the map or slice v with the given key or index.
+func pathChild(v interface{}, seg string) (interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		value, ok := t[seg]
+		return value, ok
+	case *hamt.Map:
+		return t.Get(seg)
+	case []interface{}:
+		if i, ok := pathIndex(seg, len(t)); ok {
+			return t[i], true
+		}
+	case []map[string]interface{}:
+		if i, ok := pathIndex(seg, len(t)); ok {
+			return t[i], true
+		}
+	case *vector_trie.List:
+		if i, ok := pathIndex(seg, t.Len()); ok {
+			return t.Get(i), true
+		}
+	}
+	return nil, false
+}
+
+// pathEach calls f for every item of the map or slice v. The items of a map are visited in key order.
+func pathEach(v interface{}, f func(seg string, value interface{})) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		var keys = make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f(k, t[k])
+		}
+	case *hamt.Map:
+		for _, k := range t.Keys() {
+			value, _ := t.Get(k)
+			f(k, value)
+		}
+	case []interface{}:
+		for i, value := range t {
+			f(strconv.Itoa(i), value)
+		}
+	case []map[string]interface{}:
+		for i, value := range t {
+			f(strconv.Itoa(i), value)
+		}
+	case *vector_trie.List:
+		t.Range(func(i int, value interface{}) bool {
+			f(strconv.Itoa(i), value)
+			return true
+		})
+	}
+}
+
+func pathIndex(seg string, length int) (int, bool) {
+	i, err := strconv.Atoi(seg)
+	if err != nil || i < 0 || i >= length {
+		return 0, false
+	}
+	return i, true
+}
+
+// setPath returns a copy of v with the value at path set to value. Only the maps and slices along the path
+// are copied, missing maps on the way are created.
+func setPath(v interface{}, path string, value interface{}) (interface{}, error) {
+	if _, ok := pathChild(v, path); ok {
+		return setSegments(v, []string{path}, value)
+	}
+	return setSegments(v, splitPath(path), value)
+}
+
+func setSegments(v interface{}, segs []string, value interface{}) (interface{}, error) {
+	if len(segs) == 0 {
+		return value, nil
+	}
+	seg, rest := segs[0], segs[1:]
+
+	// set replaces the child found under seg, or every child for the wildcard.
+	set := func(assign func(seg string, child interface{}) error) error {
+		if seg != pathWildcard {
+			child, _ := pathChild(v, seg)
+			return assign(seg, child)
+		}
+		var err error
+		pathEach(v, func(seg string, child interface{}) {
+			if err == nil {
+				err = assign(seg, child)
+			}
+		})
+		return err
+	}
+
+	switch t := v.(type) {
+	case nil:
+		if seg == pathWildcard {
+			return v, nil
+		}
+		return setSegments(map[string]interface{}{}, segs, value)
+	case map[string]interface{}:
+		var d = make(map[string]interface{}, len(t)+1)
+		for k, child := range t {
+			d[k] = child
+		}
+		err := set(func(seg string, child interface{}) (err error) {
+			d[seg], err = setSegments(child, rest, value)
+			return err
+		})
+		return d, err
+	case *hamt.Map:
+		var d = t.Transient()
+		err := set(func(seg string, child interface{}) error {
+			n, err := setSegments(child, rest, value)
+			d.Assoc(seg, n)
+			return err
+		})
+		return d.Persistent(), err
+	case []interface{}:
+		var d = make([]interface{}, len(t))
+		copy(d, t)
+		err := set(func(seg string, child interface{}) (err error) {
+			i, ok := pathIndex(seg, len(d))
+			if !ok {
+				return ErrInvalidArgument
+			}
+			d[i], err = setSegments(child, rest, value)
+			return err
+		})
+		return d, err
+	case []map[string]interface{}:
+		var d = make([]map[string]interface{}, len(t))
+		copy(d, t)
+		err := set(func(seg string, child interface{}) error {
+			i, ok := pathIndex(seg, len(d))
+			if !ok {
+				return ErrInvalidArgument
+			}
+			n, err := setSegments(child, rest, value)
+			m, ok := n.(map[string]interface{})
+			if !ok && err == nil {
+				err = wrongType("map[string]interface{}", n)
+			}
+			d[i] = m
+			return err
+		})
+		return d, err
+	case *vector_trie.List:
+		var d = t.Transient()
+		err := set(func(seg string, child interface{}) error {
+			i, ok := pathIndex(seg, d.Len())
+			if !ok {
+				return ErrInvalidArgument
+			}
+			n, err := setSegments(child, rest, value)
+			d.Set(i, n)
+			return err
+		})
+		return d.Persistent(), err
+	default:
+		return v, wrongType("map or slice", v)
+	}
+}
+
+// forgetPath returns a copy of v without the value at path. Removing an item of a slice shifts the items
+// after it. Nothing is changed when the path does not exist.
+func forgetPath(v interface{}, path string) interface{} {
+	if _, ok := pathChild(v, path); ok {
+		return forgetSegments(v, []string{path})
+	}
+	return forgetSegments(v, splitPath(path))
+}
+
+func forgetSegments(v interface{}, segs []string) interface{} {
+	seg, rest := segs[0], segs[1:]
+
+	// keep reports whether the child under seg stays, and replaces it with the result of the rest of the path.
+	keep := func(s string, child interface{}) (interface{}, bool) {
+		if seg != pathWildcard && s != seg {
+			return child, true
+		}
+		if len(rest) == 0 {
+			return nil, false
+		}
+		return forgetSegments(child, rest), true
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		var d = make(map[string]interface{}, len(t))
+		for k, child := range t {
+			if n, ok := keep(k, child); ok {
+				d[k] = n
+			}
+		}
+		return d
+	case *hamt.Map:
+		if seg != pathWildcard {
+			child, ok := t.Get(seg)
+			if !ok {
+				return t
+			}
+			if n, ok := keep(seg, child); ok {
+				return t.Assoc(seg, n)
+			}
+			return t.Dissoc(seg)
+		}
+		var d = hamt.New().Transient()
+		t.Range(func(k string, child interface{}) bool {
+			if n, ok := keep(k, child); ok {
+				d.Assoc(k, n)
+			}
+			return true
+		})
+		return d.Persistent()
+	case []interface{}:
+		var d = make([]interface{}, 0, len(t))
+		for i, child := range t {
+			if n, ok := keep(strconv.Itoa(i), child); ok {
+				d = append(d, n)
+			}
+		}
+		return d
+	case []map[string]interface{}:
+		var d = make([]map[string]interface{}, 0, len(t))
+		for i, child := range t {
+			if n, ok := keep(strconv.Itoa(i), child); ok {
+				d = append(d, n.(map[string]interface{}))
+			}
+		}
+		return d
+	case *vector_trie.List:
+		var d = vector_trie.New().Transient()
+		t.Range(func(i int, child interface{}) bool {
+			if n, ok := keep(strconv.Itoa(i), child); ok {
+				d.Append(n)
+			}
+			return true
+		})
+		return d.Persistent()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,109 @@
+package collection
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The columns Melt puts the name and the value of a melted column into.
+const (
+	MeltVariable = "variable"
+	MeltValue    = "value"
+)
+
+// Pivot returns a MapArrayCollection with one row per value at index, in the order of their first row. Every
+// value at columns becomes a column of the result, which holds the result of the aggregator over the rows
+// with that index and column value. The cells without any row are set to fill, which is nil by default.
+// A column value which is named like index, or like another column value, such as 1 and "1", is an
+// ErrInvalidArgument.
+//
+//	Collect(sales).Pivot("month", "product", Sum("revenue"), 0)
+func (c MapArrayCollection) Pivot(index, columns string, value Aggregator, fill ...interface{}) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	if value.fn == nil {
+		return BaseCollection{err: fmt.Errorf("%w: no aggregator", ErrInvalidArgument)}
+	}
+	var empty interface{}
+	if len(fill) > 0 {
+		empty = fill[0]
+	}
+
+	var (
+		rowOf = make(map[string]int)
+		names = make([]string, 0)
+		// seen is the hash of the column value of every column name.
+		seen = make(map[string]string)
+		d    = make([]map[string]interface{}, 0)
+	)
+	for _, g := range groupBy(c.items(), []string{index, columns}).groups {
+		hash, _ := valueHash(g.key[0])
+		i, ok := rowOf[hash]
+		if !ok {
+			i = len(d)
+			rowOf[hash] = i
+			d = append(d, map[string]interface{}{index: g.key[0]})
+		}
+		name := fmt.Sprintf("%v", g.key[1])
+		if name == index {
+			return BaseCollection{err: fmt.Errorf("%w: the pivoted column %q clashes with the index column", ErrInvalidArgument, name)}
+		}
+		columnHash, _ := valueHash(g.key[1])
+		if h, ok := seen[name]; !ok {
+			seen[name] = columnHash
+			names = append(names, name)
+		} else if h != columnHash {
+			return BaseCollection{err: fmt.Errorf("%w: two values of %s are pivoted into the column %q", ErrInvalidArgument, columns, name)}
+		}
+		d[i][name] = value.fn(g.rows)
+	}
+	for _, row := range d {
+		for _, name := range names {
+			if _, ok := row[name]; !ok {
+				row[name] = empty
+			}
+		}
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// Melt is the reverse of Pivot. Every row is turned into one row per value column, which holds the id columns,
+// the name of the value column in MeltVariable and its value in MeltValue. Without value columns all of the
+// columns which are not id columns are melted, in sorted order.
+func (c MapArrayCollection) Melt(idColumns []string, valueColumns ...string) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	items := c.items()
+	if len(valueColumns) == 0 {
+		var ids = make(map[string]bool, len(idColumns))
+		for _, k := range idColumns {
+			ids[k] = true
+		}
+		var keys = make(map[string]bool)
+		for _, item := range items {
+			for k := range item {
+				if !ids[k] && !keys[k] {
+					keys[k] = true
+					valueColumns = append(valueColumns, k)
+				}
+			}
+		}
+		sort.Strings(valueColumns)
+	}
+
+	var d = make([]map[string]interface{}, 0, len(items)*len(valueColumns))
+	for _, item := range items {
+		for _, col := range valueColumns {
+			var row = make(map[string]interface{}, len(idColumns)+2)
+			for _, k := range idColumns {
+				row[k] = lookupPath(item, k)
+			}
+			row[MeltVariable] = col
+			row[MeltValue] = lookupPath(item, col)
+			d = append(d, row)
+		}
+	}
+	return newMapArrayCollection(mapList(d))
+}
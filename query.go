@@ -0,0 +1,1040 @@
+package collection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hulklab/collection/query"
+	"github.com/shopspring/decimal"
+)
+
+// Query runs a SQL SELECT over the tables, which are MapArrayCollections by the name the query uses for
+// them, and returns the rows of the result as a MapArrayCollection.
+//
+//	Query(`SELECT u.name, COUNT(*) AS orders, SUM(o.total) AS spent
+//		FROM users u JOIN orders o ON o.user_id = u.id
+//		WHERE o.status = 'paid'
+//		GROUP BY u.name HAVING COUNT(*) > 1
+//		ORDER BY spent DESC LIMIT 10`, map[string]Collection{"users": users, "orders": orders})
+//
+// The query supports expressions with the arithmetic, comparison, logical, ||, LIKE, ILIKE, IN, BETWEEN,
+// IS NULL and CASE operators, the functions UPPER, LOWER, LENGTH, TRIM, SUBSTR, CONCAT, COALESCE, NULLIF,
+// ABS, ROUND, FLOOR and CEIL, and the aggregates COUNT, SUM, AVG, MIN, MAX and MEDIAN, which may take
+// DISTINCT. A column is a key or a path of the rows, which may be qualified with the alias of its table.
+// Numbers in the query are decimal.Decimal, and so are the results of arithmetic. An alias or the position
+// of a column of the select list may be used in GROUP BY, HAVING and ORDER BY.
+//
+// A comparison with null is null like in SQL, and WHERE, ON and HAVING keep only the rows for which the
+// condition is true. SELECT * puts the columns of every table into the result, a column which an earlier
+// table of the row has as well is named by the alias of its table and the column, such as "o.id". An outer
+// join puts no columns of the missing row into the result. A query which can not be parsed or planned returns
+// ErrInvalidArgument.
+func Query(sql string, tables map[string]Collection) Collection {
+	plan, err := query.Compile(sql)
+	if err != nil {
+		return BaseCollection{err: fmt.Errorf("%w: %w", ErrInvalidArgument, err)}
+	}
+	d, err := runQuery(plan, tables)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// queryRow holds the rows of the tables of a query, in the order of the tables. The row of a table which an
+// outer join found no match in is nil.
+type queryRow []map[string]interface{}
+
+// queryContext is what an expression is evaluated on, the aggregates are set for the groups of a query.
+type queryContext struct {
+	row        queryRow
+	aggregates []interface{}
+}
+
+type queryExpr func(ctx *queryContext) (interface{}, error)
+
+type queryCompiler struct {
+	aliases []string
+	index   map[string]int
+}
+
+func runQuery(plan *query.Plan, tables map[string]Collection) ([]map[string]interface{}, error) {
+	var (
+		q       = &queryCompiler{index: make(map[string]int)}
+		sources = make([][]map[string]interface{}, 0, len(plan.Joins)+1)
+	)
+	if plan.From.Name != "" {
+		for _, t := range append([]query.Table{plan.From}, queryJoinTables(plan.Joins)...) {
+			c, ok := tables[t.Name]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown table %q", ErrInvalidArgument, t.Name)
+			}
+			if err := c.Err(); err != nil {
+				return nil, err
+			}
+			m, ok := c.(MapArrayCollection)
+			if !ok {
+				return nil, wrongType("MapArrayCollection", c)
+			}
+			q.index[t.Alias] = len(q.aliases)
+			q.aliases = append(q.aliases, t.Alias)
+			sources = append(sources, m.items())
+		}
+	}
+
+	var rows []queryRow
+	if len(sources) == 0 {
+		rows = []queryRow{{}}
+	} else {
+		rows = make([]queryRow, len(sources[0]))
+		for i, item := range sources[0] {
+			rows[i] = make(queryRow, len(sources))
+			rows[i][0] = item
+		}
+	}
+	for i, j := range plan.Joins {
+		var err error
+		if rows, err = q.join(rows, sources[i+1], j, i+1); err != nil {
+			return nil, err
+		}
+	}
+	if plan.Where != nil {
+		where, err := q.compile(plan.Where)
+		if err != nil {
+			return nil, err
+		}
+		var d = make([]queryRow, 0, len(rows))
+		for _, row := range rows {
+			ok, err := queryTrue(where, &queryContext{row: row})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				d = append(d, row)
+			}
+		}
+		rows = d
+	}
+
+	var contexts []*queryContext
+	if plan.Grouped {
+		var err error
+		if contexts, err = q.group(rows, plan); err != nil {
+			return nil, err
+		}
+	} else {
+		contexts = make([]*queryContext, len(rows))
+		for i, row := range rows {
+			contexts[i] = &queryContext{row: row}
+		}
+	}
+	return q.project(contexts, plan)
+}
+
+func queryJoinTables(joins []query.JoinPlan) []query.Table {
+	var d = make([]query.Table, len(joins))
+	for i, j := range joins {
+		d[i] = j.Table
+	}
+	return d
+}
+
+// queryTrue determines if the condition is true. Null and false are not, and neither are the values which
+// isTrue does not take as true.
+func queryTrue(cond queryExpr, ctx *queryContext) (bool, error) {
+	v, err := cond(ctx)
+	if err != nil {
+		return false, err
+	}
+	t, known := queryTruth(v)
+	return known && t, nil
+}
+
+// queryTruth returns the truth of a condition, and false for null, which is neither true nor false.
+func queryTruth(v interface{}) (bool, bool) {
+	switch t := v.(type) {
+	case nil:
+		return false, false
+	case decimal.Decimal:
+		return !t.IsZero(), true
+	default:
+		return isTrue(v), true
+	}
+}
+
+// join joins the rows of the table at position at to rows. The matching rows are looked up in a hash table
+// by the keys of the join if it has any. A key which is not a string, bool or number may equal a value of
+// another type, such as a time and a date string, so the rows with such a key are always checked.
+func (q *queryCompiler) join(rows []queryRow, right []map[string]interface{}, j query.JoinPlan, at int) ([]queryRow, error) {
+	var (
+		on      queryExpr
+		err     error
+		matched = make([]bool, len(right))
+		d       = make([]queryRow, 0, len(rows))
+	)
+	if j.On != nil {
+		if on, err = q.compile(j.On); err != nil {
+			return nil, err
+		}
+	}
+	candidates, err := q.joinCandidates(right, j, at)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		rs, err := candidates(row)
+		if err != nil {
+			return nil, err
+		}
+		var found = false
+		for _, r := range rs {
+			joined := make(queryRow, len(row))
+			copy(joined, row)
+			joined[at] = right[r]
+			if on != nil {
+				ok, err := queryTrue(on, &queryContext{row: joined})
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+			}
+			found, matched[r] = true, true
+			d = append(d, joined)
+		}
+		if !found && (j.Kind == query.LeftJoin || j.Kind == query.FullJoin) {
+			d = append(d, row)
+		}
+	}
+	if j.Kind == query.RightJoin || j.Kind == query.FullJoin {
+		for r, item := range right {
+			if !matched[r] {
+				row := make(queryRow, len(q.aliases))
+				row[at] = item
+				d = append(d, row)
+			}
+		}
+	}
+	return d, nil
+}
+
+// joinCandidates returns the function which returns the positions of the rows of right which may match a
+// row, in ascending order.
+func (q *queryCompiler) joinCandidates(right []map[string]interface{}, j query.JoinPlan, at int) (func(row queryRow) ([]int, error), error) {
+	var all = make([]int, len(right))
+	for i := range all {
+		all[i] = i
+	}
+	if len(j.LeftKeys) == 0 {
+		return func(queryRow) ([]int, error) {
+			return all, nil
+		}, nil
+	}
+
+	keys := func(exprs []query.Expr) (func(row queryRow) ([]interface{}, bool, error), error) {
+		var compiled = make([]queryExpr, len(exprs))
+		for i, e := range exprs {
+			var err error
+			if compiled[i], err = q.compile(e); err != nil {
+				return nil, err
+			}
+		}
+		return func(row queryRow) ([]interface{}, bool, error) {
+			var (
+				values   = make([]interface{}, len(compiled))
+				hashable = true
+			)
+			for i, c := range compiled {
+				v, err := c(&queryContext{row: row})
+				if err != nil {
+					return nil, false, err
+				}
+				if v == nil {
+					return nil, false, nil
+				}
+				switch v.(type) {
+				case string, bool:
+				default:
+					hashable = hashable && isNumber(v)
+				}
+				values[i] = v
+			}
+			return values, hashable, nil
+		}, nil
+	}
+	leftKeys, err := keys(j.LeftKeys)
+	if err != nil {
+		return nil, err
+	}
+	rightKeys, err := keys(j.RightKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		hash   = make(map[string][]int)
+		always = make([]int, 0)
+		row    = make(queryRow, len(q.aliases))
+	)
+	for i, item := range right {
+		row[at] = item
+		values, hashable, err := rightKeys(row)
+		switch {
+		case err != nil:
+			return nil, err
+		case values == nil:
+		case hashable:
+			h, _ := valueHash(values)
+			hash[h] = append(hash[h], i)
+		default:
+			always = append(always, i)
+		}
+	}
+	return func(row queryRow) ([]int, error) {
+		values, hashable, err := leftKeys(row)
+		switch {
+		case err != nil:
+			return nil, err
+		case values == nil:
+			return nil, nil
+		case !hashable:
+			return all, nil
+		}
+		h, _ := valueHash(values)
+		if len(always) == 0 {
+			return hash[h], nil
+		}
+		d := append(append(make([]int, 0, len(hash[h])+len(always)), hash[h]...), always...)
+		sort.Ints(d)
+		return d, nil
+	}, nil
+}
+
+// group groups the rows by the values of GROUP BY in the order of their first row, and computes the
+// aggregates of every group. Without GROUP BY all of the rows are one group, even when there is no row.
+func (q *queryCompiler) group(rows []queryRow, plan *query.Plan) ([]*queryContext, error) {
+	var keys = make([]queryExpr, len(plan.GroupBy))
+	for i, e := range plan.GroupBy {
+		var err error
+		if keys[i], err = q.compile(e); err != nil {
+			return nil, err
+		}
+	}
+	var aggregates = make([]func(rows []queryRow) (interface{}, error), len(plan.Aggregates))
+	for i, c := range plan.Aggregates {
+		var err error
+		if aggregates[i], err = q.aggregate(c); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		index  = make(map[string]int)
+		groups = make([][]queryRow, 0)
+	)
+	if len(keys) == 0 {
+		groups = append(groups, rows)
+	} else {
+		for _, row := range rows {
+			var values = make([]interface{}, len(keys))
+			for i, key := range keys {
+				v, err := key(&queryContext{row: row})
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			h, _ := valueHash(values)
+			g, ok := index[h]
+			if !ok {
+				g = len(groups)
+				index[h] = g
+				groups = append(groups, nil)
+			}
+			groups[g] = append(groups[g], row)
+		}
+	}
+
+	var d = make([]*queryContext, 0, len(groups))
+	for _, g := range groups {
+		ctx := &queryContext{row: make(queryRow, len(q.aliases)), aggregates: make([]interface{}, len(aggregates))}
+		if len(g) > 0 {
+			ctx.row = g[0]
+		}
+		for i, aggregate := range aggregates {
+			v, err := aggregate(g)
+			if err != nil {
+				return nil, err
+			}
+			ctx.aggregates[i] = v
+		}
+		d = append(d, ctx)
+	}
+	if plan.Having == nil {
+		return d, nil
+	}
+	having, err := q.compile(plan.Having)
+	if err != nil {
+		return nil, err
+	}
+	var kept = make([]*queryContext, 0, len(d))
+	for _, ctx := range d {
+		ok, err := queryTrue(having, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, ctx)
+		}
+	}
+	return kept, nil
+}
+
+// queryAggregates are the Aggregators of the aggregate functions, they run over rows which hold the values
+// of the argument in the column "value".
+var queryAggregates = map[string]func(column string) Aggregator{
+	"count":  func(column string) Aggregator { return Count(column) },
+	"sum":    Sum,
+	"avg":    Avg,
+	"min":    Min,
+	"max":    Max,
+	"median": Median,
+}
+
+// aggregate compiles an aggregate call. The nulls are left out, and every aggregate but COUNT is null
+// without a value.
+func (q *queryCompiler) aggregate(c *query.Call) (func(rows []queryRow) (interface{}, error), error) {
+	if c.Star {
+		return func(rows []queryRow) (interface{}, error) {
+			return len(rows), nil
+		}, nil
+	}
+	arg, err := q.compile(c.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	aggregator := queryAggregates[c.Name]("value")
+	return func(rows []queryRow) (interface{}, error) {
+		var (
+			values = make([]map[string]interface{}, 0, len(rows))
+			seen   = make(map[string]bool)
+		)
+		for _, row := range rows {
+			v, err := arg(&queryContext{row: row})
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			if c.Distinct {
+				h, _ := valueHash(v)
+				if seen[h] {
+					continue
+				}
+				seen[h] = true
+			}
+			values = append(values, map[string]interface{}{"value": v})
+		}
+		if len(values) == 0 && c.Name != "count" {
+			return nil, nil
+		}
+		return aggregator.fn(values), nil
+	}, nil
+}
+
+// project computes the columns of the result, and applies DISTINCT, ORDER BY, OFFSET and LIMIT.
+func (q *queryCompiler) project(contexts []*queryContext, plan *query.Plan) ([]map[string]interface{}, error) {
+	var columns = make([]queryExpr, len(plan.Columns))
+	for i, col := range plan.Columns {
+		if col.Star {
+			continue
+		}
+		var err error
+		if columns[i], err = q.compile(col.Expr); err != nil {
+			return nil, err
+		}
+	}
+	var order = make([]queryExpr, len(plan.OrderBy))
+	for i, item := range plan.OrderBy {
+		var err error
+		if order[i], err = q.compile(item.Expr); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		d      = make([]map[string]interface{}, 0, len(contexts))
+		values = make([][]interface{}, 0, len(contexts))
+		seen   = make(map[string]bool)
+	)
+	for _, ctx := range contexts {
+		var row = make(map[string]interface{}, len(columns))
+		for i, col := range plan.Columns {
+			if col.Star {
+				q.star(row, ctx.row, col.Table)
+				continue
+			}
+			v, err := columns[i](ctx)
+			if err != nil {
+				return nil, err
+			}
+			row[col.Name] = v
+		}
+		if plan.Distinct {
+			var keys = make([]string, 0, len(row))
+			for k := range row {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			var key = make([]interface{}, 0, 2*len(keys))
+			for _, k := range keys {
+				key = append(key, k, row[k])
+			}
+			h, _ := valueHash(key)
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+		}
+		var v = make([]interface{}, len(order))
+		for i, o := range order {
+			var err error
+			if v[i], err = o(ctx); err != nil {
+				return nil, err
+			}
+		}
+		d = append(d, row)
+		values = append(values, v)
+	}
+
+	if len(order) > 0 {
+		var keys = make([]SortKey, len(plan.OrderBy))
+		for i, item := range plan.OrderBy {
+			keys[i] = SortKey{Key: strconv.Itoa(i), Desc: item.Desc}
+			switch item.Nulls {
+			case query.NullsFirst:
+				keys[i].Nulls = NullsFirst
+			case query.NullsLast:
+				keys[i].Nulls = NullsLast
+			}
+		}
+		indexes := sortOrder(len(d), keys, func(i int, key string) interface{} {
+			k, _ := strconv.Atoi(key)
+			return values[i][k]
+		})
+		var sorted = make([]map[string]interface{}, len(d))
+		for i, j := range indexes {
+			sorted[i] = d[j]
+		}
+		d = sorted
+	}
+
+	start := min(plan.Offset, len(d))
+	end := len(d)
+	if plan.Limit >= 0 {
+		end = min(start+plan.Limit, end)
+	}
+	return d[start:end], nil
+}
+
+// star puts the columns of the table with the alias, or of every table without one, into the result.
+func (q *queryCompiler) star(d map[string]interface{}, row queryRow, alias string) {
+	for i, item := range row {
+		if alias != "" && q.aliases[i] != alias {
+			continue
+		}
+		for k, v := range item {
+			if _, ok := d[k]; ok && alias == "" && i > 0 {
+				k = q.aliases[i] + pathSeparator + k
+			}
+			d[k] = v
+		}
+	}
+}
+
+// compile turns an expression into a function of the rows.
+func (q *queryCompiler) compile(e query.Expr) (queryExpr, error) {
+	switch x := e.(type) {
+	case *query.Literal:
+		return func(*queryContext) (interface{}, error) {
+			return x.Value, nil
+		}, nil
+	case *query.Column:
+		return q.compileColumn(x)
+	case *query.Aggregate:
+		return func(ctx *queryContext) (interface{}, error) {
+			return ctx.aggregates[x.Index], nil
+		}, nil
+	case *query.Unary:
+		operand, err := q.compile(x.X)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *queryContext) (interface{}, error) {
+			v, err := operand(ctx)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			if x.Op == "not" {
+				t, _ := queryTruth(v)
+				return !t, nil
+			}
+			d, ok := asDecimal(v)
+			if !ok {
+				return nil, wrongType("number", v)
+			}
+			return d.Neg(), nil
+		}, nil
+	case *query.Binary:
+		return q.compileBinary(x)
+	case *query.Like:
+		return q.compileLike(x)
+	case *query.In:
+		return q.compileIn(x)
+	case *query.Between:
+		operands, err := q.compileList([]query.Expr{x.X, x.Low, x.High})
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *queryContext) (interface{}, error) {
+			v, err := queryValues(ctx, operands)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			low, okLow := orderValues(v[0], v[1])
+			high, okHigh := orderValues(v[0], v[2])
+			if !okLow || !okHigh {
+				return nil, nil
+			}
+			return (low >= 0 && high <= 0) != x.Not, nil
+		}, nil
+	case *query.IsNull:
+		operand, err := q.compile(x.X)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *queryContext) (interface{}, error) {
+			v, err := operand(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return (v == nil) != x.Not, nil
+		}, nil
+	case *query.Case:
+		return q.compileCase(x)
+	case *query.Call:
+		return q.compileCall(x)
+	}
+	return nil, fmt.Errorf("%w: unsupported expression %T", ErrInvalidArgument, e)
+}
+
+func (q *queryCompiler) compileList(exprs []query.Expr) ([]queryExpr, error) {
+	var d = make([]queryExpr, len(exprs))
+	for i, e := range exprs {
+		var err error
+		if d[i], err = q.compile(e); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// queryValues evaluates the operands, and returns nil if any of them is null.
+func queryValues(ctx *queryContext, operands []queryExpr) ([]interface{}, error) {
+	var d = make([]interface{}, len(operands))
+	for i, o := range operands {
+		v, err := o(ctx)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		d[i] = v
+	}
+	return d, nil
+}
+
+// compileColumn looks up a qualified column in the row of its table, and an unqualified one in the only
+// table which has it.
+func (q *queryCompiler) compileColumn(c *query.Column) (queryExpr, error) {
+	if c.Table != "" || len(q.aliases) == 1 {
+		var at = 0
+		if c.Table != "" {
+			at = q.index[c.Table]
+		}
+		return func(ctx *queryContext) (interface{}, error) {
+			if ctx.row[at] == nil {
+				return nil, nil
+			}
+			return lookupPath(ctx.row[at], c.Name), nil
+		}, nil
+	}
+	return func(ctx *queryContext) (interface{}, error) {
+		var (
+			value interface{}
+			found = -1
+		)
+		for i, item := range ctx.row {
+			if item == nil {
+				continue
+			}
+			if v, ok := getPath(item, c.Name); ok {
+				if found >= 0 {
+					return nil, fmt.Errorf("%w: column %q is ambiguous, it is in %q and %q", ErrInvalidArgument,
+						c.Name, q.aliases[found], q.aliases[i])
+				}
+				value, found = v, i
+			}
+		}
+		return value, nil
+	}, nil
+}
+
+func (q *queryCompiler) compileBinary(b *query.Binary) (queryExpr, error) {
+	x, err := q.compile(b.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := q.compile(b.Y)
+	if err != nil {
+		return nil, err
+	}
+	if b.Op == "and" || b.Op == "or" {
+		// A false operand of AND and a true operand of OR decide the result, even when the other one is null.
+		decisive := b.Op == "or"
+		return func(ctx *queryContext) (interface{}, error) {
+			var known = true
+			for _, operand := range []queryExpr{x, y} {
+				v, err := operand(ctx)
+				if err != nil {
+					return nil, err
+				}
+				t, ok := queryTruth(v)
+				if ok && t == decisive {
+					return decisive, nil
+				}
+				known = known && ok
+			}
+			if !known {
+				return nil, nil
+			}
+			return !decisive, nil
+		}, nil
+	}
+
+	return func(ctx *queryContext) (interface{}, error) {
+		v, err := queryValues(ctx, []queryExpr{x, y})
+		if err != nil || v == nil {
+			return nil, err
+		}
+		switch b.Op {
+		case "=":
+			return equalValues(v[0], v[1]), nil
+		case "!=":
+			return !equalValues(v[0], v[1]), nil
+		case "<", "<=", ">", ">=":
+			c, ok := orderValues(v[0], v[1])
+			if !ok {
+				return nil, nil
+			}
+			switch b.Op {
+			case "<":
+				return c < 0, nil
+			case "<=":
+				return c <= 0, nil
+			case ">":
+				return c > 0, nil
+			default:
+				return c >= 0, nil
+			}
+		case "||":
+			return stringValue(v[0]) + stringValue(v[1]), nil
+		}
+
+		dx, ok := asDecimal(v[0])
+		if !ok {
+			return nil, wrongType("number", v[0])
+		}
+		dy, ok := asDecimal(v[1])
+		if !ok {
+			return nil, wrongType("number", v[1])
+		}
+		switch b.Op {
+		case "+":
+			return dx.Add(dy), nil
+		case "-":
+			return dx.Sub(dy), nil
+		case "*":
+			return dx.Mul(dy), nil
+		case "/", "%":
+			// Like in SQL, a division by zero is null.
+			if dy.IsZero() {
+				return nil, nil
+			}
+			if b.Op == "/" {
+				return dx.Div(dy), nil
+			}
+			return dx.Mod(dy), nil
+		}
+		return nil, fmt.Errorf("%w: unsupported operator %s", ErrInvalidArgument, b.Op)
+	}, nil
+}
+
+func (q *queryCompiler) compileLike(l *query.Like) (queryExpr, error) {
+	x, err := q.compile(l.X)
+	if err != nil {
+		return nil, err
+	}
+	// A constant pattern is compiled once.
+	if p, ok := l.Pattern.(*query.Literal); ok {
+		if p.Value == nil {
+			return func(*queryContext) (interface{}, error) {
+				return nil, nil
+			}, nil
+		}
+		re := likePattern(stringValue(p.Value), l.Fold)
+		return func(ctx *queryContext) (interface{}, error) {
+			v, err := x(ctx)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			return re.MatchString(stringValue(v)) != l.Not, nil
+		}, nil
+	}
+	pattern, err := q.compile(l.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *queryContext) (interface{}, error) {
+		v, err := queryValues(ctx, []queryExpr{x, pattern})
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return likePattern(stringValue(v[1]), l.Fold).MatchString(stringValue(v[0])) != l.Not, nil
+	}, nil
+}
+
+// compileIn returns null when no item equals the value and an item is null, like SQL does.
+func (q *queryCompiler) compileIn(in *query.In) (queryExpr, error) {
+	x, err := q.compile(in.X)
+	if err != nil {
+		return nil, err
+	}
+	list, err := q.compileList(in.List)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *queryContext) (interface{}, error) {
+		v, err := x(ctx)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		var null = false
+		for _, item := range list {
+			w, err := item(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if w == nil {
+				null = true
+			} else if equalValues(v, w) {
+				return !in.Not, nil
+			}
+		}
+		if null {
+			return nil, nil
+		}
+		return in.Not, nil
+	}, nil
+}
+
+func (q *queryCompiler) compileCase(c *query.Case) (queryExpr, error) {
+	var (
+		operand queryExpr
+		whens   = make([][2]queryExpr, len(c.Whens))
+		other   queryExpr
+		err     error
+	)
+	if c.Operand != nil {
+		if operand, err = q.compile(c.Operand); err != nil {
+			return nil, err
+		}
+	}
+	for i, w := range c.Whens {
+		if whens[i][0], err = q.compile(w.Cond); err != nil {
+			return nil, err
+		}
+		if whens[i][1], err = q.compile(w.Result); err != nil {
+			return nil, err
+		}
+	}
+	if c.Else != nil {
+		if other, err = q.compile(c.Else); err != nil {
+			return nil, err
+		}
+	}
+	return func(ctx *queryContext) (interface{}, error) {
+		var value interface{}
+		if operand != nil {
+			v, err := operand(ctx)
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		}
+		for _, w := range whens {
+			cond, err := w[0](ctx)
+			if err != nil {
+				return nil, err
+			}
+			var match bool
+			if operand != nil {
+				match = value != nil && cond != nil && equalValues(value, cond)
+			} else {
+				t, ok := queryTruth(cond)
+				match = ok && t
+			}
+			if match {
+				return w[1](ctx)
+			}
+		}
+		if other == nil {
+			return nil, nil
+		}
+		return other(ctx)
+	}, nil
+}
+
+// queryFunction is a scalar function, which takes from min to max arguments. A max of -1 takes any number.
+// Unless nulls is set, the function is null when an argument is null.
+type queryFunction struct {
+	min, max int
+	nulls    bool
+	fn       func(args []interface{}) (interface{}, error)
+}
+
+var queryFunctions = map[string]queryFunction{
+	"upper": {min: 1, max: 1, fn: func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(stringValue(args[0])), nil
+	}},
+	"lower": {min: 1, max: 1, fn: func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(stringValue(args[0])), nil
+	}},
+	"length": {min: 1, max: 1, fn: func(args []interface{}) (interface{}, error) {
+		return utf8.RuneCountInString(stringValue(args[0])), nil
+	}},
+	"trim": {min: 1, max: 1, fn: func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(stringValue(args[0])), nil
+	}},
+	"substr": {min: 2, max: 3, fn: querySubstr},
+	"concat": {min: 1, max: -1, nulls: true, fn: func(args []interface{}) (interface{}, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			if arg != nil {
+				b.WriteString(stringValue(arg))
+			}
+		}
+		return b.String(), nil
+	}},
+	"coalesce": {min: 1, max: -1, nulls: true, fn: func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	}},
+	"nullif": {min: 2, max: 2, nulls: true, fn: func(args []interface{}) (interface{}, error) {
+		if args[0] != nil && args[1] != nil && equalValues(args[0], args[1]) {
+			return nil, nil
+		}
+		return args[0], nil
+	}},
+	"abs":   {min: 1, max: 1, fn: queryNumeric(decimal.Decimal.Abs)},
+	"floor": {min: 1, max: 1, fn: queryNumeric(decimal.Decimal.Floor)},
+	"ceil":  {min: 1, max: 1, fn: queryNumeric(decimal.Decimal.Ceil)},
+	"round": {min: 1, max: 2, fn: func(args []interface{}) (interface{}, error) {
+		d, ok := asDecimal(args[0])
+		if !ok {
+			return nil, wrongType("number", args[0])
+		}
+		var places = decimal.Zero
+		if len(args) > 1 {
+			if places, ok = asDecimal(args[1]); !ok {
+				return nil, wrongType("number", args[1])
+			}
+		}
+		return d.Round(int32(places.IntPart())), nil
+	}},
+}
+
+func queryNumeric(fn func(d decimal.Decimal) decimal.Decimal) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		d, ok := asDecimal(args[0])
+		if !ok {
+			return nil, wrongType("number", args[0])
+		}
+		return fn(d), nil
+	}
+}
+
+// querySubstr returns length characters of a string from start, which counts from 1.
+func querySubstr(args []interface{}) (interface{}, error) {
+	var (
+		s      = []rune(stringValue(args[0]))
+		bounds = []int{0, math.MaxInt32}
+	)
+	for i, arg := range args[1:] {
+		d, ok := asDecimal(arg)
+		if !ok {
+			return nil, wrongType("number", arg)
+		}
+		bounds[i] = int(d.IntPart())
+	}
+	start := max(bounds[0]-1, 0)
+	end := len(s)
+	if len(args) > 2 {
+		end = min(bounds[0]-1+max(bounds[1], 0), len(s))
+	}
+	if start >= end {
+		return "", nil
+	}
+	return string(s[start:end]), nil
+}
+
+func (q *queryCompiler) compileCall(c *query.Call) (queryExpr, error) {
+	f, ok := queryFunctions[c.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown function %s", ErrInvalidArgument, strings.ToUpper(c.Name))
+	}
+	if c.Star || c.Distinct {
+		return nil, fmt.Errorf("%w: %s is not an aggregate", ErrInvalidArgument, strings.ToUpper(c.Name))
+	}
+	if len(c.Args) < f.min || (f.max >= 0 && len(c.Args) > f.max) {
+		return nil, fmt.Errorf("%w: wrong number of arguments of %s: %d", ErrInvalidArgument,
+			strings.ToUpper(c.Name), len(c.Args))
+	}
+	args, err := q.compileList(c.Args)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *queryContext) (interface{}, error) {
+		var values = make([]interface{}, len(args))
+		for i, arg := range args {
+			v, err := arg(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && !f.nulls {
+				return nil, nil
+			}
+			values[i] = v
+		}
+		return f.fn(values)
+	}, nil
+}
@@ -0,0 +1,186 @@
+// Package query parses and plans the SQL queries which collection.Query runs over collections.
+//
+// Only SELECT is supported, with expressions and aliases, JOIN, WHERE, GROUP BY with aggregates, HAVING,
+// ORDER BY, LIMIT and OFFSET. Keywords and function names are case insensitive, identifiers are not and may
+// be quoted with double quotes or backticks. A column is addressed by its name, by a path like
+// address.city, or by a table alias before either of them.
+package query
+
+import "github.com/shopspring/decimal"
+
+// Expr is an expression of a query.
+type Expr interface {
+	expr()
+}
+
+// Column is a reference to a column. Table is the alias of the table, and is empty when the column is not
+// qualified. Name is a key or a path of the rows.
+type Column struct {
+	Table string
+	Name  string
+	// segments are the identifiers as parsed, the planner splits them into Table and Name.
+	segments []string
+}
+
+// Literal is a constant. Value is nil, a bool, a string or a decimal.Decimal.
+type Literal struct {
+	Value interface{}
+}
+
+// Unary is the operator "-" or "not" applied to X.
+type Unary struct {
+	Op string
+	X  Expr
+}
+
+// Binary is one of the operators "+", "-", "*", "/", "%", "||", "=", "!=", "<", "<=", ">", ">=", "and"
+// and "or" applied to X and Y. The operator "<>" is parsed as "!=".
+type Binary struct {
+	Op   string
+	X, Y Expr
+}
+
+// Like is X LIKE Pattern, or ILIKE when Fold is set.
+type Like struct {
+	X, Pattern Expr
+	Fold, Not  bool
+}
+
+// In is X IN (List...).
+type In struct {
+	X    Expr
+	List []Expr
+	Not  bool
+}
+
+// Between is X BETWEEN Low AND High, both bounds are included.
+type Between struct {
+	X, Low, High Expr
+	Not          bool
+}
+
+// IsNull is X IS NULL.
+type IsNull struct {
+	X   Expr
+	Not bool
+}
+
+// Call is a call of a function. Name is lowercase. Star is set for COUNT(*), and Distinct for an aggregate
+// over the distinct values of its argument.
+type Call struct {
+	Name     string
+	Args     []Expr
+	Star     bool
+	Distinct bool
+}
+
+// Case is a CASE expression. Operand is nil for the searched form, whose When conditions are booleans.
+type Case struct {
+	Operand Expr
+	Whens   []When
+	Else    Expr
+}
+
+// When is a WHEN Cond THEN Result branch of a Case.
+type When struct {
+	Cond, Result Expr
+}
+
+// Aggregate is the value of the aggregate at Index in the Aggregates of the plan. The planner replaces the
+// aggregate calls with it.
+type Aggregate struct {
+	Index int
+}
+
+func (*Column) expr()    {}
+func (*Literal) expr()   {}
+func (*Unary) expr()     {}
+func (*Binary) expr()    {}
+func (*Like) expr()      {}
+func (*In) expr()        {}
+func (*Between) expr()   {}
+func (*IsNull) expr()    {}
+func (*Call) expr()      {}
+func (*Case) expr()      {}
+func (*Aggregate) expr() {}
+
+// Select is a parsed SELECT statement.
+type Select struct {
+	Distinct bool
+	Items    []SelectItem
+	From     Table
+	Joins    []Join
+	Where    Expr
+	GroupBy  []Expr
+	Having   Expr
+	OrderBy  []OrderItem
+	// Limit is -1 without a LIMIT clause.
+	Limit  int
+	Offset int
+}
+
+// SelectItem is an item of the select list. Star is set for * and for table.*, whose table is in Table.
+// Text is the source text of the expression, which names the column when there is no alias.
+type SelectItem struct {
+	Expr  Expr
+	Alias string
+	Star  bool
+	Table string
+	Text  string
+}
+
+// Table is a table of the FROM clause. Alias is the name the table is referred to by, which is its name
+// when the query gives no alias.
+type Table struct {
+	Name  string
+	Alias string
+}
+
+// JoinKind is the kind of a join.
+type JoinKind int
+
+const (
+	InnerJoin JoinKind = iota
+	LeftJoin
+	RightJoin
+	FullJoin
+	CrossJoin
+)
+
+// Join joins Table to the tables before it. On is nil for a cross join.
+type Join struct {
+	Kind  JoinKind
+	Table Table
+	On    Expr
+}
+
+// NullOrder decides where ORDER BY puts the nulls.
+type NullOrder int
+
+const (
+	// NullsDefault puts the nulls first in ascending order and last in descending order.
+	NullsDefault NullOrder = iota
+	NullsFirst
+	NullsLast
+)
+
+// OrderItem is an item of the ORDER BY clause.
+type OrderItem struct {
+	Expr  Expr
+	Desc  bool
+	Nulls NullOrder
+}
+
+// intValue returns the value of a literal integer, which GROUP BY and ORDER BY take as the position of a
+// select item.
+func intValue(e Expr) (int, bool) {
+	l, ok := e.(*Literal)
+	if !ok {
+		return 0, false
+	}
+	d, ok := l.Value.(decimal.Decimal)
+	if !ok || !d.Equal(d.Truncate(0)) {
+		return 0, false
+	}
+	return int(d.IntPart()), true
+}
@@ -0,0 +1,145 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// SyntaxError is returned for a query which can not be parsed. Pos is the offset of the error in bytes.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// operators are the operators and punctuation, the longer ones first.
+var operators = []string{"<>", "!=", "<=", ">=", "||", "==", "=", "<", ">", "+", "-", "*", "/", "%", "(", ")", ",", ".", ";"}
+
+// lex splits the query into tokens, the last one is tokenEOF.
+func lex(src string) ([]token, error) {
+	var (
+		tokens = make([]token, 0)
+		i      = 0
+	)
+	for i < len(src) {
+		r := rune(src[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case r == '\'' || r == '"' || r == '`':
+			text, end, err := lexQuoted(src, i)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenQuotedIdent
+			if r == '\'' {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: i})
+			i = end
+		case isDigit(src[i]):
+			// A number after a dot is the index segment of a path, such as items.0.sku, so it has no fraction.
+			var start, segment = i, len(tokens) > 0 && tokens[len(tokens)-1] == token{kind: tokenOperator, text: ".", pos: i - 1}
+			i = lexNumber(src, i, segment)
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], pos: start})
+		case r == '_' || r >= 0x80 || unicode.IsLetter(r):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 0x80 || isDigit(src[i]) || unicode.IsLetter(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+		default:
+			var op string
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", src[i])}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexQuoted reads the string or identifier quoted at start, a doubled quote stands for the quote itself.
+func lexQuoted(src string, start int) (string, int, error) {
+	var (
+		quote = src[start]
+		b     strings.Builder
+	)
+	for i := start + 1; i < len(src); i++ {
+		if src[i] != quote {
+			b.WriteByte(src[i])
+			continue
+		}
+		if i+1 < len(src) && src[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, &SyntaxError{Pos: start, Msg: "unterminated quote"}
+}
+
+func lexNumber(src string, i int, segment bool) int {
+	for i < len(src) && isDigit(src[i]) {
+		i++
+	}
+	if segment {
+		return i
+	}
+	if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+		i++
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < len(src) && isDigit(src[j]) {
+			i = j
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,607 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// keywords can not be used as an alias without AS, nor as a column name without quotes.
+var keywords = map[string]bool{
+	"select": true, "distinct": true, "all": true, "from": true, "where": true, "group": true, "by": true,
+	"having": true, "order": true, "limit": true, "offset": true, "join": true, "inner": true, "left": true,
+	"right": true, "full": true, "outer": true, "cross": true, "on": true, "as": true, "and": true, "or": true,
+	"not": true, "in": true, "is": true, "null": true, "like": true, "ilike": true, "between": true, "case": true,
+	"when": true, "then": true, "else": true, "end": true, "asc": true, "desc": true, "nulls": true,
+	"true": true, "false": true,
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+}
+
+// Parse parses a SELECT statement.
+func Parse(src string) (*Select, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	s, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	p.acceptOp(";")
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected("end of query")
+	}
+	return s, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// isKeyword determines if the next tokens are the given keywords.
+func (p *parser) isKeyword(words ...string) bool {
+	for j, w := range words {
+		if p.i+j >= len(p.tokens) {
+			return false
+		}
+		t := p.tokens[p.i+j]
+		if t.kind != tokenIdent || !strings.EqualFold(t.text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) acceptKeyword(words ...string) bool {
+	if !p.isKeyword(words...) {
+		return false
+	}
+	p.i += len(words)
+	return true
+}
+
+func (p *parser) expectKeyword(words ...string) error {
+	if !p.acceptKeyword(words...) {
+		return p.unexpected(strings.ToUpper(strings.Join(words, " ")))
+	}
+	return nil
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.text == op
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if !p.isOp(op) {
+		return false
+	}
+	p.i++
+	return true
+}
+
+func (p *parser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.unexpected(fmt.Sprintf("%q", op))
+	}
+	return nil
+}
+
+// unexpected returns the error of a next token which is not the expected one.
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	found := "end of query"
+	if t.kind != tokenEOF {
+		found = fmt.Sprintf("%q", t.text)
+	}
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected %s, found %s", expected, found)}
+}
+
+func (p *parser) parseSelect() (*Select, error) {
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+	s := &Select{Limit: -1}
+	if p.acceptKeyword("distinct") {
+		s.Distinct = true
+	} else {
+		p.acceptKeyword("all")
+	}
+	for {
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		s.Items = append(s.Items, item)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+
+	var err error
+	if p.acceptKeyword("from") {
+		if s.From, err = p.parseTable(); err != nil {
+			return nil, err
+		}
+		if s.Joins, err = p.parseJoins(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("where") {
+		if s.Where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("group", "by") {
+		if s.GroupBy, err = p.parseExprList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("having") {
+		if s.Having, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("order", "by") {
+		if s.OrderBy, err = p.parseOrderBy(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("limit") {
+		if s.Limit, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("offset") {
+		if s.Offset, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) parseSelectItem() (SelectItem, error) {
+	if p.acceptOp("*") {
+		return SelectItem{Star: true, Text: "*"}, nil
+	}
+	if t := p.peek(); (t.kind == tokenIdent || t.kind == tokenQuotedIdent) && p.i+2 < len(p.tokens) &&
+		p.tokens[p.i+1] == (token{kind: tokenOperator, text: ".", pos: p.tokens[p.i+1].pos}) &&
+		p.tokens[p.i+2].kind == tokenOperator && p.tokens[p.i+2].text == "*" {
+		p.i += 3
+		return SelectItem{Star: true, Table: t.text, Text: t.text + ".*"}, nil
+	}
+
+	start := p.peek().pos
+	e, err := p.parseExpr()
+	if err != nil {
+		return SelectItem{}, err
+	}
+	item := SelectItem{Expr: e, Text: strings.TrimSpace(p.src[start:p.peek().pos])}
+	item.Alias, err = p.parseAlias()
+	return item, err
+}
+
+// parseAlias parses an optional alias, with or without AS.
+func (p *parser) parseAlias() (string, error) {
+	if p.acceptKeyword("as") {
+		t := p.peek()
+		if t.kind != tokenIdent && t.kind != tokenQuotedIdent {
+			return "", p.unexpected("alias")
+		}
+		p.next()
+		return t.text, nil
+	}
+	t := p.peek()
+	if t.kind == tokenQuotedIdent || (t.kind == tokenIdent && !keywords[strings.ToLower(t.text)]) {
+		p.next()
+		return t.text, nil
+	}
+	return "", nil
+}
+
+func (p *parser) parseTable() (Table, error) {
+	t := p.peek()
+	if t.kind != tokenQuotedIdent && (t.kind != tokenIdent || keywords[strings.ToLower(t.text)]) {
+		return Table{}, p.unexpected("table name")
+	}
+	p.next()
+	alias, err := p.parseAlias()
+	if alias == "" {
+		alias = t.text
+	}
+	return Table{Name: t.text, Alias: alias}, err
+}
+
+func (p *parser) parseJoins() ([]Join, error) {
+	var joins []Join
+	for {
+		var kind JoinKind
+		switch {
+		case p.acceptOp(","), p.acceptKeyword("cross", "join"):
+			kind = CrossJoin
+		case p.acceptKeyword("join"), p.acceptKeyword("inner", "join"):
+			kind = InnerJoin
+		case p.acceptKeyword("left", "join"), p.acceptKeyword("left", "outer", "join"):
+			kind = LeftJoin
+		case p.acceptKeyword("right", "join"), p.acceptKeyword("right", "outer", "join"):
+			kind = RightJoin
+		case p.acceptKeyword("full", "join"), p.acceptKeyword("full", "outer", "join"):
+			kind = FullJoin
+		default:
+			return joins, nil
+		}
+		table, err := p.parseTable()
+		if err != nil {
+			return nil, err
+		}
+		join := Join{Kind: kind, Table: table}
+		if kind != CrossJoin {
+			if err := p.expectKeyword("on"); err != nil {
+				return nil, err
+			}
+			if join.On, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		joins = append(joins, join)
+	}
+}
+
+func (p *parser) parseOrderBy() ([]OrderItem, error) {
+	var items []OrderItem
+	for {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		item := OrderItem{Expr: e}
+		if p.acceptKeyword("desc") {
+			item.Desc = true
+		} else {
+			p.acceptKeyword("asc")
+		}
+		switch {
+		case p.acceptKeyword("nulls", "first"):
+			item.Nulls = NullsFirst
+		case p.acceptKeyword("nulls", "last"):
+			item.Nulls = NullsLast
+		}
+		items = append(items, item)
+		if !p.acceptOp(",") {
+			return items, nil
+		}
+	}
+}
+
+// parseCount parses the non negative integer of LIMIT and OFFSET.
+func (p *parser) parseCount() (int, error) {
+	t := p.peek()
+	if t.kind != tokenNumber {
+		return 0, p.unexpected("number")
+	}
+	d, err := decimal.NewFromString(t.text)
+	if err != nil || !d.Equal(d.Truncate(0)) || d.IsNegative() {
+		return 0, p.unexpected("non negative integer")
+	}
+	p.next()
+	return int(d.IntPart()), nil
+}
+
+func (p *parser) parseExprList() ([]Expr, error) {
+	var list []Expr
+	for {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.acceptOp(",") {
+			return list, nil
+		}
+	}
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: "or", X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and") {
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: "and", X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.acceptKeyword("not") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: "not", X: x}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisons = map[string]string{"=": "=", "==": "=", "!=": "!=", "<>": "!=", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+func (p *parser) parseComparison() (Expr, error) {
+	x, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if t := p.peek(); t.kind == tokenOperator && comparisons[t.text] != "" {
+			p.next()
+			y, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			x = &Binary{Op: comparisons[t.text], X: x, Y: y}
+			continue
+		}
+		if p.acceptKeyword("is") {
+			not := p.acceptKeyword("not")
+			if err := p.expectKeyword("null"); err != nil {
+				return nil, err
+			}
+			x = &IsNull{X: x, Not: not}
+			continue
+		}
+
+		not := p.isKeyword("not", "in") || p.isKeyword("not", "between") || p.isKeyword("not", "like") ||
+			p.isKeyword("not", "ilike")
+		if not {
+			p.next()
+		}
+		switch {
+		case p.acceptKeyword("in"):
+			if err := p.expectOp("("); err != nil {
+				return nil, err
+			}
+			list, err := p.parseExprList()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			x = &In{X: x, List: list, Not: not}
+		case p.acceptKeyword("between"):
+			low, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("and"); err != nil {
+				return nil, err
+			}
+			high, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			x = &Between{X: x, Low: low, High: high, Not: not}
+		case p.isKeyword("like"), p.isKeyword("ilike"):
+			fold := p.isKeyword("ilike")
+			p.next()
+			pattern, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			x = &Like{X: x, Pattern: pattern, Fold: fold, Not: not}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) parseConcat() (Expr, error) {
+	return p.parseBinary([]string{"||"}, p.parseAdditive)
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	return p.parseBinary([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	return p.parseBinary([]string{"*", "/", "%"}, p.parseUnary)
+}
+
+// parseBinary parses the left associative operators ops, whose operands are parsed by operand.
+func (p *parser) parseBinary(ops []string, operand func() (Expr, error)) (Expr, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		for _, o := range ops {
+			if p.acceptOp(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: op, X: x, Y: y}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch {
+	case p.acceptOp("-"):
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := x.(*Literal); ok {
+			if d, ok := l.Value.(decimal.Decimal); ok {
+				return &Literal{Value: d.Neg()}, nil
+			}
+		}
+		return &Unary{Op: "-", X: x}, nil
+	case p.acceptOp("+"):
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.next()
+		d, err := decimal.NewFromString(t.text)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("invalid number %q", t.text)}
+		}
+		return &Literal{Value: d}, nil
+	case tokenString:
+		p.next()
+		return &Literal{Value: t.text}, nil
+	case tokenOperator:
+		if p.acceptOp("(") {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expectOp(")")
+		}
+	case tokenQuotedIdent:
+		return p.parseColumn()
+	case tokenIdent:
+		switch strings.ToLower(t.text) {
+		case "null":
+			p.next()
+			return &Literal{}, nil
+		case "true", "false":
+			p.next()
+			return &Literal{Value: strings.EqualFold(t.text, "true")}, nil
+		case "case":
+			p.next()
+			return p.parseCase()
+		}
+		if keywords[strings.ToLower(t.text)] {
+			break
+		}
+		if p.tokens[p.i+1].kind == tokenOperator && p.tokens[p.i+1].text == "(" {
+			return p.parseCall()
+		}
+		return p.parseColumn()
+	}
+	return nil, p.unexpected("expression")
+}
+
+// parseColumn parses the identifiers of a column, separated by dots.
+func (p *parser) parseColumn() (Expr, error) {
+	var c = &Column{}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokenQuotedIdent, t.kind == tokenIdent, t.kind == tokenNumber && len(c.segments) > 0:
+			p.next()
+			c.segments = append(c.segments, t.text)
+		default:
+			return nil, p.unexpected("column name")
+		}
+		if !p.acceptOp(".") {
+			c.Name = strings.Join(c.segments, ".")
+			return c, nil
+		}
+	}
+}
+
+func (p *parser) parseCall() (Expr, error) {
+	c := &Call{Name: strings.ToLower(p.next().text)}
+	p.next()
+	if p.acceptOp("*") {
+		c.Star = true
+		return c, p.expectOp(")")
+	}
+	if p.acceptOp(")") {
+		return c, nil
+	}
+	c.Distinct = p.acceptKeyword("distinct")
+	args, err := p.parseExprList()
+	if err != nil {
+		return nil, err
+	}
+	c.Args = args
+	return c, p.expectOp(")")
+}
+
+func (p *parser) parseCase() (Expr, error) {
+	var (
+		c   = &Case{}
+		err error
+	)
+	if !p.isKeyword("when") {
+		if c.Operand, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	for p.acceptKeyword("when") {
+		var w When
+		if w.Cond, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("then"); err != nil {
+			return nil, err
+		}
+		if w.Result, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		c.Whens = append(c.Whens, w)
+	}
+	if len(c.Whens) == 0 {
+		return nil, p.unexpected("WHEN")
+	}
+	if p.acceptKeyword("else") {
+		if c.Else, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return c, p.expectKeyword("end")
+}
@@ -0,0 +1,359 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// aggregates are the names of the aggregate functions.
+var aggregates = map[string]bool{"count": true, "sum": true, "avg": true, "min": true, "max": true, "median": true}
+
+// Plan is a checked Select, ready to be run. The columns are resolved to their tables, the aliases and
+// positions of the select list are replaced in GROUP BY, HAVING and ORDER BY, and the aggregate calls are
+// replaced with Aggregate references.
+type Plan struct {
+	Distinct bool
+	// From is the zero Table for a query without FROM, which runs over one empty row.
+	From  Table
+	Joins []JoinPlan
+	Where Expr
+	// Grouped is set for a query with GROUP BY, HAVING or an aggregate. Its rows are grouped by the values of
+	// GroupBy, all of them are one group without GROUP BY, and Columns, Having and OrderBy are evaluated once
+	// for every group. A column outside of an aggregate takes its value from the first row of the group.
+	Grouped    bool
+	GroupBy    []Expr
+	Aggregates []*Call
+	Columns    []OutputColumn
+	Having     Expr
+	OrderBy    []OrderItem
+	// Limit is -1 without a limit.
+	Limit  int
+	Offset int
+}
+
+// JoinPlan is a join with the keys of its ON condition. Every pair of LeftKeys and RightKeys is compared
+// with = by the condition, a left key is a column of the tables before and the right key a column of the
+// joined table, so the matching rows may be looked up by them. The condition must still be checked.
+type JoinPlan struct {
+	Join
+	LeftKeys  []Expr
+	RightKeys []Expr
+}
+
+// OutputColumn is a column of the result. Star is set for * and table.*, which put all of the columns of
+// every table, or of the table with the alias Table, into the result.
+type OutputColumn struct {
+	Name  string
+	Expr  Expr
+	Star  bool
+	Table string
+}
+
+// Compile parses and plans a query.
+func Compile(src string) (*Plan, error) {
+	s, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return Prepare(s)
+}
+
+// Prepare checks a parsed Select and plans it.
+func Prepare(s *Select) (*Plan, error) {
+	var (
+		p       = &Plan{Distinct: s.Distinct, From: s.From, Limit: s.Limit, Offset: s.Offset}
+		aliases = make(map[string]bool)
+		err     error
+	)
+	for _, t := range append([]Table{s.From}, joinTables(s.Joins)...) {
+		if t.Name == "" {
+			continue
+		}
+		if aliases[t.Alias] {
+			return nil, fmt.Errorf("duplicate table alias %q", t.Alias)
+		}
+		aliases[t.Alias] = true
+	}
+	resolve := func(e Expr) (Expr, error) {
+		return resolveColumns(e, aliases)
+	}
+
+	// The columns of the select list.
+	var (
+		names = make(map[string]bool)
+		items = make(map[string]Expr)
+	)
+	for _, item := range s.Items {
+		if item.Star {
+			if item.Table != "" && !aliases[item.Table] {
+				return nil, fmt.Errorf("unknown table %q in %s", item.Table, item.Text)
+			}
+			if s.From.Name == "" {
+				return nil, errors.New("* without FROM")
+			}
+			p.Columns = append(p.Columns, OutputColumn{Star: true, Table: item.Table})
+			continue
+		}
+		col := OutputColumn{Name: item.Alias}
+		if col.Expr, err = resolve(item.Expr); err != nil {
+			return nil, err
+		}
+		if col.Name == "" {
+			col.Name = item.Text
+			if c, ok := col.Expr.(*Column); ok {
+				col.Name = c.Name
+			}
+		}
+		if names[col.Name] {
+			return nil, fmt.Errorf("duplicate column %q, give the columns different aliases", col.Name)
+		}
+		names[col.Name] = true
+		if item.Alias != "" {
+			items[item.Alias] = col.Expr
+		}
+		p.Columns = append(p.Columns, col)
+	}
+
+	// The aliases and positions of the select list in GROUP BY, HAVING and ORDER BY.
+	selected := func(e Expr, clause string) (Expr, error) {
+		if n, ok := intValue(e); ok {
+			if n < 1 || n > len(p.Columns) || p.Columns[n-1].Star {
+				return nil, fmt.Errorf("%s position %d is not a column of the select list", clause, n)
+			}
+			return p.Columns[n-1].Expr, nil
+		}
+		e, err := resolve(e)
+		if err != nil {
+			return nil, err
+		}
+		return transform(e, func(e Expr) (Expr, error) {
+			if c, ok := e.(*Column); ok && c.Table == "" {
+				if item, ok := items[c.Name]; ok {
+					return item, nil
+				}
+			}
+			return e, nil
+		})
+	}
+	for _, e := range s.GroupBy {
+		if e, err = selected(e, "GROUP BY"); err != nil {
+			return nil, err
+		}
+		if err = noAggregate(e, "GROUP BY"); err != nil {
+			return nil, err
+		}
+		p.GroupBy = append(p.GroupBy, e)
+	}
+	if s.Having != nil {
+		if p.Having, err = selected(s.Having, "HAVING"); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range s.OrderBy {
+		if item.Expr, err = selected(item.Expr, "ORDER BY"); err != nil {
+			return nil, err
+		}
+		p.OrderBy = append(p.OrderBy, item)
+	}
+
+	if s.Where != nil {
+		if p.Where, err = resolve(s.Where); err != nil {
+			return nil, err
+		}
+		if err = noAggregate(p.Where, "WHERE"); err != nil {
+			return nil, err
+		}
+	}
+	var previous = map[string]bool{s.From.Alias: true}
+	for _, j := range s.Joins {
+		jp := JoinPlan{Join: j}
+		if j.On != nil {
+			if jp.On, err = resolve(j.On); err != nil {
+				return nil, err
+			}
+			if err = noAggregate(jp.On, "ON"); err != nil {
+				return nil, err
+			}
+			jp.LeftKeys, jp.RightKeys = joinKeys(jp.On, previous, j.Table.Alias)
+		}
+		previous[j.Table.Alias] = true
+		p.Joins = append(p.Joins, jp)
+	}
+
+	// The aggregates, which are computed once for every group.
+	extract := func(e Expr) (Expr, error) {
+		return transform(e, func(e Expr) (Expr, error) {
+			c, ok := e.(*Call)
+			if !ok || !aggregates[c.Name] {
+				return e, nil
+			}
+			if err := checkAggregate(c); err != nil {
+				return nil, err
+			}
+			for i, a := range p.Aggregates {
+				if reflect.DeepEqual(a, c) {
+					return &Aggregate{Index: i}, nil
+				}
+			}
+			p.Aggregates = append(p.Aggregates, c)
+			return &Aggregate{Index: len(p.Aggregates) - 1}, nil
+		})
+	}
+	for i := range p.Columns {
+		if p.Columns[i].Expr != nil {
+			if p.Columns[i].Expr, err = extract(p.Columns[i].Expr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if p.Having != nil {
+		if p.Having, err = extract(p.Having); err != nil {
+			return nil, err
+		}
+	}
+	for i := range p.OrderBy {
+		if p.OrderBy[i].Expr, err = extract(p.OrderBy[i].Expr); err != nil {
+			return nil, err
+		}
+	}
+	p.Grouped = len(p.GroupBy) > 0 || len(p.Aggregates) > 0 || p.Having != nil
+	return p, nil
+}
+
+func joinTables(joins []Join) []Table {
+	var d = make([]Table, len(joins))
+	for i, j := range joins {
+		d[i] = j.Table
+	}
+	return d
+}
+
+// resolveColumns splits the identifiers of the columns into the table alias and the name.
+func resolveColumns(e Expr, aliases map[string]bool) (Expr, error) {
+	return transform(e, func(e Expr) (Expr, error) {
+		c, ok := e.(*Column)
+		if !ok || len(c.segments) == 0 {
+			return e, nil
+		}
+		if len(c.segments) > 1 && aliases[c.segments[0]] {
+			return &Column{Table: c.segments[0], Name: strings.Join(c.segments[1:], ".")}, nil
+		}
+		if len(aliases) == 0 {
+			return nil, fmt.Errorf("column %q without FROM", c.Name)
+		}
+		return &Column{Name: c.Name}, nil
+	})
+}
+
+func checkAggregate(c *Call) error {
+	if c.Star {
+		if c.Name != "count" || c.Distinct {
+			return fmt.Errorf("%s(*) is not supported", strings.ToUpper(c.Name))
+		}
+		return nil
+	}
+	if len(c.Args) != 1 {
+		return fmt.Errorf("%s takes one argument, got %d", strings.ToUpper(c.Name), len(c.Args))
+	}
+	return noAggregate(c.Args[0], "an aggregate")
+}
+
+// noAggregate returns an error if e calls an aggregate, which the clause does not allow.
+func noAggregate(e Expr, clause string) error {
+	_, err := transform(e, func(e Expr) (Expr, error) {
+		switch x := e.(type) {
+		case *Call:
+			if aggregates[x.Name] {
+				return nil, fmt.Errorf("aggregate %s in %s", strings.ToUpper(x.Name), clause)
+			}
+		case *Aggregate:
+			// The aggregates inside of an aggregate are replaced before it is checked.
+			return nil, fmt.Errorf("aggregate in %s", clause)
+		}
+		return e, nil
+	})
+	return err
+}
+
+// joinKeys returns the pairs of columns which the conjuncts of on compare with =, one of a table in left
+// and the other of the table right.
+func joinKeys(on Expr, left map[string]bool, right string) ([]Expr, []Expr) {
+	var (
+		leftKeys, rightKeys []Expr
+		walk                func(e Expr)
+	)
+	walk = func(e Expr) {
+		b, ok := e.(*Binary)
+		if !ok {
+			return
+		}
+		switch b.Op {
+		case "and":
+			walk(b.X)
+			walk(b.Y)
+		case "=":
+			x, okX := b.X.(*Column)
+			y, okY := b.Y.(*Column)
+			switch {
+			case !okX || !okY:
+			case left[x.Table] && y.Table == right:
+				leftKeys, rightKeys = append(leftKeys, x), append(rightKeys, y)
+			case left[y.Table] && x.Table == right:
+				leftKeys, rightKeys = append(leftKeys, y), append(rightKeys, x)
+			}
+		}
+	}
+	walk(on)
+	return leftKeys, rightKeys
+}
+
+// transform returns a copy of e in which every expression is replaced with the result of fn, the children
+// before their parent. An error of fn stops the walk.
+func transform(e Expr, fn func(Expr) (Expr, error)) (Expr, error) {
+	var (
+		err error
+		t   = func(e Expr) Expr {
+			if e == nil || err != nil {
+				return e
+			}
+			var d Expr
+			d, err = transform(e, fn)
+			return d
+		}
+		list = func(es []Expr) []Expr {
+			var d = make([]Expr, len(es))
+			for i, e := range es {
+				d[i] = t(e)
+			}
+			return d
+		}
+	)
+	switch x := e.(type) {
+	case *Unary:
+		e = &Unary{Op: x.Op, X: t(x.X)}
+	case *Binary:
+		e = &Binary{Op: x.Op, X: t(x.X), Y: t(x.Y)}
+	case *Like:
+		e = &Like{X: t(x.X), Pattern: t(x.Pattern), Fold: x.Fold, Not: x.Not}
+	case *In:
+		e = &In{X: t(x.X), List: list(x.List), Not: x.Not}
+	case *Between:
+		e = &Between{X: t(x.X), Low: t(x.Low), High: t(x.High), Not: x.Not}
+	case *IsNull:
+		e = &IsNull{X: t(x.X), Not: x.Not}
+	case *Call:
+		e = &Call{Name: x.Name, Args: list(x.Args), Star: x.Star, Distinct: x.Distinct}
+	case *Case:
+		c := &Case{Operand: t(x.Operand), Else: t(x.Else), Whens: make([]When, len(x.Whens))}
+		for i, w := range x.Whens {
+			c.Whens[i] = When{Cond: t(w.Cond), Result: t(w.Result)}
+		}
+		e = c
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fn(e)
+}
@@ -0,0 +1,70 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/shopspring/decimal"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse(`SELECT DISTINCT u.name AS "user name", -price * 2 total, o.*
+		FROM users u LEFT OUTER JOIN orders AS o ON o.user_id = u.id, tags -- the tags
+		WHERE NOT a.b.0 BETWEEN 1 AND 2 OR name NOT LIKE 'a''%' AND x IS NOT NULL
+		ORDER BY total DESC NULLS LAST, 1 LIMIT 10 OFFSET 5;`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, s.Distinct, true)
+	assert.Equal(t, s.Items[0].Alias, "user name")
+	assert.Equal(t, s.Items[1].Text, "-price * 2")
+	assert.Equal(t, s.Items[1].Expr, &Binary{Op: "*", X: &Unary{Op: "-", X: &Column{Name: "price", segments: []string{"price"}}},
+		Y: &Literal{Value: decimal.New(2, 0)}})
+	assert.Equal(t, s.Items[2], SelectItem{Star: true, Table: "o", Text: "o.*"})
+	assert.Equal(t, s.From, Table{Name: "users", Alias: "u"})
+	assert.Equal(t, len(s.Joins), 2)
+	assert.Equal(t, s.Joins[0].Kind, LeftJoin)
+	assert.Equal(t, s.Joins[1], Join{Kind: CrossJoin, Table: Table{Name: "tags", Alias: "tags"}})
+	or := s.Where.(*Binary)
+	assert.Equal(t, or.Op, "or")
+	assert.Equal(t, or.X.(*Unary).X.(*Between).X, &Column{Name: "a.b.0", segments: []string{"a", "b", "0"}})
+	assert.Equal(t, or.Y.(*Binary).X.(*Like).Pattern, &Literal{Value: "a'%"})
+	assert.Equal(t, or.Y.(*Binary).Y, &IsNull{X: &Column{Name: "x", segments: []string{"x"}}, Not: true})
+	assert.Equal(t, s.OrderBy[0].Nulls, NullsLast)
+	assert.Equal(t, s.Limit, 10)
+	assert.Equal(t, s.Offset, 5)
+
+	for _, src := range []string{"SELECT", "SELECT a FROM", "SELECT a b c", "SELECT 'a", "SELECT a LIMIT -1", "SELECT #"} {
+		_, err := Parse(src)
+		var syntax *SyntaxError
+		assert.Equal(t, errors.As(err, &syntax), true, src)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	p, err := Compile(`SELECT u.name, COUNT(*) AS n, SUM(o.total) FROM users u JOIN orders o ON o.user_id = u.id AND o.total > 1
+		GROUP BY 1 HAVING n > 1 ORDER BY SUM(o.total) DESC`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p.Grouped, true)
+	assert.Equal(t, p.Columns[0], OutputColumn{Name: "name", Expr: &Column{Table: "u", Name: "name"}})
+	assert.Equal(t, p.Columns[1].Expr, &Aggregate{Index: 0})
+	assert.Equal(t, p.Columns[2].Name, "SUM(o.total)")
+	assert.Equal(t, p.GroupBy, []Expr{&Column{Table: "u", Name: "name"}})
+	assert.Equal(t, p.Having, &Binary{Op: ">", X: &Aggregate{Index: 0}, Y: &Literal{Value: decimal.New(1, 0)}})
+	assert.Equal(t, p.OrderBy[0].Expr, &Aggregate{Index: 1})
+	assert.Equal(t, len(p.Aggregates), 2)
+	assert.Equal(t, p.Joins[0].LeftKeys, []Expr{&Column{Table: "u", Name: "id"}})
+	assert.Equal(t, p.Joins[0].RightKeys, []Expr{&Column{Table: "o", Name: "user_id"}})
+
+	for _, src := range []string{
+		"SELECT a FROM t JOIN t ON t.a = t.b",
+		"SELECT a, b AS a FROM t",
+		"SELECT a FROM t WHERE SUM(a) > 1",
+		"SELECT SUM(COUNT(a)) FROM t",
+		"SELECT a FROM t ORDER BY 2",
+		"SELECT x.* FROM t",
+		"SELECT a",
+	} {
+		_, err := Compile(src)
+		assert.Equal(t, err != nil, true, src)
+	}
+}
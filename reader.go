@@ -0,0 +1,148 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hulklab/collection/hamt"
+	"github.com/hulklab/collection/vector_trie"
+	"github.com/shopspring/decimal"
+)
+
+// CollectReader reads a json array or object from r. The items of an array are decoded one by one, so the
+// text of the whole document is never held in memory, and they are collected into the collection of their
+// kind like JSONPath does, an empty array into an empty MapArrayCollection. An object becomes a
+// MapCollection. The numbers, also the nested ones, are decoded into decimal.Decimal, so large integers and
+// precise decimals are not rounded through float64.
+func CollectReader(r io.Reader) Collection {
+	dec := newJSONDecoder(r)
+	delim, err := jsonStart(dec)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	if delim == '{' {
+		var m = make(map[string]interface{})
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return BaseCollection{err: jsonError(err)}
+			}
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return BaseCollection{err: fmt.Errorf("member %q: %w", t, jsonError(err))}
+			}
+			m[t.(string)] = jsonNumbers(v)
+		}
+		if err := jsonEnd(dec); err != nil {
+			return BaseCollection{err: err}
+		}
+		return newMapCollection(hamt.FromMap(m))
+	}
+
+	var items = make([]interface{}, 0)
+	if err := jsonItemsOf(dec, func(v interface{}) bool {
+		items = append(items, v)
+		return true
+	}); err != nil {
+		return BaseCollection{err: err}
+	}
+	return collectNodes(items, "use StreamJSON and AllE")
+}
+
+// StreamJSON returns a LazyCollection of the items of the json array read from r. The items are decoded
+// one at a time while the pipeline runs, with their numbers decoded into decimal.Decimal like
+// CollectReader. Collect collects them into the collection of their kind. The reader is consumed by the
+// first run of the pipeline, a second run returns an ErrWrongValue.
+func StreamJSON(r io.Reader) LazyCollection {
+	dec := newJSONDecoder(r)
+	return LazyCollection{source: onceSource(func(yield func(value interface{}) bool) error {
+		delim, err := jsonStart(dec)
+		if err != nil {
+			return err
+		}
+		if delim != '[' {
+			return fmt.Errorf("%w: not a json array", ErrWrongValue)
+		}
+		return jsonItemsOf(dec, yield)
+	}), wrap: func(l *vector_trie.List) Collection {
+		return collectNodes(l.ToSlice(), "use AllE")
+	}}
+}
+
+func newJSONDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec
+}
+
+// jsonStart reads the delimiter which starts the document, the start of an array or an object.
+func jsonStart(dec *json.Decoder) (json.Delim, error) {
+	t, err := dec.Token()
+	if err == io.EOF {
+		return 0, fmt.Errorf("%w: empty json", ErrWrongValue)
+	}
+	if err != nil {
+		return 0, jsonError(err)
+	}
+	delim, ok := t.(json.Delim)
+	if !ok || (delim != '[' && delim != '{') {
+		return 0, fmt.Errorf("%w: not a json array or object", ErrWrongValue)
+	}
+	return delim, nil
+}
+
+// jsonItemsOf decodes the items of the array whose start has been read, and passes them to yield until it
+// returns false.
+func jsonItemsOf(dec *json.Decoder, yield func(value interface{}) bool) error {
+	for i := 0; dec.More(); i++ {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("item %d: %w", i, jsonError(err))
+		}
+		if !yield(jsonNumbers(v)) {
+			return nil
+		}
+	}
+	return jsonEnd(dec)
+}
+
+// jsonEnd reads the delimiter which ends the document, and checks that nothing follows it.
+func jsonEnd(dec *json.Decoder) error {
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: data after the end of the json", ErrWrongValue)
+	}
+	return nil
+}
+
+// jsonError wraps the errors of malformed json with ErrWrongValue.
+func jsonError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%w: %v", ErrWrongValue, err)
+	}
+	return err
+}
+
+// jsonNumbers replaces the json.Number values of v, also the nested ones, with decimal.Decimal.
+func jsonNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if d, err := decimal.NewFromString(t.String()); err == nil {
+			return d
+		}
+	case map[string]interface{}:
+		for k, value := range t {
+			t[k] = jsonNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range t {
+			t[i] = jsonNumbers(value)
+		}
+	}
+	return v
+}
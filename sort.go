@@ -0,0 +1,249 @@
+package collection
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// NullOrder decides where the items without a value for a sort key are put.
+type NullOrder int
+
+const (
+	// NullsDefault sorts nil as the smallest value, so nulls come first in ascending order and last in
+	// descending order.
+	NullsDefault NullOrder = iota
+	// NullsFirst puts nulls first in either direction.
+	NullsFirst
+	// NullsLast puts nulls last in either direction.
+	NullsLast
+)
+
+// SortKey is a key of SortBy and SortByDesc. Key is a key or path of the items of a MapArrayCollection, and
+// is empty for the items of a StringArrayCollection or NumberArrayCollection. Compare replaces the default
+// comparison, it returns a negative number, zero or a positive number like strings.Compare.
+type SortKey struct {
+	Key     string
+	Desc    bool
+	Nulls   NullOrder
+	Compare func(a, b interface{}) int
+}
+
+// Asc returns the SortKey which sorts by key in ascending order.
+func Asc(key string) SortKey {
+	return SortKey{Key: key}
+}
+
+// Desc returns the SortKey which sorts by key in descending order.
+func Desc(key string) SortKey {
+	return SortKey{Key: key, Desc: true}
+}
+
+// sortKeys turns the arguments of SortBy into SortKeys. A key is a string, a SortKey, or a comparison function
+// of the items themselves.
+func sortKeys(keys []interface{}, desc bool) ([]SortKey, error) {
+	var d = make([]SortKey, len(keys))
+	for i, key := range keys {
+		switch k := key.(type) {
+		case string:
+			d[i] = SortKey{Key: k}
+		case SortKey:
+			d[i] = k
+		case func(a, b interface{}) int:
+			d[i] = SortKey{Compare: k}
+		default:
+			return nil, wrongType("string, SortKey or func(a, b interface{}) int", key)
+		}
+		if desc {
+			d[i].Desc = !d[i].Desc
+		}
+	}
+	return d, nil
+}
+
+func (k SortKey) compare(a, b interface{}) int {
+	if k.Nulls != NullsDefault && (a == nil || b == nil) {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case (a == nil) == (k.Nulls == NullsFirst):
+			return -1
+		default:
+			return 1
+		}
+	}
+	var c int
+	if k.Compare != nil {
+		c = k.Compare(a, b)
+	} else {
+		c = compareValues(a, b)
+	}
+	if k.Desc {
+		return -c
+	}
+	return c
+}
+
+// sortOrder returns the indexes of n items in sorted order. The values of every key are looked up once, and
+// the original index breaks the ties, which keeps the sort stable while pdqsort sorts the entries in place.
+func sortOrder(n int, keys []SortKey, value func(i int, key string) interface{}) []int {
+	type entry struct {
+		index  int
+		values []interface{}
+	}
+	var entries = make([]entry, n)
+	for i := range entries {
+		entries[i] = entry{index: i, values: make([]interface{}, len(keys))}
+		for j, k := range keys {
+			entries[i].values[j] = value(i, k.Key)
+		}
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		for j, k := range keys {
+			if c := k.compare(a.values[j], b.values[j]); c != 0 {
+				return c
+			}
+		}
+		return a.index - b.index
+	})
+	var d = make([]int, n)
+	for i, e := range entries {
+		d[i] = e.index
+	}
+	return d
+}
+
+// itemSortKeys checks the keys of the collections whose items are not maps, and sorts ascending by the item
+// without keys.
+func itemSortKeys(keys []interface{}, desc bool) ([]SortKey, error) {
+	if len(keys) == 0 {
+		keys = []interface{}{""}
+	}
+	d, err := sortKeys(keys, desc)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range d {
+		if k.Key != "" {
+			return nil, fmt.Errorf("%w: sort key %q of an array without maps", ErrInvalidArgument, k.Key)
+		}
+	}
+	return d, nil
+}
+
+// sortDecimals sorts d in place.
+func sortDecimals(d []decimal.Decimal, desc bool) {
+	slices.SortFunc(d, func(a, b decimal.Decimal) int {
+		if desc {
+			return b.Cmp(a)
+		}
+		return a.Cmp(b)
+	})
+}
+
+// SortBy sorts the collection by the value at the given keys or paths. A key is a string, which sorts in
+// ascending order, or a SortKey with a direction, the place of nulls and an optional comparison function.
+// Later keys break the ties of earlier ones, and the sort is stable.
+func (c MapArrayCollection) SortBy(keys ...interface{}) Collection {
+	return c.sortBy(keys, false)
+}
+
+// SortByDesc has the same signature as the sortBy method, but will sort the collection in the opposite order.
+func (c MapArrayCollection) SortByDesc(keys ...interface{}) Collection {
+	return c.sortBy(keys, true)
+}
+
+func (c MapArrayCollection) sortBy(keys []interface{}, desc bool) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	if len(keys) == 0 {
+		return BaseCollection{err: fmt.Errorf("%w: no sort key", ErrInvalidArgument)}
+	}
+	sk, err := sortKeys(keys, desc)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	items := c.items()
+	var d = make([]map[string]interface{}, len(items))
+	for i, j := range sortOrder(len(items), sk, func(i int, key string) interface{} {
+		return lookupPath(items[i], key)
+	}) {
+		d[i] = items[j]
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// Sort sorts the collection.
+func (c StringArrayCollection) Sort() Collection {
+	return c.SortBy()
+}
+
+// SortBy sorts the collection in ascending order, or by the given SortKeys or comparison functions, whose
+// Key must be empty. The sort is stable.
+func (c StringArrayCollection) SortBy(keys ...interface{}) Collection {
+	return c.sortBy(keys, false)
+}
+
+// SortByDesc has the same signature as the sortBy method, but will sort the collection in the opposite order.
+func (c StringArrayCollection) SortByDesc(keys ...interface{}) Collection {
+	return c.sortBy(keys, true)
+}
+
+func (c StringArrayCollection) sortBy(keys []interface{}, desc bool) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	sk, err := itemSortKeys(keys, desc)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	items := c.items()
+	var d = make([]string, len(items))
+	for i, j := range sortOrder(len(items), sk, func(i int, _ string) interface{} {
+		return items[i]
+	}) {
+		d[i] = items[j]
+	}
+	return newStringArrayCollection(stringList(d))
+}
+
+// Sort sorts the collection.
+func (c NumberArrayCollection) Sort() Collection {
+	return c.sortBy(nil, false)
+}
+
+// SortBy sorts the collection in ascending order, or by the given SortKeys or comparison functions, whose
+// Key must be empty. The sort is stable.
+func (c NumberArrayCollection) SortBy(keys ...interface{}) Collection {
+	return c.sortBy(keys, false)
+}
+
+// SortByDesc has the same signature as the sortBy method, but will sort the collection in the opposite order.
+func (c NumberArrayCollection) SortByDesc(keys ...interface{}) Collection {
+	return c.sortBy(keys, true)
+}
+
+func (c NumberArrayCollection) sortBy(keys []interface{}, desc bool) Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	if len(keys) == 0 {
+		var d = c.items()
+		sortDecimals(d, desc)
+		return newNumberArrayCollection(decimalList(d))
+	}
+	sk, err := itemSortKeys(keys, desc)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	items := c.items()
+	var d = make([]decimal.Decimal, len(items))
+	for i, j := range sortOrder(len(items), sk, func(i int, _ string) interface{} {
+		return items[i]
+	}) {
+		d[i] = items[j]
+	}
+	return newNumberArrayCollection(decimalList(d))
+}
@@ -0,0 +1,540 @@
+package collection
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// Interpolation decides the value of a quantile which lies between two items.
+type Interpolation int
+
+const (
+	// QuantileLinear interpolates linearly between the two items.
+	QuantileLinear Interpolation = iota
+	// QuantileLower takes the lower item.
+	QuantileLower
+	// QuantileHigher takes the higher item.
+	QuantileHigher
+	// QuantileNearest takes the nearest item, or the one with the even index when both are as near.
+	QuantileNearest
+	// QuantileMidpoint takes the average of the two items.
+	QuantileMidpoint
+)
+
+// Description is the summary of the values returned from Describe. StdDev is the sample standard deviation,
+// and P25, Median and P75 are the linearly interpolated quartiles.
+type Description struct {
+	Count  int
+	Mean   decimal.Decimal
+	StdDev decimal.Decimal
+	Min    decimal.Decimal
+	P25    decimal.Decimal
+	Median decimal.Decimal
+	P75    decimal.Decimal
+	Max    decimal.Decimal
+}
+
+var (
+	decimalOne = decimal.New(1, 0)
+	decimalTwo = decimal.New(2, 0)
+)
+
+// statsValues returns the values the statistics are computed from, the items of a NumberArrayCollection or
+// the non-nil values at key of a MapArrayCollection.
+func (c NumberArrayCollection) statsValues(_ []string) ([]decimal.Decimal, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.items(), nil
+}
+
+func (c MapArrayCollection) statsValues(key []string) ([]decimal.Decimal, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: no key", ErrInvalidArgument)
+	}
+	values := aggregateValues(c.items(), key[0])
+	var d = make([]decimal.Decimal, len(values))
+	for i, v := range values {
+		if !isNumber(v) {
+			return nil, wrongType("number", v)
+		}
+		d[i] = nd(v)
+	}
+	return d, nil
+}
+
+// quantileArgument returns the percentile or quantile f as a decimal, and ErrInvalidArgument if it is not a
+// number.
+func quantileArgument(f float64) (decimal.Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return decimal.Decimal{}, fmt.Errorf("%w: %v is not a number", ErrInvalidArgument, f)
+	}
+	return decimal.NewFromFloat(f), nil
+}
+
+// quantile returns the q-quantile of the sorted values, q is between 0 and 1.
+func quantile(sorted []decimal.Decimal, q decimal.Decimal, interpolation Interpolation) (decimal.Decimal, error) {
+	if len(sorted) == 0 {
+		return decimal.Decimal{}, fmt.Errorf("%w: no values", ErrWrongValue)
+	}
+	if q.LessThan(decimal.New(0, 0)) || q.GreaterThan(decimalOne) {
+		return decimal.Decimal{}, fmt.Errorf("%w: quantile %s is not between 0 and 1", ErrInvalidArgument, q)
+	}
+	var (
+		h    = q.Mul(decimal.New(int64(len(sorted)-1), 0))
+		lo   = int(h.Floor().IntPart())
+		hi   = int(h.Ceil().IntPart())
+		frac = h.Sub(h.Floor())
+	)
+	switch interpolation {
+	case QuantileLinear:
+		return sorted[lo].Add(frac.Mul(sorted[hi].Sub(sorted[lo]))), nil
+	case QuantileLower:
+		return sorted[lo], nil
+	case QuantileHigher:
+		return sorted[hi], nil
+	case QuantileNearest:
+		half := decimal.New(5, -1)
+		if frac.GreaterThan(half) || (frac.Equal(half) && lo%2 == 1) {
+			return sorted[hi], nil
+		}
+		return sorted[lo], nil
+	case QuantileMidpoint:
+		return sorted[lo].Add(sorted[hi]).Div(decimalTwo), nil
+	default:
+		return decimal.Decimal{}, fmt.Errorf("%w: unknown interpolation %d", ErrInvalidArgument, interpolation)
+	}
+}
+
+// sortedCopy returns the values in ascending order without changing them.
+func sortedCopy(values []decimal.Decimal) []decimal.Decimal {
+	var d = make([]decimal.Decimal, len(values))
+	copy(d, values)
+	sortDecimals(d, false)
+	return d
+}
+
+// moments returns the mean and the second, third and fourth central moments of the values.
+func moments(values []decimal.Decimal) (mean, m2, m3, m4 decimal.Decimal) {
+	var (
+		n   = decimal.New(int64(len(values)), 0)
+		sum = decimal.New(0, 0)
+	)
+	for _, v := range values {
+		sum = sum.Add(v)
+	}
+	mean = sum.Div(n)
+	m2, m3, m4 = decimal.New(0, 0), decimal.New(0, 0), decimal.New(0, 0)
+	for _, v := range values {
+		d := v.Sub(mean)
+		d2 := d.Mul(d)
+		m2 = m2.Add(d2)
+		m3 = m3.Add(d2.Mul(d))
+		m4 = m4.Add(d2.Mul(d2))
+	}
+	return mean, m2.Div(n), m3.Div(n), m4.Div(n)
+}
+
+// variance returns the population variance of the values, or the sample variance which divides by n-1.
+func variance(values []decimal.Decimal, sample bool) (decimal.Decimal, error) {
+	n := len(values)
+	if n == 0 || (sample && n < 2) {
+		return decimal.Decimal{}, fmt.Errorf("%w: not enough values for a variance", ErrWrongValue)
+	}
+	_, m2, _, _ := moments(values)
+	if sample {
+		return m2.Mul(decimal.New(int64(n), 0)).Div(decimal.New(int64(n-1), 0)), nil
+	}
+	return m2, nil
+}
+
+// sqrtDecimal returns the square root of x rounded to decimal.DivisionPrecision places, using Newton's method.
+func sqrtDecimal(x decimal.Decimal) decimal.Decimal {
+	if x.Sign() <= 0 {
+		return decimal.New(0, 0)
+	}
+	var (
+		places = int32(decimal.DivisionPrecision)
+		// Start from the float square root, so a few iterations add the digits a float lacks.
+		f, _ = x.Float64()
+		z    = decimal.NewFromFloat(math.Sqrt(f))
+	)
+	if z.Sign() <= 0 {
+		z = x
+	}
+	for i := 0; i < 100; i++ {
+		next := z.Add(x.DivRound(z, places+2)).Div(decimalTwo).Round(places + 2)
+		if next.Equal(z) {
+			break
+		}
+		z = next
+	}
+	return z.Round(places)
+}
+
+func stdDev(values []decimal.Decimal, sample bool) (decimal.Decimal, error) {
+	v, err := variance(values, sample)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return sqrtDecimal(v), nil
+}
+
+// skewness returns the population skewness m3 / m2^1.5 of the values.
+func skewness(values []decimal.Decimal) (decimal.Decimal, error) {
+	if len(values) == 0 {
+		return decimal.Decimal{}, fmt.Errorf("%w: no values", ErrWrongValue)
+	}
+	_, m2, m3, _ := moments(values)
+	if m2.IsZero() {
+		return decimal.Decimal{}, fmt.Errorf("%w: the values do not vary", ErrWrongValue)
+	}
+	return m3.Div(m2.Mul(sqrtDecimal(m2))), nil
+}
+
+// kurtosis returns the population excess kurtosis m4 / m2^2 - 3 of the values.
+func kurtosis(values []decimal.Decimal) (decimal.Decimal, error) {
+	if len(values) == 0 {
+		return decimal.Decimal{}, fmt.Errorf("%w: no values", ErrWrongValue)
+	}
+	_, m2, _, m4 := moments(values)
+	if m2.IsZero() {
+		return decimal.Decimal{}, fmt.Errorf("%w: the values do not vary", ErrWrongValue)
+	}
+	return m4.Div(m2.Mul(m2)).Sub(decimal.New(3, 0)), nil
+}
+
+func describe(values []decimal.Decimal) (Description, error) {
+	if len(values) == 0 {
+		return Description{}, fmt.Errorf("%w: no values", ErrWrongValue)
+	}
+	var (
+		sorted = sortedCopy(values)
+		d      = Description{Count: len(values), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	)
+	d.Mean, _, _, _ = moments(values)
+	if len(values) > 1 {
+		d.StdDev, _ = stdDev(values, true)
+	}
+	d.P25, _ = quantile(sorted, decimal.New(25, -2), QuantileLinear)
+	d.Median, _ = quantile(sorted, decimal.New(5, -1), QuantileLinear)
+	d.P75, _ = quantile(sorted, decimal.New(75, -2), QuantileLinear)
+	return d, nil
+}
+
+// Percentile returns the p-th percentile of the values, p is between 0 and 100. The value between two
+// items is interpolated linearly.
+func (c NumberArrayCollection) Percentile(p float64, key ...string) decimal.Decimal {
+	d, _ := c.PercentileE(p, key...)
+	return d
+}
+
+func (c NumberArrayCollection) PercentileE(p float64, key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	d, err := quantileArgument(p)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return quantile(sortedCopy(values), d.Div(decimal.New(100, 0)), QuantileLinear)
+}
+
+// Quantile returns the q-quantile of the values, q is between 0 and 1. The interpolation decides the value
+// between two items.
+func (c NumberArrayCollection) Quantile(q float64, interpolation Interpolation, key ...string) decimal.Decimal {
+	d, _ := c.QuantileE(q, interpolation, key...)
+	return d
+}
+
+func (c NumberArrayCollection) QuantileE(q float64, interpolation Interpolation, key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	d, err := quantileArgument(q)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return quantile(sortedCopy(values), d, interpolation)
+}
+
+// Variance returns the population variance of the values.
+func (c NumberArrayCollection) Variance(key ...string) decimal.Decimal {
+	d, _ := c.VarianceE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) VarianceE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return variance(values, false)
+}
+
+// SampleVariance returns the sample variance of the values, which divides by n-1.
+func (c NumberArrayCollection) SampleVariance(key ...string) decimal.Decimal {
+	d, _ := c.SampleVarianceE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) SampleVarianceE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return variance(values, true)
+}
+
+// StdDev returns the population standard deviation of the values.
+func (c NumberArrayCollection) StdDev(key ...string) decimal.Decimal {
+	d, _ := c.StdDevE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) StdDevE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return stdDev(values, false)
+}
+
+// SampleStdDev returns the sample standard deviation of the values.
+func (c NumberArrayCollection) SampleStdDev(key ...string) decimal.Decimal {
+	d, _ := c.SampleStdDevE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) SampleStdDevE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return stdDev(values, true)
+}
+
+// IQR returns the interquartile range of the values, the difference of the 75th and the 25th percentile.
+func (c NumberArrayCollection) IQR(key ...string) decimal.Decimal {
+	d, _ := c.IQRE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) IQRE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	sorted := sortedCopy(values)
+	p25, err := quantile(sorted, decimal.New(25, -2), QuantileLinear)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	p75, _ := quantile(sorted, decimal.New(75, -2), QuantileLinear)
+	return p75.Sub(p25), nil
+}
+
+// Skewness returns the population skewness of the values.
+func (c NumberArrayCollection) Skewness(key ...string) decimal.Decimal {
+	d, _ := c.SkewnessE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) SkewnessE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return skewness(values)
+}
+
+// Kurtosis returns the population excess kurtosis of the values, which is 0 for a normal distribution.
+func (c NumberArrayCollection) Kurtosis(key ...string) decimal.Decimal {
+	d, _ := c.KurtosisE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) KurtosisE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return kurtosis(values)
+}
+
+// Describe returns the count, mean, standard deviation, minimum, quartiles and maximum of the values.
+func (c NumberArrayCollection) Describe(key ...string) Description {
+	d, _ := c.DescribeE(key...)
+	return d
+}
+
+func (c NumberArrayCollection) DescribeE(key ...string) (Description, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return Description{}, err
+	}
+	return describe(values)
+}
+
+// Percentile returns the p-th percentile of the values at key, p is between 0 and 100. The value between two
+// items is interpolated linearly.
+func (c MapArrayCollection) Percentile(p float64, key ...string) decimal.Decimal {
+	d, _ := c.PercentileE(p, key...)
+	return d
+}
+
+func (c MapArrayCollection) PercentileE(p float64, key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	d, err := quantileArgument(p)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return quantile(sortedCopy(values), d.Div(decimal.New(100, 0)), QuantileLinear)
+}
+
+// Quantile returns the q-quantile of the values at key, q is between 0 and 1. The interpolation decides the value
+// between two items.
+func (c MapArrayCollection) Quantile(q float64, interpolation Interpolation, key ...string) decimal.Decimal {
+	d, _ := c.QuantileE(q, interpolation, key...)
+	return d
+}
+
+func (c MapArrayCollection) QuantileE(q float64, interpolation Interpolation, key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	d, err := quantileArgument(q)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return quantile(sortedCopy(values), d, interpolation)
+}
+
+// Variance returns the population variance of the values at key.
+func (c MapArrayCollection) Variance(key ...string) decimal.Decimal {
+	d, _ := c.VarianceE(key...)
+	return d
+}
+
+func (c MapArrayCollection) VarianceE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return variance(values, false)
+}
+
+// SampleVariance returns the sample variance of the values at key, which divides by n-1.
+func (c MapArrayCollection) SampleVariance(key ...string) decimal.Decimal {
+	d, _ := c.SampleVarianceE(key...)
+	return d
+}
+
+func (c MapArrayCollection) SampleVarianceE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return variance(values, true)
+}
+
+// StdDev returns the population standard deviation of the values at key.
+func (c MapArrayCollection) StdDev(key ...string) decimal.Decimal {
+	d, _ := c.StdDevE(key...)
+	return d
+}
+
+func (c MapArrayCollection) StdDevE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return stdDev(values, false)
+}
+
+// SampleStdDev returns the sample standard deviation of the values at key.
+func (c MapArrayCollection) SampleStdDev(key ...string) decimal.Decimal {
+	d, _ := c.SampleStdDevE(key...)
+	return d
+}
+
+func (c MapArrayCollection) SampleStdDevE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return stdDev(values, true)
+}
+
+// IQR returns the interquartile range of the values at key, the difference of the 75th and the 25th percentile.
+func (c MapArrayCollection) IQR(key ...string) decimal.Decimal {
+	d, _ := c.IQRE(key...)
+	return d
+}
+
+func (c MapArrayCollection) IQRE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	sorted := sortedCopy(values)
+	p25, err := quantile(sorted, decimal.New(25, -2), QuantileLinear)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	p75, _ := quantile(sorted, decimal.New(75, -2), QuantileLinear)
+	return p75.Sub(p25), nil
+}
+
+// Skewness returns the population skewness of the values at key.
+func (c MapArrayCollection) Skewness(key ...string) decimal.Decimal {
+	d, _ := c.SkewnessE(key...)
+	return d
+}
+
+func (c MapArrayCollection) SkewnessE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return skewness(values)
+}
+
+// Kurtosis returns the population excess kurtosis of the values at key, which is 0 for a normal distribution.
+func (c MapArrayCollection) Kurtosis(key ...string) decimal.Decimal {
+	d, _ := c.KurtosisE(key...)
+	return d
+}
+
+func (c MapArrayCollection) KurtosisE(key ...string) (decimal.Decimal, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return kurtosis(values)
+}
+
+// Describe returns the count, mean, standard deviation, minimum, quartiles and maximum of the values at key.
+func (c MapArrayCollection) Describe(key ...string) Description {
+	d, _ := c.DescribeE(key...)
+	return d
+}
+
+func (c MapArrayCollection) DescribeE(key ...string) (Description, error) {
+	values, err := c.statsValues(key)
+	if err != nil {
+		return Description{}, err
+	}
+	return describe(values)
+}
@@ -2,25 +2,53 @@ package collection
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"math"
 	"math/rand"
 	"time"
+
+	"github.com/hulklab/collection/hamt"
+	"github.com/hulklab/collection/vector_trie"
 )
 
+// StringArrayCollection is backed by a persistent vector trie, so Push, Pop, Shift, Splice and the other
+// immutable updates share the untouched items with the original collection instead of copying them.
 type StringArrayCollection struct {
-	value []string
+	value *vector_trie.List
 	BaseCollection
 }
 
+func newStringArrayCollection(l *vector_trie.List) StringArrayCollection {
+	return StringArrayCollection{l, BaseCollection{length: l.Len()}}
+}
+
+func stringList(s []string) *vector_trie.List {
+	var l = vector_trie.New().Transient()
+	for _, v := range s {
+		l.Append(v)
+	}
+	return l.Persistent()
+}
+
+// items returns the collection's values as a plain golang slice.
+func (c StringArrayCollection) items() []string {
+	var s = make([]string, 0, c.value.Len())
+	c.value.Range(func(_ int, v interface{}) bool {
+		s = append(s, v.(string))
+		return true
+	})
+	return s
+}
+
 // Join joins the collection's values with a string.
 func (c StringArrayCollection) Join(delimiter string) string {
+	items := c.items()
 	s := ""
-	for i := 0; i < len(c.value); i++ {
-		if i != len(c.value)-1 {
-			s += c.value[i] + delimiter
+	for i := 0; i < len(items); i++ {
+		if i != len(items)-1 {
+			s += items[i] + delimiter
 		} else {
-			s += c.value[i]
+			s += items[i]
 		}
 	}
 	return s
@@ -32,15 +60,15 @@ func (c StringArrayCollection) JoinE(delimiter string) (string, error) {
 
 // Length return the length of the collection.
 func (c StringArrayCollection) Length() int {
-	return len(c.value)
+	return c.value.Len()
 }
 
 // Combine combines the values of the collection, as keys, with the values of another array or collection.
 func (c StringArrayCollection) Combine(value []interface{}) Collection {
+	items := c.items()
 	var (
-		m      = make(map[string]interface{}, 0)
+		m      = hamt.New().Transient()
 		length = c.length
-		d      MapCollection
 	)
 
 	if length > len(value) {
@@ -48,72 +76,59 @@ func (c StringArrayCollection) Combine(value []interface{}) Collection {
 	}
 
 	for i := 0; i < length; i++ {
-		m[c.value[i]] = value[i]
+		m.Assoc(items[i], value[i])
 	}
 
-	d.value = m
-	d.length = len(m)
-
-	return d
+	return newMapCollection(m.Persistent())
 }
 
 // Prepend adds an item to the beginning of the collection.
 func (c StringArrayCollection) Prepend(values ...interface{}) Collection {
+	s, ok := values[0].(string)
+	if !ok {
+		return BaseCollection{err: wrongType("string", values[0])}
+	}
+	var d = vector_trie.New().Transient()
 
-	var d StringArrayCollection
-
-	var n = make([]string, len(c.value))
-	copy(n, c.value)
-
-	d.value = append([]string{values[0].(string)}, n...)
-	d.length = len(d.value)
+	d.Append(s)
+	c.value.Range(func(_ int, v interface{}) bool {
+		d.Append(v)
+		return true
+	})
 
-	return d
+	return newStringArrayCollection(d.Persistent())
 }
 
 // Splice removes and returns a slice of items starting at the specified index.
 func (c StringArrayCollection) Splice(index ...int) Collection {
 
 	if len(index) == 1 {
-		var n = make([]string, len(c.value))
-		copy(n, c.value)
-		n = n[index[0]:]
-
-		return StringArrayCollection{n, BaseCollection{length: len(n)}}
+		return newStringArrayCollection(c.value.Slice(index[0], c.value.Len()))
 	} else if len(index) > 1 {
-		var n = make([]string, len(c.value))
-		copy(n, c.value)
-		n = n[index[0] : index[0]+index[1]]
-
-		return StringArrayCollection{n, BaseCollection{length: len(n)}}
+		return newStringArrayCollection(c.value.Slice(index[0], index[0]+index[1]))
 	} else {
-		return BaseCollection{err: errors.New("invalid argument")}
+		return BaseCollection{err: ErrInvalidArgument}
 	}
 }
 
 // Take returns a new collection with the specified number of items.
 func (c StringArrayCollection) Take(num int) Collection {
-	var d StringArrayCollection
-	if num > c.length {
-		return BaseCollection{err: errors.New("not enough elements to take")}
+	if num > c.value.Len() {
+		return BaseCollection{err: fmt.Errorf("%w: not enough elements to take", ErrInvalidArgument)}
 	}
 
 	if num >= 0 {
-		d.value = c.value[:num]
-		d.length = num
-	} else {
-		d.value = c.value[len(c.value)+num:]
-		d.length = 0 - num
+		return newStringArrayCollection(c.value.Slice(0, num))
 	}
-
-	return d
+	return newStringArrayCollection(c.value.Slice(c.value.Len()+num, c.value.Len()))
 }
 
 // All returns the underlying array represented by the collection.
 func (c StringArrayCollection) All() []interface{} {
-	s := make([]interface{}, len(c.value))
-	for i := 0; i < len(c.value); i++ {
-		s[i] = c.value[i]
+	items := c.items()
+	s := make([]interface{}, len(items))
+	for i := 0; i < len(items); i++ {
+		s[i] = items[i]
 	}
 
 	return s
@@ -148,11 +163,11 @@ func (c StringArrayCollection) ModeE(key ...string) ([]interface{}, error) {
 
 // ToStringArray converts the collection into a plain golang slice which contains string.
 func (c StringArrayCollection) ToStringArray() []string {
-	return c.value
+	return c.items()
 }
 
 func (c StringArrayCollection) ToStringArrayE() ([]string, error) {
-	return c.value, c.err
+	return c.items(), c.err
 }
 
 // Chunk breaks the collection into multiple, smaller collections of a given size.
@@ -184,18 +199,22 @@ func (c StringArrayCollection) Chunk(num int) MultiDimensionalArrayCollection {
 
 // Concat appends the given array or collection values onto the end of the collection.
 func (c StringArrayCollection) Concat(value interface{}) Collection {
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
-	return StringArrayCollection{
-		value:          append(d, value.([]string)...),
-		BaseCollection: BaseCollection{length: c.length + len(value.([]string))},
+	s, ok := value.([]string)
+	if !ok {
+		return BaseCollection{err: wrongType("[]string", value)}
+	}
+	var d = c.value.Transient()
+	for _, v := range s {
+		d.Append(v)
 	}
+	return newStringArrayCollection(d.Persistent())
 }
 
 // Contains determines whether the collection contains a given item.
 func (c StringArrayCollection) Contains(value ...interface{}) bool {
+	items := c.items()
 	if callback, ok := value[0].(CB); ok {
-		for k, v := range c.value {
+		for k, v := range items {
 			if callback(k, v) {
 				return true
 			}
@@ -203,7 +222,7 @@ func (c StringArrayCollection) Contains(value ...interface{}) bool {
 		return false
 	}
 
-	for _, v := range c.value {
+	for _, v := range items {
 		if v == value[0].(string) {
 			return true
 		}
@@ -217,16 +236,17 @@ func (c StringArrayCollection) ContainsE(value ...interface{}) (bool, error) {
 
 // CountBy counts the occurrences of values in the collection. By default, the method counts the occurrences of every element.
 func (c StringArrayCollection) CountBy(callback ...interface{}) map[interface{}]int {
+	items := c.items()
 	valueCount := make(map[interface{}]int)
 
 	if len(callback) > 0 {
 		if cb, ok := callback[0].(FilterFun); ok {
-			for _, v := range c.value {
+			for _, v := range items {
 				valueCount[cb(v)]++
 			}
 		}
 	} else {
-		for _, v := range c.value {
+		for _, v := range items {
 			valueCount[v]++
 		}
 	}
@@ -240,9 +260,10 @@ func (c StringArrayCollection) CountByE(callback ...interface{}) (map[interface{
 
 // CrossJoin cross joins the collection's values among the given arrays or collections, returning a Cartesian product with all possible permutations.
 func (c StringArrayCollection) CrossJoin(array ...[]interface{}) MultiDimensionalArrayCollection {
+	items := c.items()
 	var d MultiDimensionalArrayCollection
 
-	length := len(c.value)
+	length := len(items)
 	for _, s := range array {
 		length *= len(s)
 	}
@@ -253,7 +274,7 @@ func (c StringArrayCollection) CrossJoin(array ...[]interface{}) MultiDimensiona
 
 	offset := length / c.length
 	for i := 0; i < length; i++ {
-		value[i][0] = c.value[i/offset]
+		value[i][0] = items[i/offset]
 	}
 	assignmentToValue(value, array, length, 1, 0, offset)
 
@@ -285,9 +306,13 @@ func (c StringArrayCollection) DumpE() error {
 // Diff compares the collection against another collection or a plain PHP array based on its values.
 // This method will return the values in the original collection that are not present in the given collection.
 func (c StringArrayCollection) Diff(m interface{}) Collection {
-	ms := m.([]string)
+	ms, ok := m.([]string)
+	if !ok {
+		return BaseCollection{err: wrongType("[]string", m)}
+	}
+	items := c.items()
 	var d = make([]string, 0)
-	for _, value := range c.value {
+	for _, value := range items {
 		exist := false
 		for i := 0; i < len(ms); i++ {
 			if ms[i] == value {
@@ -299,34 +324,36 @@ func (c StringArrayCollection) Diff(m interface{}) Collection {
 			d = append(d, value)
 		}
 	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // Each iterates over the items in the collection and passes each item to a callback.
 func (c StringArrayCollection) Each(cb func(item, value interface{}) (interface{}, bool)) Collection {
+	items := c.items()
 	var d = make([]string, 0)
 	var (
 		newValue interface{}
 		stop     = false
 	)
-	for key, value := range c.value {
+	for key, value := range items {
 		if !stop {
 			newValue, stop = cb(key, value)
-			d = append(d, newValue.(string))
+			s, ok := newValue.(string)
+			if !ok {
+				return BaseCollection{err: wrongType("string", newValue)}
+			}
+			d = append(d, s)
 		} else {
 			d = append(d, value)
 		}
 	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // Every may be used to verify that all elements of a collection pass a given truth test.
 func (c StringArrayCollection) Every(cb CB) bool {
-	for key, value := range c.value {
+	items := c.items()
+	for key, value := range items {
 		if !cb(key, value) {
 			return false
 		}
@@ -340,29 +367,29 @@ func (c StringArrayCollection) EveryE(cb CB) (bool, error) {
 
 // Filter filters the collection using the given callback, keeping only those items that pass a given truth test.
 func (c StringArrayCollection) Filter(cb CB) Collection {
+	items := c.items()
 	var d = make([]string, 0)
-	for key, value := range c.value {
+	for key, value := range items {
 		if cb(key, value) {
 			d = append(d, value)
 		}
 	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // First returns the first element in the collection that passes a given truth test.
 func (c StringArrayCollection) First(cbs ...CB) interface{} {
+	items := c.items()
 	if len(cbs) > 0 {
-		for key, value := range c.value {
+		for key, value := range items {
 			if cbs[0](key, value) {
 				return value
 			}
 		}
 		return nil
 	} else {
-		if len(c.value) > 0 {
-			return c.value[0]
+		if len(items) > 0 {
+			return items[0]
 		} else {
 			return nil
 		}
@@ -375,8 +402,9 @@ func (c StringArrayCollection) FirstE(cbs ...CB) (interface{}, error) {
 
 // Intersect removes any values from the original collection that are not present in the given array or collection.
 func (c StringArrayCollection) Intersect(keys []string) Collection {
+	items := c.items()
 	var d = make([]string, 0)
-	for _, value := range c.value {
+	for _, value := range items {
 		for _, v := range keys {
 			if v == value {
 				d = append(d, value)
@@ -384,34 +412,25 @@ func (c StringArrayCollection) Intersect(keys []string) Collection {
 			}
 		}
 	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // ForPage returns a new collection containing the items that would be present on a given page number.
 func (c StringArrayCollection) ForPage(page, size int) Collection {
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
-	if size > len(d) || size*(page-1) > len(d) {
-		return StringArrayCollection{
-			value: d,
-		}
+	var length = c.value.Len()
+	if size > length || size*(page-1) > length {
+		return c
 	}
-	if (page+1)*size > len(d) {
-		return StringArrayCollection{
-			value: d[(page-1)*size:],
-		}
+	if (page+1)*size > length {
+		return newStringArrayCollection(c.value.Slice((page-1)*size, length))
 	} else {
-		return StringArrayCollection{
-			value: d[(page-1)*size : (page)*size],
-		}
+		return newStringArrayCollection(c.value.Slice((page-1)*size, page*size))
 	}
 }
 
 // IsEmpty returns true if the collection is empty; otherwise, false is returned.
 func (c StringArrayCollection) IsEmpty() bool {
-	return len(c.value) == 0
+	return c.value.Len() == 0
 }
 
 func (c StringArrayCollection) IsEmptyE() (bool, error) {
@@ -420,7 +439,7 @@ func (c StringArrayCollection) IsEmptyE() (bool, error) {
 
 // IsNotEmpty returns true if the collection is not empty; otherwise, false is returned.
 func (c StringArrayCollection) IsNotEmpty() bool {
-	return len(c.value) != 0
+	return c.value.Len() != 0
 }
 
 func (c StringArrayCollection) IsNotEmptyE() (bool, error) {
@@ -429,17 +448,18 @@ func (c StringArrayCollection) IsNotEmptyE() (bool, error) {
 
 // Last returns the last element in the collection that passes a given truth test.
 func (c StringArrayCollection) Last(cbs ...CB) interface{} {
+	items := c.items()
 	if len(cbs) > 0 {
 		var last interface{}
-		for key, value := range c.value {
+		for key, value := range items {
 			if cbs[0](key, value) {
 				last = value
 			}
 		}
 		return last
 	} else {
-		if len(c.value) > 0 {
-			return c.value[len(c.value)-1]
+		if len(items) > 0 {
+			return items[len(items)-1]
 		} else {
 			return nil
 		}
@@ -454,9 +474,11 @@ func (c StringArrayCollection) LastE(cbs ...CB) (interface{}, error) {
 // matches a string key in the original collection, the given items's value will overwrite the value in the
 // original collection.
 func (c StringArrayCollection) Merge(i interface{}) Collection {
-	m := i.([]string)
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
+	m, ok := i.([]string)
+	if !ok {
+		return BaseCollection{err: wrongType("[]string", i)}
+	}
+	var d = c.items()
 
 	for i := 0; i < len(m); i++ {
 		exist := false
@@ -471,72 +493,62 @@ func (c StringArrayCollection) Merge(i interface{}) Collection {
 		}
 	}
 
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // Pad will fill the array with the given value until the array reaches the specified size.
 func (c StringArrayCollection) Pad(num int, value interface{}) Collection {
-	if len(c.value) > num {
-		d := make([]string, len(c.value))
-		copy(d, c.value)
-		return StringArrayCollection{
-			value: d,
-		}
+	s, ok := value.(string)
+	if !ok {
+		return BaseCollection{err: wrongType("string", value)}
+	}
+	items := c.items()
+	if len(items) > num {
+		return c
 	}
 	if num > 0 {
 		d := make([]string, num)
 		for i := 0; i < num; i++ {
-			if i < len(c.value) {
-				d[i] = c.value[i]
+			if i < len(items) {
+				d[i] = items[i]
 			} else {
-				d[i] = value.(string)
+				d[i] = s
 			}
 		}
-		return StringArrayCollection{
-			value: d,
-		}
+		return newStringArrayCollection(stringList(d))
 	} else {
 		d := make([]string, -num)
 		for i := 0; i < -num; i++ {
-			if i < -num-len(c.value) {
-				d[i] = value.(string)
+			if i < -num-len(items) {
+				d[i] = s
 			} else {
-				d[i] = c.value[i]
+				d[i] = items[i]
 			}
 		}
-		return StringArrayCollection{
-			value: d,
-		}
+		return newStringArrayCollection(stringList(d))
 	}
 }
 
 // Partition separate elements that pass a given truth test from those that do not.
 func (c StringArrayCollection) Partition(cb PartCB) (Collection, Collection) {
+	items := c.items()
 	var d1 = make([]string, 0)
 	var d2 = make([]string, 0)
 
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if cb(i) {
-			d1 = append(d1, c.value[i])
+			d1 = append(d1, items[i])
 		} else {
-			d2 = append(d2, c.value[i])
+			d2 = append(d2, items[i])
 		}
 	}
 
-	return StringArrayCollection{
-			value: d1,
-		}, StringArrayCollection{
-			value: d2,
-		}
+	return newStringArrayCollection(stringList(d1)), newStringArrayCollection(stringList(d2))
 }
 
 // Pop removes and returns the last item from the collection.
 func (c StringArrayCollection) Pop() interface{} {
-	last := c.value[len(c.value)-1]
-	c.value = c.value[:len(c.value)-1]
-	return last
+	return c.value.Last()
 }
 
 func (c StringArrayCollection) PopE() (interface{}, error) {
@@ -545,48 +557,40 @@ func (c StringArrayCollection) PopE() (interface{}, error) {
 
 // Push appends an item to the end of the collection.
 func (c StringArrayCollection) Push(v interface{}) Collection {
-	var d = make([]string, len(c.value)+1)
-	for i := 0; i < len(d); i++ {
-		if i < len(c.value) {
-			d[i] = c.value[i]
-		} else {
-			d[i] = v.(string)
-		}
-	}
-
-	return StringArrayCollection{
-		value: d,
+	s, ok := v.(string)
+	if !ok {
+		return BaseCollection{err: wrongType("string", v)}
 	}
+	return newStringArrayCollection(c.value.Append(s))
 }
 
 // Random returns a random item from the collection.
 func (c StringArrayCollection) Random(num ...int) Collection {
+	items := c.items()
 	if len(num) == 0 {
 		return BaseCollection{
-			value: c.value[rand.Intn(len(c.value))],
+			value: items[rand.Intn(len(items))],
 		}
 	} else {
-		if num[0] > len(c.value) {
-			return BaseCollection{err: errors.New("wrong num")}
+		if num[0] > len(items) {
+			return BaseCollection{err: fmt.Errorf("%w: wrong num", ErrInvalidArgument)}
 		}
-		var d = make([]string, len(c.value))
-		copy(d, c.value)
-		for i := 0; i < len(c.value)-num[0]; i++ {
+		var d = items
+		for i := 0; i < len(items)-num[0]; i++ {
 			index := rand.Intn(len(d))
 			d = append(d[:index], d[index+1:]...)
 		}
-		return StringArrayCollection{
-			value: d,
-		}
+		return newStringArrayCollection(stringList(d))
 	}
 }
 
 // Reduce reduces the collection to a single value, passing the result of each iteration into the subsequent iteration.
 func (c StringArrayCollection) Reduce(cb ReduceCB) interface{} {
+	items := c.items()
 	var res interface{}
 
-	for i := 0; i < len(c.value); i++ {
-		res = cb(res, c.value[i])
+	for i := 0; i < len(items); i++ {
+		res = cb(res, items[i])
 	}
 
 	return res
@@ -598,43 +602,42 @@ func (c StringArrayCollection) ReduceE(cb ReduceCB) (interface{}, error) {
 
 // Reject filters the collection using the given callback.
 func (c StringArrayCollection) Reject(cb CB) Collection {
+	items := c.items()
 	var d = make([]string, 0)
-	for key, value := range c.value {
+	for key, value := range items {
 		if !cb(key, value) {
 			d = append(d, value)
 		}
 	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // Reverse reverses the order of the collection's items, preserving the original keys.
 func (c StringArrayCollection) Reverse() Collection {
-	var d = make([]string, len(c.value))
+	items := c.items()
+	var d = make([]string, len(items))
 	j := 0
-	for i := len(c.value) - 1; i > -1; i-- {
-		d[j] = c.value[i]
+	for i := len(items) - 1; i > -1; i-- {
+		d[j] = items[i]
 		j++
 	}
-	return StringArrayCollection{
-		value: d,
-	}
+	return newStringArrayCollection(stringList(d))
 }
 
 // Search searches the collection for the given value and returns its key if found. If the item is not found,
 // -1 is returned.
 func (c StringArrayCollection) Search(v interface{}) int {
+	items := c.items()
 	if s, ok := v.(string); ok {
-		for i := 0; i < len(c.value); i++ {
-			if s == c.value[i] {
+		for i := 0; i < len(items); i++ {
+			if s == items[i] {
 				return i
 			}
 		}
 	} else {
 		cb := v.(CB)
-		for i := 0; i < len(c.value); i++ {
-			if cb(i, c.value[i]) {
+		for i := 0; i < len(items); i++ {
+			if cb(i, items[i]) {
 				return i
 			}
 		}
@@ -648,55 +651,43 @@ func (c StringArrayCollection) SearchE(v interface{}) (int, error) {
 
 // Shift removes and returns the first item from the collection.
 func (c StringArrayCollection) Shift() Collection {
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
-	return StringArrayCollection{
-		value: d[1:],
-	}
+	return newStringArrayCollection(c.value.Slice(1, c.value.Len()))
 }
 
 // Shuffle randomly shuffles the items in the collection.
 func (c StringArrayCollection) Shuffle() Collection {
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
+	var d = c.items()
 	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(c.value), func(i, j int) { d[i], d[j] = d[j], d[i] })
-	return StringArrayCollection{
-		value: d,
-	}
+	rand.Shuffle(len(d), func(i, j int) { d[i], d[j] = d[j], d[i] })
+	return newStringArrayCollection(stringList(d))
 }
 
 // Slice returns a slice of the collection starting at the given index.
 func (c StringArrayCollection) Slice(keys ...int) Collection {
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
 	if len(keys) == 1 {
-		return StringArrayCollection{
-			value: d[keys[0]:],
-		}
+		return newStringArrayCollection(c.value.Slice(keys[0], c.value.Len()))
 	} else {
-		return StringArrayCollection{
-			value: d[keys[0] : keys[0]+keys[1]],
-		}
+		return newStringArrayCollection(c.value.Slice(keys[0], keys[0]+keys[1]))
 	}
 }
 
 // Split breaks a collection into the given number of groups.
 func (c StringArrayCollection) Split(num int) Collection {
-	var d = make([][]interface{}, int(math.Ceil(float64(len(c.value))/float64(num))))
+	items := c.items()
+	var d = make([][]interface{}, int(math.Ceil(float64(len(items))/float64(num))))
 
 	j := -1
-	for i := 0; i < len(c.value); i++ {
+	for i := 0; i < len(items); i++ {
 		if i%num == 0 {
 			j++
-			if i+num <= len(c.value) {
+			if i+num <= len(items) {
 				d[j] = make([]interface{}, num)
 			} else {
-				d[j] = make([]interface{}, len(c.value)-i)
+				d[j] = make([]interface{}, len(items)-i)
 			}
-			d[j][i%num] = c.value[i]
+			d[j][i%num] = items[i]
 		} else {
-			d[j][i%num] = c.value[i]
+			d[j][i%num] = items[i]
 		}
 	}
 
@@ -707,8 +698,7 @@ func (c StringArrayCollection) Split(num int) Collection {
 
 // Unique returns all of the unique items in the collection.
 func (c StringArrayCollection) Unique() Collection {
-	var d = make([]string, len(c.value))
-	copy(d, c.value)
+	var d = c.items()
 	x := make([]string, 0)
 	for _, i := range d {
 		if len(x) == 0 {
@@ -724,14 +714,12 @@ func (c StringArrayCollection) Unique() Collection {
 			}
 		}
 	}
-	return StringArrayCollection{
-		value: x,
-	}
+	return newStringArrayCollection(stringList(x))
 }
 
 // ToJson converts the collection into a json string.
 func (c StringArrayCollection) ToJson() string {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.items())
 	if err != nil {
 		return ""
 	}
@@ -739,9 +727,9 @@ func (c StringArrayCollection) ToJson() string {
 }
 
 func (c StringArrayCollection) ToJsonE() (string, error) {
-	s, err := json.Marshal(c.value)
+	s, err := json.Marshal(c.items())
 	if err != nil {
-		c.errorHandle(err.Error())
+		c.errorHandle(err)
 		return "", c.err
 	}
 	return string(s), c.err
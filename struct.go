@@ -0,0 +1,255 @@
+package collection
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// The tags which name the key of a struct field, in the order they are looked up.
+var structTags = []string{"collection", "json", "mapstructure"}
+
+// structField describes how a field of a struct is turned into a map entry.
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+	// promote puts the fields of an embedded or squashed struct into the parent map.
+	promote bool
+	// flatten puts the fields of a nested struct into the parent map, with keys prefixed by name and the path
+	// separator, so "address.city" is still found by the path methods.
+	flatten bool
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// CollectStructs converts a []T or []*T of structs into a MapArrayCollection, one map per struct.
+//
+// The key of a field is taken from its collection, json or mapstructure tag, in that order, or is the field
+// name. A field tagged "-" and the unexported fields are left out, and the omitempty option leaves out the
+// empty values like encoding/json does. The fields of embedded structs are put into the parent map unless
+// the embedded field is named by a tag, the squash option does the same for any struct field. A nested
+// struct becomes a nested map, or is flattened into keys like "address.city" with the flatten option. A
+// json or text marshaler such as decimal.Decimal or time.Time, and a struct without exported fields, is
+// kept as it is.
+func CollectStructs(src interface{}) Collection {
+	v := reflect.ValueOf(src)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return BaseCollection{err: wrongType("slice of structs", src)}
+	}
+	if !isStructType(v.Type().Elem()) {
+		return BaseCollection{err: wrongType("slice of structs", src)}
+	}
+
+	var d = make([]map[string]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		if !item.IsValid() {
+			return BaseCollection{err: fmt.Errorf("%w: nil struct at index %d", ErrWrongValue, i)}
+		}
+		d[i] = structToMap(item)
+	}
+	return newMapArrayCollection(mapList(d))
+}
+
+// isStructSlice determines if src can be collected by CollectStructs.
+func isStructSlice(src interface{}) bool {
+	t := reflect.TypeOf(src)
+	return t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && isStructType(t.Elem())
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isStructType determines if t, or the type t points to, is a struct which is turned into a map. The structs
+// which have a representation of their own, the json or text marshalers such as decimal.Decimal and
+// time.Time, and the structs without exported fields are kept as values.
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, m := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if t.Implements(m) || reflect.PtrTo(t).Implements(m) {
+			return false
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.PkgPath == "" || sf.Anonymous {
+			return true
+		}
+	}
+	return false
+}
+
+func structToMap(v reflect.Value) map[string]interface{} {
+	var d = make(map[string]interface{})
+	putStruct(d, v, "")
+	return d
+}
+
+// putStruct puts the fields of the struct v into d, with every key prefixed by prefix. The fields of the
+// struct itself win over the promoted fields of its embedded structs.
+func putStruct(d map[string]interface{}, v reflect.Value, prefix string) {
+	var promoted []reflect.Value
+	for _, f := range cachedStructFields(v.Type()) {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if f.promote || f.flatten {
+			if fv = reflect.Indirect(fv); !fv.IsValid() {
+				continue
+			}
+			if f.promote {
+				promoted = append(promoted, fv)
+			} else {
+				putStruct(d, fv, prefix+f.name+pathSeparator)
+			}
+			continue
+		}
+		d[prefix+f.name] = structValue(fv)
+	}
+
+	for _, fv := range promoted {
+		var fields = make(map[string]interface{})
+		putStruct(fields, fv, prefix)
+		for k, value := range fields {
+			if _, ok := d[k]; !ok {
+				d[k] = value
+			}
+		}
+	}
+}
+
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := structFieldsCache.LoadOrStore(t, structFields(t))
+	return fields.([]structField)
+}
+
+func structFields(t reflect.Type) []structField {
+	var fields = make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, tagged := structTag(sf)
+		if name == "-" && opts == "" {
+			continue
+		}
+		isStruct := isStructType(sf.Type)
+		// The exported fields of an embedded struct are promoted even when its type is unexported.
+		if sf.PkgPath != "" && !(sf.Anonymous && isStruct) {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := structField{index: i, name: name}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "squash", "inline":
+				f.promote = isStruct
+			case "flatten":
+				f.flatten = isStruct
+			}
+		}
+		if sf.Anonymous && isStruct && !tagged {
+			f.promote = true
+		}
+		if sf.PkgPath != "" && !f.promote {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// structTag returns the name and the options of the first tag the field has, and whether the tag names it.
+func structTag(sf reflect.StructField) (name string, opts string, named bool) {
+	for _, key := range structTags {
+		tag, ok := sf.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name, opts = tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		return name, opts, name != ""
+	}
+	return "", "", false
+}
+
+// structValue returns the value of a field. Structs, also inside slices and maps, are turned into maps.
+func structValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if isStructType(v.Type()) {
+			return structToMap(v.Elem())
+		}
+		if v.Kind() == reflect.Interface {
+			return structValue(v.Elem())
+		}
+	case reflect.Struct:
+		if isStructType(v.Type()) {
+			return structToMap(v)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if isStructType(v.Type().Elem()) || v.Type().Elem().Kind() == reflect.Interface {
+			var d = make([]interface{}, v.Len())
+			for i := range d {
+				d[i] = structValue(v.Index(i))
+			}
+			return d
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Key().Kind() == reflect.String && isStructType(v.Type().Elem()) {
+			var d = make(map[string]interface{}, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				d[iter.Key().String()] = structValue(iter.Value())
+			}
+			return d
+		}
+	}
+	return v.Interface()
+}
+
+// isEmptyValue reports whether v is empty in the sense of the omitempty option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+package collection
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// TypedCollection is the type-safe counterpart of Collection. It keeps the element type, so callbacks
+// receive a T instead of an interface{} which needs a type assertion. Like Collection, every method
+// returns a new TypedCollection and never changes the original one.
+//
+// Go methods can not declare type parameters, so the operations which change the element type, such as
+// Map, Reduce and GroupBy, are plain functions taking the collection as their first argument.
+type TypedCollection[T any] struct {
+	value []T
+}
+
+// CollectTyped transforms items into a TypedCollection.
+func CollectTyped[T any](items []T) TypedCollection[T] {
+	var d = make([]T, len(items))
+	copy(d, items)
+	return TypedCollection[T]{value: d}
+}
+
+// FromCollection converts a Collection into a TypedCollection. The values of a NumberArrayCollection are
+// converted from decimal.Decimal when T is a golang number type.
+func FromCollection[T any](c Collection) (TypedCollection[T], error) {
+	all, err := c.AllE()
+	if err != nil {
+		return TypedCollection[T]{}, err
+	}
+	var d = make([]T, len(all))
+	for i, v := range all {
+		t, ok := convertTo[T](v)
+		if !ok {
+			return TypedCollection[T]{}, wrongType(fmt.Sprintf("%T", t), v)
+		}
+		d[i] = t
+	}
+	return TypedCollection[T]{value: d}, nil
+}
+
+// ToCollection converts the collection into an interface{}-based Collection using Collect.
+func (c TypedCollection[T]) ToCollection() Collection {
+	return Collect(interface{}(c.All()))
+}
+
+// All returns the underlying array represented by the collection.
+func (c TypedCollection[T]) All() []T {
+	var d = make([]T, len(c.value))
+	copy(d, c.value)
+	return d
+}
+
+// Length return the length of the collection.
+func (c TypedCollection[T]) Length() int {
+	return len(c.value)
+}
+
+// IsEmpty returns true if the collection is empty; otherwise, false is returned.
+func (c TypedCollection[T]) IsEmpty() bool {
+	return len(c.value) == 0
+}
+
+// Each iterates over the items in the collection and passes each item to a callback until it returns false.
+func (c TypedCollection[T]) Each(cb func(i int, value T) bool) TypedCollection[T] {
+	for i, v := range c.value {
+		if !cb(i, v) {
+			break
+		}
+	}
+	return c
+}
+
+// Filter filters the collection using the given callback, keeping only those items that pass a given truth test.
+func (c TypedCollection[T]) Filter(cb func(value T) bool) TypedCollection[T] {
+	var d = make([]T, 0)
+	for _, v := range c.value {
+		if cb(v) {
+			d = append(d, v)
+		}
+	}
+	return TypedCollection[T]{value: d}
+}
+
+// Reject filters the collection using the given callback, removing the items that pass a given truth test.
+func (c TypedCollection[T]) Reject(cb func(value T) bool) TypedCollection[T] {
+	return c.Filter(func(value T) bool {
+		return !cb(value)
+	})
+}
+
+// First returns the first element in the collection that passes a given truth test, and whether there is one.
+func (c TypedCollection[T]) First(cbs ...func(value T) bool) (T, bool) {
+	for _, v := range c.value {
+		if len(cbs) == 0 || cbs[0](v) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Take returns a new collection with the specified number of items. A negative number takes the items
+// from the end of the collection.
+func (c TypedCollection[T]) Take(num int) TypedCollection[T] {
+	if num >= 0 {
+		return CollectTyped(c.value[:min(num, len(c.value))])
+	}
+	return CollectTyped(c.value[max(len(c.value)+num, 0):])
+}
+
+// Reverse reverses the order of the collection's items.
+func (c TypedCollection[T]) Reverse() TypedCollection[T] {
+	var d = make([]T, len(c.value))
+	for i, v := range c.value {
+		d[len(d)-1-i] = v
+	}
+	return TypedCollection[T]{value: d}
+}
+
+// Chunk breaks the collection into multiple, smaller collections of a given size.
+func (c TypedCollection[T]) Chunk(num int) []TypedCollection[T] {
+	if num <= 0 {
+		return nil
+	}
+	var d = make([]TypedCollection[T], 0, (len(c.value)+num-1)/num)
+	for i := 0; i < len(c.value); i += num {
+		d = append(d, CollectTyped(c.value[i:min(i+num, len(c.value))]))
+	}
+	return d
+}
+
+// Sort sorts the collection with the given less function. The sort is stable.
+func (c TypedCollection[T]) Sort(less func(a, b T) bool) TypedCollection[T] {
+	var d = c.All()
+	sort.SliceStable(d, func(i, j int) bool {
+		return less(d[i], d[j])
+	})
+	return TypedCollection[T]{value: d}
+}
+
+// Map iterates through the collection and passes each value to the given callback, collecting the results.
+func Map[T, U any](c TypedCollection[T], cb func(value T) U) TypedCollection[U] {
+	var d = make([]U, len(c.value))
+	for i, v := range c.value {
+		d[i] = cb(v)
+	}
+	return TypedCollection[U]{value: d}
+}
+
+// Reduce reduces the collection to a single value, passing the result of each iteration into the
+// subsequent iteration.
+func Reduce[T, U any](c TypedCollection[T], initial U, cb func(carry U, value T) U) U {
+	var res = initial
+	for _, v := range c.value {
+		res = cb(res, v)
+	}
+	return res
+}
+
+// GroupBy groups the collection's items by the key returned from the given callback.
+func GroupBy[T any, K comparable](c TypedCollection[T], cb func(value T) K) map[K]TypedCollection[T] {
+	var groups = make(map[K][]T)
+	for _, v := range c.value {
+		k := cb(v)
+		groups[k] = append(groups[k], v)
+	}
+	var d = make(map[K]TypedCollection[T], len(groups))
+	for k, v := range groups {
+		d[k] = TypedCollection[T]{value: v}
+	}
+	return d
+}
+
+// SortBy sorts the collection by the key returned from the given callback. The sort is stable.
+func SortBy[T any, K cmp.Ordered](c TypedCollection[T], cb func(value T) K) TypedCollection[T] {
+	return c.Sort(func(a, b T) bool {
+		return cb(a) < cb(b)
+	})
+}
+
+// SortByDesc has the same signature as SortBy, but will sort the collection in the opposite order.
+func SortByDesc[T any, K cmp.Ordered](c TypedCollection[T], cb func(value T) K) TypedCollection[T] {
+	return c.Sort(func(a, b T) bool {
+		return cb(a) > cb(b)
+	})
+}
+
+// Unique returns all of the unique items in the collection, keeping the first occurrence of each one.
+func Unique[T comparable](c TypedCollection[T]) TypedCollection[T] {
+	return UniqueBy(c, func(value T) T {
+		return value
+	})
+}
+
+// UniqueBy returns the items whose key, returned from the given callback, is unique in the collection.
+func UniqueBy[T any, K comparable](c TypedCollection[T], cb func(value T) K) TypedCollection[T] {
+	var (
+		seen = make(map[K]struct{})
+		d    = make([]T, 0)
+	)
+	for _, v := range c.value {
+		k := cb(v)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		d = append(d, v)
+	}
+	return TypedCollection[T]{value: d}
+}
+
+// convertTo asserts v to T, converting decimal.Decimal into the golang number types. A decimal converts into
+// an integer type only when it is whole and in the range of the type.
+func convertTo[T any](v interface{}) (T, bool) {
+	if t, ok := v.(T); ok {
+		return t, true
+	}
+	var t T
+	d, ok := v.(decimal.Decimal)
+	if !ok {
+		return t, false
+	}
+	switch p := interface{}(&t).(type) {
+	case *int:
+		n, ok := decimalInt(d, strconv.IntSize)
+		*p = int(n)
+		return t, ok
+	case *int8:
+		n, ok := decimalInt(d, 8)
+		*p = int8(n)
+		return t, ok
+	case *int16:
+		n, ok := decimalInt(d, 16)
+		*p = int16(n)
+		return t, ok
+	case *int32:
+		n, ok := decimalInt(d, 32)
+		*p = int32(n)
+		return t, ok
+	case *int64:
+		n, ok := decimalInt(d, 64)
+		*p = n
+		return t, ok
+	case *uint:
+		n, ok := decimalUint(d, strconv.IntSize)
+		*p = uint(n)
+		return t, ok
+	case *uint8:
+		n, ok := decimalUint(d, 8)
+		*p = uint8(n)
+		return t, ok
+	case *uint16:
+		n, ok := decimalUint(d, 16)
+		*p = uint16(n)
+		return t, ok
+	case *uint32:
+		n, ok := decimalUint(d, 32)
+		*p = uint32(n)
+		return t, ok
+	case *uint64:
+		n, ok := decimalUint(d, 64)
+		*p = n
+		return t, ok
+	case *float32:
+		f, _ := d.Float64()
+		*p = float32(f)
+	case *float64:
+		*p, _ = d.Float64()
+	case *string:
+		*p = d.String()
+	default:
+		return t, false
+	}
+	return t, true
+}
+
+// decimalInt returns d as an integer of the given bit size, if it is whole and in range.
+func decimalInt(d decimal.Decimal, bitSize int) (int64, bool) {
+	if !d.Equal(d.Truncate(0)) {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(d.String(), 10, bitSize)
+	return n, err == nil
+}
+
+// decimalUint returns d as an unsigned integer of the given bit size, if it is whole and in range.
+func decimalUint(d decimal.Decimal, bitSize int) (uint64, bool) {
+	if !d.Equal(d.Truncate(0)) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(d.String(), 10, bitSize)
+	return n, err == nil
+}
@@ -1 +1,409 @@
+// Package vector_trie implements a persistent vector as a 32-way trie with tail optimization.
+//
+// Append, Set and Pop copy only the path from the root to the touched leaf, so they cost
+// O(log32 n) while the old List stays valid and shares everything else with the new one.
+// Slice returns a view over the same trie in O(1). A Transient may be used to build a
+// List with in place appends.
 package vector_trie
+
+const (
+	bitsPerLevel = 5
+	branchFactor = 1 << bitsPerLevel
+	levelMask    = branchFactor - 1
+)
+
+// owner marks the nodes created by a transient, those nodes may be edited in place.
+type owner struct{ _ byte }
+
+// persistent owns every node created by a persistent operation, no transient ever matches it.
+var persistent = &owner{}
+
+type node struct {
+	// items holds the values on the leaves and *node on the branches.
+	items []interface{}
+	edit  *owner
+}
+
+func newNode(edit *owner) *node {
+	return &node{items: make([]interface{}, branchFactor), edit: edit}
+}
+
+func (n *node) editable(edit *owner) *node {
+	if edit != persistent && n.edit == edit {
+		return n
+	}
+	items := make([]interface{}, branchFactor)
+	copy(items, n.items)
+	return &node{items: items, edit: edit}
+}
+
+// List is a persistent vector of interface{}. A nil *List behaves like an empty list.
+type List struct {
+	// cnt, shift, root and tail describe the underlying trie, start and end the visible window of it.
+	cnt   int
+	shift uint
+	root  *node
+	tail  []interface{}
+	start int
+	end   int
+}
+
+// New returns an empty List.
+func New() *List {
+	return &List{shift: bitsPerLevel, root: newNode(persistent), tail: []interface{}{}}
+}
+
+// FromSlice builds a List which contains the items of s.
+func FromSlice(s []interface{}) *List {
+	t := New().Transient()
+	for _, v := range s {
+		t.Append(v)
+	}
+	return t.Persistent()
+}
+
+// Len returns the number of items in the list.
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return l.end - l.start
+}
+
+// Get returns the item at index i. It panics if i is out of range.
+func (l *List) Get(i int) interface{} {
+	l.checkIndex(i)
+	i += l.start
+	return l.leafFor(i)[i&levelMask]
+}
+
+// Set returns a new list with the item at index i replaced by v. Setting the index Len()
+// appends v.
+func (l *List) Set(i int, v interface{}) *List {
+	if i == l.Len() {
+		return l.Append(v)
+	}
+	l.checkIndex(i)
+	d := *l
+	d.assoc(i+l.start, v)
+	return &d
+}
+
+// Append returns a new list with v added to the end.
+func (l *List) Append(v interface{}) *List {
+	if l == nil {
+		l = New()
+	}
+	d := *l
+	if l.end < l.cnt {
+		// A slice view shadows the items after its end, overwrite them.
+		d.assoc(l.end, v)
+	} else {
+		d.conj(v)
+	}
+	d.end++
+	return &d
+}
+
+// Pop returns a new list without the last item. It panics if the list is empty.
+func (l *List) Pop() *List {
+	if l.Len() == 0 {
+		panic("vector_trie: pop on an empty list")
+	}
+	if l.start != 0 || l.end != l.cnt {
+		d := *l
+		d.end--
+		return &d
+	}
+	if l.cnt == 1 {
+		return New()
+	}
+	d := *l
+	d.pop()
+	d.end--
+	return &d
+}
+
+// Last returns the last item of the list. It panics if the list is empty.
+func (l *List) Last() interface{} {
+	return l.Get(l.Len() - 1)
+}
+
+// Slice returns the items from index start up to but not including end. It shares the
+// underlying trie with the list.
+func (l *List) Slice(start, end int) *List {
+	if start < 0 || end > l.Len() || start > end {
+		panic("vector_trie: slice bounds out of range")
+	}
+	d := *l
+	d.start = l.start + start
+	d.end = l.start + end
+	return &d
+}
+
+// Range calls f for every item of the list in order until f returns false.
+func (l *List) Range(f func(i int, v interface{}) bool) {
+	if l == nil {
+		return
+	}
+	for i := l.start; i < l.end; {
+		leaf := l.leafFor(i)
+		for j := i & levelMask; j < len(leaf) && i < l.end; j++ {
+			if !f(i-l.start, leaf[j]) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// ToSlice converts the list into a plain golang slice.
+func (l *List) ToSlice() []interface{} {
+	s := make([]interface{}, 0, l.Len())
+	l.Range(func(_ int, v interface{}) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// Transient returns a mutable copy of the list for batch edits. The list itself is never
+// changed by the transient.
+func (l *List) Transient() *Transient {
+	if l == nil {
+		l = New()
+	}
+	t := &Transient{edit: &owner{}}
+	if l.start != 0 || l.end != l.cnt {
+		t.cnt, t.shift, t.root = 0, bitsPerLevel, newNode(t.edit)
+		t.tail = make([]interface{}, 0, branchFactor)
+		l.Range(func(_ int, v interface{}) bool {
+			t.Append(v)
+			return true
+		})
+		return t
+	}
+	t.cnt, t.shift, t.root = l.cnt, l.shift, l.root
+	t.tail = make([]interface{}, len(l.tail), branchFactor)
+	copy(t.tail, l.tail)
+	return t
+}
+
+func (l *List) checkIndex(i int) {
+	if i < 0 || i >= l.Len() {
+		panic("vector_trie: index out of range")
+	}
+}
+
+func (l *List) tailOffset() int {
+	return tailOffset(l.cnt)
+}
+
+func tailOffset(cnt int) int {
+	if cnt < branchFactor {
+		return 0
+	}
+	return ((cnt - 1) >> bitsPerLevel) << bitsPerLevel
+}
+
+// leafFor returns the leaf array which holds the underlying index i.
+func (l *List) leafFor(i int) []interface{} {
+	return leafFor(l.root, l.shift, l.cnt, l.tail, i)
+}
+
+func leafFor(root *node, shift uint, cnt int, tail []interface{}, i int) []interface{} {
+	if i >= tailOffset(cnt) {
+		return tail
+	}
+	n := root
+	for level := shift; level > 0; level -= bitsPerLevel {
+		n = n.items[(i>>level)&levelMask].(*node)
+	}
+	return n.items
+}
+
+func (l *List) assoc(i int, v interface{}) {
+	if i >= l.tailOffset() {
+		tail := make([]interface{}, len(l.tail))
+		copy(tail, l.tail)
+		tail[i&levelMask] = v
+		l.tail = tail
+		return
+	}
+	l.root = doAssoc(persistent, l.shift, l.root, i, v)
+}
+
+func doAssoc(edit *owner, level uint, n *node, i int, v interface{}) *node {
+	d := n.editable(edit)
+	if level == 0 {
+		d.items[i&levelMask] = v
+		return d
+	}
+	sub := (i >> level) & levelMask
+	d.items[sub] = doAssoc(edit, level-bitsPerLevel, n.items[sub].(*node), i, v)
+	return d
+}
+
+func (l *List) conj(v interface{}) {
+	if l.cnt-l.tailOffset() < branchFactor {
+		tail := make([]interface{}, len(l.tail)+1)
+		copy(tail, l.tail)
+		tail[len(l.tail)] = v
+		l.tail = tail
+		l.cnt++
+		return
+	}
+	l.root, l.shift = pushTail(persistent, l.root, l.shift, l.cnt, &node{items: l.tail, edit: persistent})
+	l.tail = []interface{}{v}
+	l.cnt++
+}
+
+// pushTail moves a full tail into the trie, growing the trie by one level when the root is full.
+func pushTail(edit *owner, root *node, shift uint, cnt int, tail *node) (*node, uint) {
+	if (cnt >> bitsPerLevel) > (1 << shift) {
+		r := newNode(edit)
+		r.items[0] = root
+		r.items[1] = newPath(edit, shift, tail)
+		return r, shift + bitsPerLevel
+	}
+	return doPushTail(edit, cnt, shift, root, tail), shift
+}
+
+func doPushTail(edit *owner, cnt int, level uint, parent *node, tail *node) *node {
+	d := parent.editable(edit)
+	sub := ((cnt - 1) >> level) & levelMask
+	var insert *node
+	if level == bitsPerLevel {
+		insert = tail
+	} else if child, ok := parent.items[sub].(*node); ok {
+		insert = doPushTail(edit, cnt, level-bitsPerLevel, child, tail)
+	} else {
+		insert = newPath(edit, level-bitsPerLevel, tail)
+	}
+	d.items[sub] = insert
+	return d
+}
+
+func newPath(edit *owner, level uint, n *node) *node {
+	if level == 0 {
+		return n
+	}
+	r := newNode(edit)
+	r.items[0] = newPath(edit, level-bitsPerLevel, n)
+	return r
+}
+
+func (l *List) pop() {
+	if l.cnt-l.tailOffset() > 1 {
+		tail := make([]interface{}, len(l.tail)-1)
+		copy(tail, l.tail)
+		l.tail = tail
+		l.cnt--
+		return
+	}
+	l.tail = l.leafFor(l.cnt - 2)
+	root := popTail(l.cnt, l.shift, l.root)
+	if root == nil {
+		root = newNode(persistent)
+	}
+	if l.shift > bitsPerLevel && root.items[1] == nil {
+		root = root.items[0].(*node)
+		l.shift -= bitsPerLevel
+	}
+	l.root = root
+	l.cnt--
+}
+
+func popTail(cnt int, level uint, n *node) *node {
+	sub := ((cnt - 2) >> level) & levelMask
+	if level > bitsPerLevel {
+		child := popTail(cnt, level-bitsPerLevel, n.items[sub].(*node))
+		if child == nil && sub == 0 {
+			return nil
+		}
+		d := n.editable(persistent)
+		if child == nil {
+			d.items[sub] = nil
+		} else {
+			d.items[sub] = child
+		}
+		return d
+	}
+	if sub == 0 {
+		return nil
+	}
+	d := n.editable(persistent)
+	d.items[sub] = nil
+	return d
+}
+
+// Transient is a mutable List used for batch edits. It must not be used after Persistent
+// has been called, and it is not safe for concurrent use.
+type Transient struct {
+	cnt   int
+	shift uint
+	root  *node
+	tail  []interface{}
+	edit  *owner
+}
+
+// Len returns the number of items in the transient.
+func (t *Transient) Len() int {
+	return t.cnt
+}
+
+// Get returns the item at index i. It panics if i is out of range.
+func (t *Transient) Get(i int) interface{} {
+	t.ensureEditable()
+	if i < 0 || i >= t.cnt {
+		panic("vector_trie: index out of range")
+	}
+	return leafFor(t.root, t.shift, t.cnt, t.tail, i)[i&levelMask]
+}
+
+// Append adds v to the end in place.
+func (t *Transient) Append(v interface{}) *Transient {
+	t.ensureEditable()
+	if t.cnt-tailOffset(t.cnt) < branchFactor {
+		t.tail = append(t.tail, v)
+		t.cnt++
+		return t
+	}
+	t.root, t.shift = pushTail(t.edit, t.root, t.shift, t.cnt, &node{items: t.tail, edit: t.edit})
+	t.tail = make([]interface{}, 1, branchFactor)
+	t.tail[0] = v
+	t.cnt++
+	return t
+}
+
+// Set replaces the item at index i in place. Setting the index Len() appends v.
+func (t *Transient) Set(i int, v interface{}) *Transient {
+	t.ensureEditable()
+	if i == t.cnt {
+		return t.Append(v)
+	}
+	if i < 0 || i > t.cnt {
+		panic("vector_trie: index out of range")
+	}
+	if i >= tailOffset(t.cnt) {
+		t.tail[i&levelMask] = v
+		return t
+	}
+	t.root = doAssoc(t.edit, t.shift, t.root, i, v)
+	return t
+}
+
+// Persistent ends the batch edit and returns the resulting List.
+func (t *Transient) Persistent() *List {
+	t.ensureEditable()
+	t.edit = nil
+	tail := make([]interface{}, len(t.tail))
+	copy(tail, t.tail)
+	return &List{cnt: t.cnt, shift: t.shift, root: t.root, tail: tail, end: t.cnt}
+}
+
+func (t *Transient) ensureEditable() {
+	if t.edit == nil {
+		panic("vector_trie: transient used after Persistent")
+	}
+}
@@ -0,0 +1,84 @@
+package vector_trie
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestList_Append(t *testing.T) {
+	l := New()
+	for i := 0; i < 5000; i++ {
+		l = l.Append(i)
+	}
+	assert.Equal(t, l.Len(), 5000)
+	for i := 0; i < 5000; i++ {
+		assert.Equal(t, l.Get(i), i)
+	}
+
+	l2 := l.Append(5000)
+	assert.Equal(t, l.Len(), 5000)
+	assert.Equal(t, l2.Last(), 5000)
+}
+
+func TestList_Set(t *testing.T) {
+	l := FromSlice([]interface{}{1, 2, 3})
+	l2 := l.Set(1, 20)
+
+	assert.Equal(t, l.ToSlice(), []interface{}{1, 2, 3})
+	assert.Equal(t, l2.ToSlice(), []interface{}{1, 20, 3})
+	assert.Equal(t, l2.Set(3, 4).ToSlice(), []interface{}{1, 20, 3, 4})
+}
+
+func TestList_Pop(t *testing.T) {
+	l := New()
+	for i := 0; i < 2000; i++ {
+		l = l.Append(i)
+	}
+	for i := 1999; i >= 0; i-- {
+		assert.Equal(t, l.Last(), i)
+		l = l.Pop()
+		assert.Equal(t, l.Len(), i)
+	}
+}
+
+func TestList_Slice(t *testing.T) {
+	s := make([]interface{}, 100)
+	for i := range s {
+		s[i] = i
+	}
+	l := FromSlice(s)
+
+	sub := l.Slice(10, 20)
+	assert.Equal(t, sub.Len(), 10)
+	assert.Equal(t, sub.Get(0), 10)
+	assert.Equal(t, sub.ToSlice(), s[10:20])
+
+	sub2 := sub.Append("x")
+	assert.Equal(t, sub2.Get(10), "x")
+	assert.Equal(t, l.Get(20), 20)
+	assert.Equal(t, sub.Pop().ToSlice(), s[10:19])
+}
+
+func TestList_Transient(t *testing.T) {
+	l := FromSlice([]interface{}{1, 2, 3})
+
+	tr := l.Transient()
+	for i := 4; i <= 100; i++ {
+		tr.Append(i)
+	}
+	tr.Set(0, 0)
+	l2 := tr.Persistent()
+
+	assert.Equal(t, l.ToSlice(), []interface{}{1, 2, 3})
+	assert.Equal(t, l2.Len(), 100)
+	assert.Equal(t, l2.Get(0), 0)
+	assert.Equal(t, l2.Get(99), 100)
+}
+
+func BenchmarkList_Append(b *testing.B) {
+	l := New()
+	for i := 0; i < b.N; i++ {
+		l = l.Append(i)
+	}
+}
@@ -0,0 +1,437 @@
+package collection
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The layouts a string is parsed with when it is compared with a date.
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// whereCondition reports whether an item passes a condition of Where.
+type whereCondition func(item map[string]interface{}) bool
+
+// WhereQuery builds nested conditions for WhereGroup. The conditions are joined like in SQL, AND binds
+// tighter than OR, so q.Where(a).OrWhere(b).Where(c) is a OR (b AND c). The arguments of Where and OrWhere
+// are the same as the arguments of the Where method of a collection.
+type WhereQuery struct {
+	clauses []whereClause
+	err     error
+}
+
+type whereClause struct {
+	or   bool
+	cond whereCondition
+}
+
+// Where adds a condition which must hold together with the previous one.
+func (q *WhereQuery) Where(key string, values ...interface{}) *WhereQuery {
+	return q.add(false, key, values)
+}
+
+// OrWhere adds a condition which is enough on its own or together with the conditions after it.
+func (q *WhereQuery) OrWhere(key string, values ...interface{}) *WhereQuery {
+	return q.add(true, key, values)
+}
+
+// WhereGroup adds the conditions built by fn as a single condition, like parentheses in SQL.
+func (q *WhereQuery) WhereGroup(fn func(q *WhereQuery)) *WhereQuery {
+	return q.group(false, fn)
+}
+
+// OrWhereGroup is the same as WhereGroup, but joins the group with OR.
+func (q *WhereQuery) OrWhereGroup(fn func(q *WhereQuery)) *WhereQuery {
+	return q.group(true, fn)
+}
+
+func (q *WhereQuery) add(or bool, key string, values []interface{}) *WhereQuery {
+	cond, err := compileWhere(key, values)
+	if err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+	q.clauses = append(q.clauses, whereClause{or: or, cond: cond})
+	return q
+}
+
+func (q *WhereQuery) group(or bool, fn func(q *WhereQuery)) *WhereQuery {
+	var sub = new(WhereQuery)
+	fn(sub)
+	if sub.err != nil && q.err == nil {
+		q.err = sub.err
+	}
+	q.clauses = append(q.clauses, whereClause{or: or, cond: sub.match})
+	return q
+}
+
+// match reports whether the item passes the query. A query without conditions passes every item.
+func (q *WhereQuery) match(item map[string]interface{}) bool {
+	var all = true
+	for i, c := range q.clauses {
+		if i > 0 && c.or {
+			if all {
+				return true
+			}
+			all = true
+		}
+		all = all && c.cond(item)
+	}
+	return all
+}
+
+// buildWhereQuery runs fn on a fresh WhereQuery and returns it with the first error of its conditions.
+func buildWhereQuery(fn func(q *WhereQuery)) (*WhereQuery, error) {
+	var q = new(WhereQuery)
+	fn(q)
+	return q, q.err
+}
+
+// compileWhere returns the condition of Where. Without values the item's value of key must be true, with one
+// value it must equal the value, also when it is a string like "null", otherwise values are an operator and
+// its operands. The key may be a path, a path with a wildcard matches when any of its values passes.
+//
+// The operators are =, ==, !=, <>, >, >=, <, <=, like, not like, ilike, not ilike, between, not between,
+// regexp, not regexp, in, not in, contains, not contains, starts with, ends with, and is and is not, whose
+// operand is nil, such as Where("email", "is", nil). The
+// pattern of like has the wildcards % and _. Times compare as times, also with strings in a date layout.
+func compileWhere(key string, values []interface{}) (whereCondition, error) {
+	match, err := compileMatch(values)
+	if err != nil {
+		return nil, err
+	}
+	if !isWildcardPath(key) {
+		return func(item map[string]interface{}) bool {
+			return match(lookupPath(item, key))
+		}, nil
+	}
+	return func(item map[string]interface{}) bool {
+		value, _ := getPath(item, key)
+		for _, v := range value.([]interface{}) {
+			if match(v) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func compileMatch(values []interface{}) (func(value interface{}) bool, error) {
+	switch len(values) {
+	case 0:
+		return isTrue, nil
+	case 1:
+		return func(value interface{}) bool { return equalValues(value, values[0]) }, nil
+	}
+
+	s, ok := values[0].(string)
+	if !ok {
+		return nil, wrongType("operator string", values[0])
+	}
+	op, operands := normalizeOperator(s), values[1:]
+	if op == "between" || op == "not between" {
+		return compileBetween(op == "not between", operands)
+	}
+	if len(operands) > 1 {
+		return nil, fmt.Errorf("%w: too many operands for %q", ErrInvalidArgument, s)
+	}
+	operand := operands[0]
+
+	switch op {
+	case "=", "==":
+		return func(value interface{}) bool { return equalValues(value, operand) }, nil
+	case "!=", "<>":
+		return func(value interface{}) bool { return !equalValues(value, operand) }, nil
+	case "is", "is not":
+		if operand != nil {
+			return nil, fmt.Errorf("%w: the operand of %q must be nil", ErrInvalidArgument, s)
+		}
+		return func(value interface{}) bool { return (value == nil) == (op == "is") }, nil
+	case ">", ">=", "<", "<=":
+		return func(value interface{}) bool {
+			c, ok := orderValues(value, operand)
+			if !ok {
+				return false
+			}
+			switch op {
+			case ">":
+				return c > 0
+			case ">=":
+				return c >= 0
+			case "<":
+				return c < 0
+			default:
+				return c <= 0
+			}
+		}, nil
+	case "like", "not like", "ilike", "not ilike":
+		pattern, ok := operand.(string)
+		if !ok {
+			return nil, wrongType("string", operand)
+		}
+		re := likePattern(pattern, strings.HasSuffix(op, "ilike"))
+		return negate(strings.HasPrefix(op, "not "), func(value interface{}) bool {
+			return value != nil && re.MatchString(stringValue(value))
+		}), nil
+	case "regexp", "not regexp":
+		var re *regexp.Regexp
+		switch p := operand.(type) {
+		case *regexp.Regexp:
+			re = p
+		case string:
+			var err error
+			if re, err = regexp.Compile(p); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+			}
+		default:
+			return nil, wrongType("string or *regexp.Regexp", operand)
+		}
+		return negate(op == "not regexp", func(value interface{}) bool {
+			return value != nil && re.MatchString(stringValue(value))
+		}), nil
+	case "in", "not in":
+		list := reflect.ValueOf(operand)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return nil, wrongType("slice", operand)
+		}
+		return negate(op == "not in", func(value interface{}) bool {
+			for i := 0; i < list.Len(); i++ {
+				if equalValues(value, list.Index(i).Interface()) {
+					return true
+				}
+			}
+			return false
+		}), nil
+	case "contains", "not contains":
+		return negate(op == "not contains", func(value interface{}) bool {
+			return containsValue(value, operand)
+		}), nil
+	case "starts with", "ends with":
+		affix, ok := operand.(string)
+		if !ok {
+			return nil, wrongType("string", operand)
+		}
+		return func(value interface{}) bool {
+			s, ok := value.(string)
+			if op == "starts with" {
+				return ok && strings.HasPrefix(s, affix)
+			}
+			return ok && strings.HasSuffix(s, affix)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidArgument, s)
+	}
+}
+
+// compileBetween returns the condition of between and not between. Both bounds are inclusive.
+func compileBetween(not bool, operands []interface{}) (func(value interface{}) bool, error) {
+	bounds, err := betweenBounds(operands)
+	if err != nil {
+		return nil, err
+	}
+	low, high := bounds[0], bounds[1]
+	return func(value interface{}) bool {
+		l, ok1 := orderValues(value, low)
+		h, ok2 := orderValues(value, high)
+		if !ok1 || !ok2 {
+			return false
+		}
+		return (l >= 0 && h <= 0) != not
+	}, nil
+}
+
+// betweenBounds accepts the bounds of between as two operands or as one slice of two items.
+func betweenBounds(operands []interface{}) ([]interface{}, error) {
+	if len(operands) == 1 {
+		if list := reflect.ValueOf(operands[0]); list.Kind() == reflect.Slice || list.Kind() == reflect.Array {
+			operands = make([]interface{}, list.Len())
+			for i := range operands {
+				operands[i] = list.Index(i).Interface()
+			}
+		}
+	}
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("%w: between needs two bounds", ErrInvalidArgument)
+	}
+	return operands, nil
+}
+
+func negate(not bool, match func(value interface{}) bool) func(value interface{}) bool {
+	if !not {
+		return match
+	}
+	return func(value interface{}) bool {
+		return !match(value)
+	}
+}
+
+// normalizeOperator lowercases the operator and collapses its white space, so "NOT  LIKE" is "not like".
+func normalizeOperator(op string) string {
+	return strings.Join(strings.Fields(strings.ToLower(op)), " ")
+}
+
+// likePattern turns a pattern of like into a regular expression. A backslash escapes a wildcard.
+func likePattern(pattern string, fold bool) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)")
+	if fold {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	var escaped = false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// equalValues determines if two values are equal. Numbers of different types are equal when their values
+// are, and times are equal when they are the same instant.
+func equalValues(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return nd(a).Equal(nd(b))
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := asTime(b); ok {
+			return ta.Equal(tb)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// orderValues compares two values for the range operators, and reports whether they can be compared. Nil
+// can not be compared. A time compares with a time or a string in a date layout, two dates in strings
+// compare as times too, numbers compare as numbers, also in strings, and other strings compare lexically.
+func orderValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	sa, aString := a.(string)
+	sb, bString := b.(string)
+	if aTime || bTime || (aString && bString) {
+		ta, okA := asTime(a)
+		tb, okB := asTime(b)
+		if okA && okB {
+			return ta.Compare(tb), true
+		}
+		if aTime || bTime {
+			return 0, false
+		}
+	}
+	da, okA := asDecimal(a)
+	db, okB := asDecimal(b)
+	if okA && okB {
+		return da.Cmp(db), true
+	}
+	if aString && bString {
+		return strings.Compare(sa, sb), true
+	}
+	return 0, false
+}
+
+func asTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	case string:
+		for _, layout := range dateLayouts {
+			if d, err := time.Parse(layout, t); err == nil {
+				return d, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func asDecimal(v interface{}) (decimal.Decimal, bool) {
+	if isNumber(v) {
+		return nd(v), true
+	}
+	if s, ok := v.(string); ok {
+		d, err := decimal.NewFromString(s)
+		return d, err == nil
+	}
+	return decimal.Decimal{}, false
+}
+
+// containsValue determines if a string contains the operand as a substring, a slice contains it as an item,
+// or a map contains it as a key.
+func containsValue(value, operand interface{}) bool {
+	switch t := value.(type) {
+	case nil:
+		return false
+	case string:
+		s, ok := operand.(string)
+		return ok && strings.Contains(t, s)
+	case map[string]interface{}:
+		s, ok := operand.(string)
+		if !ok {
+			return false
+		}
+		_, ok = t[s]
+		return ok
+	}
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < list.Len(); i++ {
+		if equalValues(list.Index(i).Interface(), operand) {
+			return true
+		}
+	}
+	return false
+}
+
+// WhereGroup filters the collection by the conditions built by fn, which may be joined with OR and nested.
+//
+//	c.WhereGroup(func(q *WhereQuery) {
+//		q.Where("age", ">=", 18).OrWhereGroup(func(q *WhereQuery) {
+//			q.Where("vip", true).Where("name", "like", "j%")
+//		})
+//	})
+func (c MapArrayCollection) WhereGroup(fn func(q *WhereQuery)) Collection {
+	q, err := buildWhereQuery(fn)
+	if err != nil {
+		return BaseCollection{err: err}
+	}
+	var d = make([]map[string]interface{}, 0)
+	for _, item := range c.items() {
+		if q.match(item) {
+			d = append(d, item)
+		}
+	}
+	return newMapArrayCollection(mapList(d))
+}
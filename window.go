@@ -0,0 +1,273 @@
+package collection
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// WindowCollection adds columns computed over windows of rows, like the window functions of SQL. The rows are
+// split into partitions by the values of the partition keys, and every partition is ordered by the order keys.
+// The columns are computed when Collect is called, and the result keeps the rows in their original order.
+type WindowCollection struct {
+	source      []map[string]interface{}
+	partitionBy []string
+	orderBy     []SortKey
+	columns     []windowColumn
+	err         error
+}
+
+// windowColumn sets a column of the rows of one ordered partition.
+type windowColumn func(w window)
+
+// window is one partition. The rows at order are in the order of the window, and peer reports whether the
+// row at position i has the same order values as the row before it.
+type window struct {
+	rows  []map[string]interface{}
+	order []int
+	peer  func(i int) bool
+}
+
+func (w window) row(i int) map[string]interface{} {
+	return w.rows[w.order[i]]
+}
+
+// Window returns a WindowCollection over the rows of the collection. The rows are partitioned by the values
+// at the partitionBy keys or paths, and ordered by the orderBy keys which SortBy takes. Without partition keys
+// all of the rows are in one partition, and without order keys a partition keeps the order of the collection.
+func (c MapArrayCollection) Window(partitionBy []string, orderBy ...interface{}) WindowCollection {
+	if c.err != nil {
+		return WindowCollection{err: c.err}
+	}
+	keys, err := sortKeys(orderBy, false)
+	if err != nil {
+		return WindowCollection{err: err}
+	}
+	return WindowCollection{source: c.items(), partitionBy: partitionBy, orderBy: keys}
+}
+
+// Err returns the error of the collection the window was built from, or of an invalid argument.
+func (c WindowCollection) Err() error {
+	return c.err
+}
+
+func (c WindowCollection) then(column windowColumn) WindowCollection {
+	var d = make([]windowColumn, len(c.columns), len(c.columns)+1)
+	copy(d, c.columns)
+	c.columns = append(d, column)
+	return c
+}
+
+// RowNumber sets column to the position of the row in its partition, starting at 1.
+func (c WindowCollection) RowNumber(column string) WindowCollection {
+	return c.then(func(w window) {
+		for i := range w.order {
+			w.row(i)[column] = i + 1
+		}
+	})
+}
+
+// Rank sets column to the rank of the row in its partition. Rows with the same order values have the same
+// rank, and leave a gap after them.
+func (c WindowCollection) Rank(column string) WindowCollection {
+	return c.then(func(w window) {
+		var rank = 0
+		for i := range w.order {
+			if !w.peer(i) {
+				rank = i + 1
+			}
+			w.row(i)[column] = rank
+		}
+	})
+}
+
+// DenseRank is the same as Rank, but leaves no gaps after the rows with the same order values.
+func (c WindowCollection) DenseRank(column string) WindowCollection {
+	return c.then(func(w window) {
+		var rank = 0
+		for i := range w.order {
+			if !w.peer(i) {
+				rank++
+			}
+			w.row(i)[column] = rank
+		}
+	})
+}
+
+// Ntile sets column to the number of the bucket the row is in, when its partition is split into n buckets
+// as equal as possible. The first buckets get the remaining rows.
+func (c WindowCollection) Ntile(column string, n int) WindowCollection {
+	if n < 1 {
+		c.err = fmt.Errorf("%w: ntile of %d buckets", ErrInvalidArgument, n)
+		return c
+	}
+	return c.then(func(w window) {
+		var (
+			size  = len(w.order) / n
+			rest  = len(w.order) % n
+			large = rest * (size + 1)
+		)
+		for i := range w.order {
+			if i < large {
+				w.row(i)[column] = i/(size+1) + 1
+			} else {
+				w.row(i)[column] = rest + (i-large)/size + 1
+			}
+		}
+	})
+}
+
+// Lag sets column to the value at key of the row offset rows before in the partition, or to the given
+// default value if there is no such row.
+func (c WindowCollection) Lag(column, key string, offset int, def ...interface{}) WindowCollection {
+	return c.shift(column, key, -offset, def)
+}
+
+// Lead sets column to the value at key of the row offset rows after in the partition, or to the given
+// default value if there is no such row.
+func (c WindowCollection) Lead(column, key string, offset int, def ...interface{}) WindowCollection {
+	return c.shift(column, key, offset, def)
+}
+
+func (c WindowCollection) shift(column, key string, offset int, def []interface{}) WindowCollection {
+	var value interface{}
+	if len(def) > 0 {
+		value = def[0]
+	}
+	return c.then(func(w window) {
+		// The values are read first, so a column which replaces key still sees the original values.
+		var values = make([]interface{}, len(w.order))
+		for i := range w.order {
+			values[i] = lookupPath(w.row(i), key)
+		}
+		for i := range w.order {
+			if j := i + offset; j >= 0 && j < len(w.order) {
+				w.row(i)[column] = values[j]
+			} else {
+				w.row(i)[column] = value
+			}
+		}
+	})
+}
+
+// RunningSum sets column to the sum of the values at key of the rows from the start of the partition up to
+// and including the row.
+func (c WindowCollection) RunningSum(column, key string) WindowCollection {
+	return c.frame(column, key, 0, func(sum decimal.Decimal, count int) interface{} {
+		return sum
+	})
+}
+
+// RunningAvg sets column to the average of the values at key of the rows from the start of the partition up
+// to and including the row. The rows without a value are left out, and the average of no value is nil.
+func (c WindowCollection) RunningAvg(column, key string) WindowCollection {
+	return c.frame(column, key, 0, average)
+}
+
+// MovingAvg sets column to the average of the values at key of the row and the size-1 rows before it in the
+// partition. The rows without a value are left out, and the average of no value is nil.
+func (c WindowCollection) MovingAvg(column, key string, size int) WindowCollection {
+	if size < 1 {
+		c.err = fmt.Errorf("%w: moving average over %d rows", ErrInvalidArgument, size)
+		return c
+	}
+	return c.frame(column, key, size, average)
+}
+
+func average(sum decimal.Decimal, count int) interface{} {
+	if count == 0 {
+		return nil
+	}
+	return sum.Div(decimal.New(int64(count), 0))
+}
+
+// frame sets column to the result of the sum and the count of the non-nil values at key in the frame of
+// every row, which holds the size rows up to and including the row, or all of them for a size of 0.
+func (c WindowCollection) frame(column, key string, size int, result func(sum decimal.Decimal, count int) interface{}) WindowCollection {
+	return c.then(func(w window) {
+		var (
+			values = make([]interface{}, len(w.order))
+			sum    = decimal.New(0, 0)
+			count  = 0
+		)
+		for i := range w.order {
+			values[i] = lookupPath(w.row(i), key)
+		}
+		for i := range w.order {
+			if values[i] != nil {
+				sum, count = sum.Add(nd(values[i])), count+1
+			}
+			if j := i - size; size > 0 && j >= 0 && values[j] != nil {
+				sum, count = sum.Sub(nd(values[j])), count-1
+			}
+			w.row(i)[column] = result(sum, count)
+		}
+	})
+}
+
+// Collect computes the columns and returns the rows as a MapArrayCollection, in the order of the source. The
+// rows are copied, the source is not changed.
+func (c WindowCollection) Collect() Collection {
+	if c.err != nil {
+		return BaseCollection{err: c.err}
+	}
+	var rows = make([]map[string]interface{}, len(c.source))
+	for i, row := range c.source {
+		rows[i] = make(map[string]interface{}, len(row)+len(c.columns))
+		for k, v := range row {
+			rows[i][k] = v
+		}
+	}
+
+	for _, part := range c.partitions(rows) {
+		var values = make([][]interface{}, len(part))
+		order := sortOrder(len(part), c.orderBy, func(i int, key string) interface{} {
+			return lookupPath(rows[part[i]], key)
+		})
+		for i, j := range order {
+			order[i] = part[j]
+			values[i] = make([]interface{}, len(c.orderBy))
+			for k, key := range c.orderBy {
+				values[i][k] = lookupPath(rows[order[i]], key.Key)
+			}
+		}
+		w := window{rows: rows, order: order, peer: func(i int) bool {
+			if i == 0 {
+				return false
+			}
+			for k, key := range c.orderBy {
+				if key.compare(values[i-1][k], values[i][k]) != 0 {
+					return false
+				}
+			}
+			return true
+		}}
+		for _, column := range c.columns {
+			column(w)
+		}
+	}
+	return newMapArrayCollection(mapList(rows))
+}
+
+// partitions returns the indexes of the rows of every partition, in the order of their first row.
+func (c WindowCollection) partitions(rows []map[string]interface{}) [][]int {
+	var (
+		index = make(map[string]int)
+		parts = make([][]int, 0)
+	)
+	for i, row := range rows {
+		var key = make([]interface{}, len(c.partitionBy))
+		for j, k := range c.partitionBy {
+			key[j] = lookupPath(row, k)
+		}
+		hash, _ := valueHash(key)
+		p, ok := index[hash]
+		if !ok {
+			p = len(parts)
+			index[hash] = p
+			parts = append(parts, nil)
+		}
+		parts[p] = append(parts[p], i)
+	}
+	return parts
+}